@@ -0,0 +1,20 @@
+#!/bin/sh
+# Роли сервиса, создаются при первом запуске контейнера Postgres (docker-entrypoint-initdb.d).
+# APP_DB_USER — обычная роль без SUPERUSER и BYPASSRLS, от её имени работает API, и политики row-level security
+# для неё действуют. APP_DB_WORKER_ROLE — роль с BYPASSRLS без входа: на неё переключаются только транзакции
+# фоновых задач, которым нужны строки всех арендаторов
+set -e
+
+psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" \
+	-v app_user="$APP_DB_USER" -v app_password="$APP_DB_PASSWORD" -v worker_role="$APP_DB_WORKER_ROLE" <<'EOSQL'
+CREATE ROLE :"app_user" LOGIN PASSWORD :'app_password' NOSUPERUSER NOBYPASSRLS;
+CREATE ROLE :"worker_role" NOLOGIN BYPASSRLS;
+GRANT :"worker_role" TO :"app_user";
+GRANT USAGE, CREATE ON SCHEMA public TO :"app_user";
+
+-- Таблицы создаёт сервис, поэтому права роли фоновых задач выдаются на будущие таблицы и последовательности
+ALTER DEFAULT PRIVILEGES FOR ROLE :"app_user" IN SCHEMA public
+	GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO :"worker_role";
+ALTER DEFAULT PRIVILEGES FOR ROLE :"app_user" IN SCHEMA public
+	GRANT USAGE, SELECT, UPDATE ON SEQUENCES TO :"worker_role";
+EOSQL
@@ -0,0 +1,179 @@
+package apikeys
+
+import (
+	"app/internal/logging"
+	"app/internal/models"
+	"app/internal/tenant"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Handlers — контракт для HTTP-обработчиков API-ключей
+type Handlers interface {
+	CreateKey(c *gin.Context)
+	GetKeyByID(c *gin.Context)
+	ListKeys(c *gin.Context)
+	RevokeKey(c *gin.Context)
+}
+
+// handlers — структура, реализующая интерфейс Handlers
+type handlers struct {
+	service Service
+	logger  *logrus.Logger
+}
+
+// NewHandlers — конструктор handlers
+func NewHandlers(service Service, logger *logrus.Logger) Handlers {
+	return &handlers{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateKey godoc
+// @Summary Выпустить API-ключ
+// @Description Выпускает API-ключ для сервисного клиента с областями доступа read, write и/или admin и необязательным сроком действия expires_at.
+// @Description Ключ относится к арендатору запроса, возвращается в поле key только в этом ответе и передаётся в заголовке X-API-Key
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body models.APIKey true "Название, области доступа и срок действия ключа"
+// @Success 201 {object} models.APIKey
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /api-keys [post]
+func (h *handlers) CreateKey(c *gin.Context) {
+	h.log(c).Info("handlers.CreateKey: Creating API key")
+	var key models.APIKey
+	if err := c.ShouldBindJSON(&key); err != nil {
+		h.log(c).Errorf("handlers.CreateKey: Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.scoped(c).CreateKey(&key); err != nil {
+		h.log(c).Errorf("handlers.CreateKey: Failed to create API key: %v", err)
+		h.writeError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	h.log(c).Infof("handlers.CreateKey: API key created successfully with ID %d", key.ID)
+	c.JSON(http.StatusCreated, key)
+}
+
+// GetKeyByID godoc
+// @Summary Получить API-ключ по ID
+// @Description Возвращает сведения об API-ключе без самого ключа
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "ID ключа"
+// @Success 200 {object} models.APIKey
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /api-keys/{id} [get]
+func (h *handlers) GetKeyByID(c *gin.Context) {
+	id, ok := h.parseID(c, "GetKeyByID")
+	if !ok {
+		return
+	}
+
+	key, err := h.scoped(c).GetKeyByID(id)
+	if err != nil {
+		h.log(c).Warnf("handlers.GetKeyByID: Failed to fetch API key with ID %d: %v", id, err)
+		h.writeError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// ListKeys godoc
+// @Summary Список API-ключей
+// @Description Возвращает все API-ключи, включая отозванные и истёкшие, без самих ключей
+// @Tags api-keys
+// @Produce json
+// @Success 200 {array} models.APIKey
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /api-keys [get]
+func (h *handlers) ListKeys(c *gin.Context) {
+	keys, err := h.scoped(c).ListKeys()
+	if err != nil {
+		h.log(c).Errorf("handlers.ListKeys: Failed to fetch list of API keys: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeKey godoc
+// @Summary Отозвать API-ключ
+// @Description Отзывает API-ключ: запросы с ним сразу начинают отклоняться, запись ключа сохраняется
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "ID ключа"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /api-keys/{id} [delete]
+func (h *handlers) RevokeKey(c *gin.Context) {
+	id, ok := h.parseID(c, "RevokeKey")
+	if !ok {
+		return
+	}
+
+	if err := h.scoped(c).RevokeKey(id); err != nil {
+		h.log(c).Warnf("handlers.RevokeKey: Failed to revoke API key with ID %d: %v", id, err)
+		h.writeError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.log(c).Infof("handlers.RevokeKey: API key with ID %d revoked successfully", id)
+	c.Status(http.StatusNoContent)
+}
+
+// scoped возвращает Service арендатора запроса
+func (h *handlers) scoped(c *gin.Context) Service {
+	return h.service.ForTenant(tenant.From(c))
+}
+
+// parseID разбирает ID ключа из пути; при ошибке отвечает 400
+func (h *handlers) parseID(c *gin.Context, method string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.%s: Invalid ID format: %v", method, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeError отвечает 404 для несуществующих ключей, иначе fallback
+func (h *handlers) writeError(c *gin.Context, err error, fallback int) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "api key not found"})
+	case fallback == http.StatusInternalServerError:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	default:
+		c.JSON(fallback, gin.H{"error": err.Error()})
+	}
+}
+
+// log возвращает логгер с полями запроса
+func (h *handlers) log(c *gin.Context) *logrus.Entry {
+	return logging.From(c.Request.Context(), h.logger)
+}
@@ -0,0 +1,115 @@
+package apikeys
+
+import (
+	"app/internal/database"
+	"app/internal/models"
+	"app/internal/tenant"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Repository — контракт для работы с API-ключами в бд
+type Repository interface {
+	Create(key *models.APIKey) error
+	GetByID(id uint) (*models.APIKey, error)
+	GetByHash(hash string) (*models.APIKey, error)
+	List() ([]models.APIKey, error)
+	Revoke(id uint, at time.Time) error
+	TouchLastUsed(id uint, at time.Time) error
+	ForTenant(tenantID string) Repository
+}
+
+// repository — структура, реализующая интерфейс Repository.
+// Пустой tenantID — репозиторий без ограничения арендатором (проверка ключа по хешу)
+type repository struct {
+	db       *gorm.DB
+	tenantID string
+	logger   *logrus.Logger
+}
+
+// NewRepository — конструктор repository
+func NewRepository(logger *logrus.Logger) Repository {
+	return &repository{
+		db:     database.Get(),
+		logger: logger,
+	}
+}
+
+// ForTenant возвращает репозиторий, работающий только с ключами арендатора tenantID
+func (r *repository) ForTenant(tenantID string) Repository {
+	return &repository{
+		db:       tenant.DB(r.db, tenantID),
+		tenantID: tenantID,
+		logger:   r.logger,
+	}
+}
+
+// Create сохраняет новый API-ключ
+func (r *repository) Create(key *models.APIKey) error {
+	r.logger.Infof("repository.Create: Creating API key %q", key.Name)
+	if r.tenantID != "" {
+		key.TenantID = r.tenantID
+	}
+	if err := r.db.Create(key).Error; err != nil {
+		r.logger.Errorf("repository.Create: Failed to create API key: %v", err)
+		return err
+	}
+	r.logger.Infof("repository.Create: API key created successfully with ID %d", key.ID)
+	return nil
+}
+
+// GetByID возвращает API-ключ по ID
+func (r *repository) GetByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		r.logger.Warnf("repository.GetByID: Failed to fetch API key with ID %d: %v", id, err)
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetByHash возвращает API-ключ по SHA-256 ключа
+func (r *repository) GetByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("hash = ?", hash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// List возвращает все API-ключи, новые первыми
+func (r *repository) List() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.db.Order("id DESC").Find(&keys).Error; err != nil {
+		r.logger.Errorf("repository.List: Failed to fetch list of API keys: %v", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke отзывает API-ключ; запись остаётся для аудита
+func (r *repository) Revoke(id uint, at time.Time) error {
+	r.logger.Infof("repository.Revoke: Revoking API key with ID %d", id)
+	result := r.db.Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", at)
+	if result.Error != nil {
+		r.logger.Errorf("repository.Revoke: Failed to revoke API key with ID %d: %v", id, result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := r.GetByID(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TouchLastUsed обновляет время последнего использования API-ключа
+func (r *repository) TouchLastUsed(id uint, at time.Time) error {
+	if err := r.db.Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", at).Error; err != nil {
+		r.logger.Warnf("repository.TouchLastUsed: Failed to update API key with ID %d: %v", id, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package apikeys
+
+import (
+	"app/internal/auth"
+	"app/internal/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// keyPrefix — префикс, по которому ключи этого сервиса узнаются в логах и сканерах секретов
+const keyPrefix = "sk_"
+
+// lastUsedPrecision — не чаще, чем раз в этот интервал, обновляется время последнего использования ключа
+const lastUsedPrecision = time.Minute
+
+// scopeRoles — роли, которые получает вызывающий с API-ключом по каждой области доступа
+var scopeRoles = map[string]string{
+	models.APIKeyScopeRead:  auth.RoleAnalyst,
+	models.APIKeyScopeWrite: auth.RoleWriter,
+	models.APIKeyScopeAdmin: auth.RoleAdmin,
+}
+
+// Service — контракт для работы с API-ключами
+type Service interface {
+	CreateKey(key *models.APIKey) error
+	GetKeyByID(id uint) (*models.APIKey, error)
+	ListKeys() ([]models.APIKey, error)
+	RevokeKey(id uint) error
+	Authenticate(key string) (auth.Identity, error)
+	ForTenant(tenantID string) Service
+}
+
+// service — структура, реализующая интерфейс Service
+type service struct {
+	repo   Repository
+	logger *logrus.Logger
+}
+
+// NewService — конструктор service
+func NewService(repo Repository, logger *logrus.Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// ForTenant возвращает Service, работающий только с ключами арендатора tenantID
+func (s *service) ForTenant(tenantID string) Service {
+	return &service{
+		repo:   s.repo.ForTenant(tenantID),
+		logger: s.logger,
+	}
+}
+
+// CreateKey выпускает API-ключ; сам ключ возвращается в поле key только в этом ответе
+func (s *service) CreateKey(key *models.APIKey) error {
+	s.logger.Infof("service.CreateKey: Creating API key %q with scopes %v", key.Name, key.Scopes)
+	if key.ID != 0 {
+		s.logger.Warnf("service.CreateKey: ID should not be provided when creating an API key")
+		return errors.New("ID should not be provided when creating an API key")
+	}
+	key.Name = strings.TrimSpace(key.Name)
+	if key.Name == "" {
+		s.logger.Warnf("service.CreateKey: name is required")
+		return errors.New("name is required")
+	}
+	if len(key.Scopes) == 0 {
+		s.logger.Warnf("service.CreateKey: scopes are required")
+		return errors.New("scopes are required")
+	}
+	for _, scope := range key.Scopes {
+		if _, ok := scopeRoles[scope]; !ok {
+			s.logger.Warnf("service.CreateKey: unsupported scope %q", scope)
+			return fmt.Errorf("unsupported scope %q, must be one of: read, write, admin", scope)
+		}
+	}
+	slices.Sort(key.Scopes)
+	key.Scopes = slices.Compact(key.Scopes)
+	if key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now()) {
+		s.logger.Warnf("service.CreateKey: expires_at must be in the future")
+		return errors.New("expires_at must be in the future")
+	}
+
+	secret, err := newKey()
+	if err != nil {
+		return err
+	}
+	key.Hash = hashKey(secret)
+	key.Prefix = secret[:len(keyPrefix)+6]
+	key.RevokedAt = nil
+	key.LastUsedAt = nil
+	if err := s.repo.Create(key); err != nil {
+		return err
+	}
+	key.Key = secret
+	return nil
+}
+
+// GetKeyByID возвращает API-ключ по ID
+func (s *service) GetKeyByID(id uint) (*models.APIKey, error) {
+	return s.repo.GetByID(id)
+}
+
+// ListKeys возвращает все API-ключи, включая отозванные и истёкшие
+func (s *service) ListKeys() ([]models.APIKey, error) {
+	return s.repo.List()
+}
+
+// RevokeKey отзывает API-ключ; отозванный ключ больше не принимается
+func (s *service) RevokeKey(id uint) error {
+	s.logger.Infof("service.RevokeKey: Revoking API key with ID %d", id)
+	return s.repo.Revoke(id, time.Now())
+}
+
+// Authenticate проверяет API-ключ и возвращает Identity с ролями по его областям доступа и арендатором ключа
+func (s *service) Authenticate(secret string) (auth.Identity, error) {
+	key, err := s.repo.GetByHash(hashKey(secret))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return auth.Identity{}, auth.ErrInvalidAPIKey
+		}
+		return auth.Identity{}, fmt.Errorf("database error: %w", err)
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return auth.Identity{}, fmt.Errorf("%w: key %d is revoked", auth.ErrInvalidAPIKey, key.ID)
+	}
+	if key.ExpiresAt != nil && !now.Before(*key.ExpiresAt) {
+		return auth.Identity{}, fmt.Errorf("%w: key %d has expired", auth.ErrInvalidAPIKey, key.ID)
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedPrecision {
+		// Время последнего использования информационное, ошибка его записи не отклоняет запрос
+		_ = s.repo.TouchLastUsed(key.ID, now)
+	}
+
+	identity := auth.Identity{Subject: fmt.Sprintf("apikey:%d", key.ID), TenantID: key.TenantID}
+	for _, scope := range key.Scopes {
+		if role, ok := scopeRoles[scope]; ok {
+			identity.Roles = append(identity.Roles, role)
+		}
+	}
+	return identity, nil
+}
+
+// newKey генерирует новый API-ключ
+func newKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("apikeys: failed to generate key: %w", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashKey возвращает SHA-256 ключа; ключи случайные и длинные, поэтому медленный хеш не нужен
+func hashKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
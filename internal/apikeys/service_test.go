@@ -0,0 +1,175 @@
+package apikeys_test
+
+import (
+	"app/internal/apikeys"
+	"app/internal/auth"
+	"app/internal/database"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newService подключается к новой бд SQLite в памяти и возвращает сервис ключей арендатора acme
+func newService(t *testing.T) apikeys.Service {
+	t.Helper()
+	dbtest.SQLite(t)
+	return apikeys.NewService(apikeys.NewRepository(dbtest.Logger()), dbtest.Logger()).ForTenant("acme")
+}
+
+func TestAuthenticateScopeRoles(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		roles  []string
+	}{
+		{name: "read", scopes: []string{models.APIKeyScopeRead}, roles: []string{auth.RoleAnalyst}},
+		{name: "write", scopes: []string{models.APIKeyScopeWrite}, roles: []string{auth.RoleWriter}},
+		{name: "admin", scopes: []string{models.APIKeyScopeAdmin}, roles: []string{auth.RoleAdmin}},
+		{name: "read and write", scopes: []string{models.APIKeyScopeWrite, models.APIKeyScopeRead}, roles: []string{auth.RoleAnalyst, auth.RoleWriter}},
+		{name: "duplicate scopes", scopes: []string{models.APIKeyScopeRead, models.APIKeyScopeRead}, roles: []string{auth.RoleAnalyst}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newService(t)
+			key := &models.APIKey{Name: "ci", Scopes: tt.scopes}
+			if err := svc.CreateKey(key); err != nil {
+				t.Fatalf("CreateKey: %v", err)
+			}
+			if !strings.HasPrefix(key.Key, "sk_") || !strings.HasPrefix(key.Key, key.Prefix) {
+				t.Errorf("key %q, prefix %q: want sk_ key starting with its prefix", key.Key, key.Prefix)
+			}
+
+			identity, err := svc.Authenticate(key.Key)
+			if err != nil {
+				t.Fatalf("Authenticate: %v", err)
+			}
+			if !slices.Equal(identity.Roles, tt.roles) {
+				t.Errorf("roles = %v, want %v", identity.Roles, tt.roles)
+			}
+			if identity.TenantID != "acme" || identity.Subject == "" {
+				t.Errorf("identity = %+v, want subject and tenant acme", identity)
+			}
+			if identity.HasRole(auth.RoleUser) {
+				t.Errorf("API key with roles %v must not fall back to the user role", identity.Roles)
+			}
+		})
+	}
+}
+
+func TestCreateKeyValidation(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	tests := []struct {
+		name string
+		key  models.APIKey
+		err  string
+	}{
+		{name: "missing name", key: models.APIKey{Name: "  ", Scopes: []string{models.APIKeyScopeRead}}, err: "name is required"},
+		{name: "missing scopes", key: models.APIKey{Name: "ci"}, err: "scopes are required"},
+		{name: "unknown scope", key: models.APIKey{Name: "ci", Scopes: []string{"owner"}}, err: `unsupported scope "owner"`},
+		{name: "expiry in the past", key: models.APIKey{Name: "ci", Scopes: []string{models.APIKeyScopeRead}, ExpiresAt: &past}, err: "expires_at must be in the future"},
+		{name: "explicit id", key: models.APIKey{ID: 5, Name: "ci", Scopes: []string{models.APIKeyScopeRead}}, err: "ID should not be provided"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newService(t)
+			err := svc.CreateKey(&tt.key)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("CreateKey() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateRejectsKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare меняет выпущенный ключ и возвращает ключ, который предъявит вызывающий
+		prepare func(t *testing.T, svc apikeys.Service, key *models.APIKey) string
+		err     string
+	}{
+		{
+			name: "unknown key",
+			prepare: func(t *testing.T, svc apikeys.Service, key *models.APIKey) string {
+				return key.Key + "x"
+			},
+		},
+		{
+			name: "revoked",
+			prepare: func(t *testing.T, svc apikeys.Service, key *models.APIKey) string {
+				if err := svc.RevokeKey(key.ID); err != nil {
+					t.Fatalf("RevokeKey: %v", err)
+				}
+				return key.Key
+			},
+			err: "is revoked",
+		},
+		{
+			name: "expired",
+			prepare: func(t *testing.T, svc apikeys.Service, key *models.APIKey) string {
+				expired := time.Now().Add(-time.Second)
+				if err := database.Get().Model(&models.APIKey{}).Where("id = ?", key.ID).Update("expires_at", expired).Error; err != nil {
+					t.Fatal(err)
+				}
+				return key.Key
+			},
+			err: "has expired",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := newService(t)
+			key := &models.APIKey{Name: "ci", Scopes: []string{models.APIKeyScopeAdmin}}
+			if err := svc.CreateKey(key); err != nil {
+				t.Fatalf("CreateKey: %v", err)
+			}
+			if _, err := svc.Authenticate(key.Key); err != nil {
+				t.Fatalf("Authenticate before %s: %v", tt.name, err)
+			}
+
+			_, err := svc.Authenticate(tt.prepare(t, svc, key))
+			if !errors.Is(err, auth.ErrInvalidAPIKey) || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Authenticate() error = %v, want ErrInvalidAPIKey with %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuthenticateAcceptsUnexpiredKey(t *testing.T) {
+	svc := newService(t)
+	future := time.Now().Add(time.Hour)
+	key := &models.APIKey{Name: "ci", Scopes: []string{models.APIKeyScopeRead}, ExpiresAt: &future}
+	if err := svc.CreateKey(key); err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+	if _, err := svc.Authenticate(key.Key); err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+
+	stored, err := svc.GetKeyByID(key.ID)
+	if err != nil {
+		t.Fatalf("GetKeyByID: %v", err)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("last_used_at is not recorded after authentication")
+	}
+}
+
+func TestRevokeKeyOfAnotherTenant(t *testing.T) {
+	svc := newService(t)
+	other := apikeys.NewService(apikeys.NewRepository(dbtest.Logger()), dbtest.Logger()).ForTenant("globex")
+	key := &models.APIKey{Name: "ci", Scopes: []string{models.APIKeyScopeRead}}
+	if err := other.CreateKey(key); err != nil {
+		t.Fatalf("CreateKey: %v", err)
+	}
+
+	if err := svc.RevokeKey(key.ID); err == nil {
+		t.Fatal("RevokeKey() of another tenant's key succeeded, want not found")
+	}
+	if _, err := other.Authenticate(key.Key); err != nil {
+		t.Fatalf("key revoked by another tenant: %v", err)
+	}
+}
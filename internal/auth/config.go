@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// minSecretLength — минимальная длина AUTH_JWT_SECRET: ключ HS256 не короче выхода SHA-256
+const minSecretLength = 32
+
+// placeholderSecrets — значения-заглушки из примеров, которые нельзя использовать как секрет
+var placeholderSecrets = []string{"change-me", "changeme", "secret", "password", "jwt-secret", "your-secret", "example"}
+
+// Config — настройки проверки JWT
+type Config struct {
+	Enabled     bool          `config:"enabled" env:"AUTH_ENABLED"`
+	Secret      string        `config:"jwt_secret" env:"AUTH_JWT_SECRET" secret:"true"`
+	JWKSFile    string        `config:"jwks_file" env:"AUTH_JWKS_FILE"`
+	Issuer      string        `config:"issuer" env:"AUTH_ISSUER"`
+	Audience    string        `config:"audience" env:"AUTH_AUDIENCE"`
+	RolesClaim  string        `config:"roles_claim" env:"AUTH_ROLES_CLAIM"`
+	TenantClaim string        `config:"tenant_claim" env:"AUTH_TENANT_CLAIM"`
+	Leeway      time.Duration `config:"leeway" env:"AUTH_LEEWAY"`
+}
+
+// ConfigFrom читает настройки аутентификации из переменных AUTH_*.
+// Аутентификация включена по умолчанию и требует AUTH_JWT_SECRET и/или AUTH_JWKS_FILE
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Enabled:     true,
+		Secret:      getenv("AUTH_JWT_SECRET"),
+		JWKSFile:    getenv("AUTH_JWKS_FILE"),
+		Issuer:      getenv("AUTH_ISSUER"),
+		Audience:    getenv("AUTH_AUDIENCE"),
+		RolesClaim:  getenv("AUTH_ROLES_CLAIM"),
+		TenantClaim: getenv("AUTH_TENANT_CLAIM"),
+	}
+	if cfg.RolesClaim == "" {
+		cfg.RolesClaim = "roles"
+	}
+	if cfg.TenantClaim == "" {
+		cfg.TenantClaim = "tenant_id"
+	}
+
+	if value := getenv("AUTH_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("auth.ConfigFrom: invalid AUTH_ENABLED %q", value)
+		}
+		cfg.Enabled = enabled
+	}
+	if value := getenv("AUTH_LEEWAY"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("auth.ConfigFrom: invalid AUTH_LEEWAY %q", value)
+		}
+		cfg.Leeway = d
+	}
+
+	if !cfg.Enabled {
+		return cfg, nil
+	}
+	if cfg.Secret == "" && cfg.JWKSFile == "" {
+		return cfg, fmt.Errorf("auth.ConfigFrom: AUTH_JWT_SECRET or AUTH_JWKS_FILE is required when authentication is enabled")
+	}
+	if err := validateSecret(cfg.Secret); err != nil {
+		return cfg, fmt.Errorf("auth.ConfigFrom: invalid AUTH_JWT_SECRET: %w", err)
+	}
+	return cfg, nil
+}
+
+// validateSecret отклоняет секрет HS256, который легко подобрать: короткий или заглушку из примеров.
+// Пустой секрет допустим — тогда токены проверяются только по AUTH_JWKS_FILE
+func validateSecret(secret string) error {
+	if secret == "" {
+		return nil
+	}
+	// Заглушкой считается и секрет из одного повторяющегося символа, например "xxxx…"
+	normalized := strings.ToLower(strings.TrimSpace(secret))
+	if normalized == "" || slices.Contains(placeholderSecrets, normalized) || strings.Trim(normalized, normalized[:1]) == "" {
+		return fmt.Errorf("placeholder value, generate one with `openssl rand -hex 32`")
+	}
+	if len(secret) < minSecretLength {
+		return fmt.Errorf("must be at least %d bytes long, got %d", minSecretLength, len(secret))
+	}
+	return nil
+}
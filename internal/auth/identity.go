@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Роли вызывающего
+const (
+	// RoleAdmin — полный доступ ко всем данным
+	RoleAdmin = "admin"
+	// RoleAnalyst — чтение всех данных без права изменения
+	RoleAnalyst = "analyst"
+	// RoleWriter — чтение и изменение подписок всех пользователей без администрирования. Её получает API-ключ
+	// с областью write: analyst не может ничего менять, а admin смог бы выпускать ключи и регистрировать вебхуки
+	RoleWriter = "writer"
+	// RoleUser — доступ только к собственным подпискам; назначается, если в токене нет ролей
+	RoleUser = "user"
+	// RolePlatformAdmin — изменение общих для всех арендаторов каталога сервисов и категорий;
+	// действует только в токене без арендатора, поэтому её не получить ни администратору арендатора, ни API-ключу
+	RolePlatformAdmin = "platform-admin"
+)
+
+// Identity — аутентифицированный вызывающий: subject токена, его роли и арендатор (пустой — не указан)
+type Identity struct {
+	Subject  string
+	Roles    []string
+	TenantID string
+}
+
+// HasRole проверяет, есть ли у вызывающего роль role
+func (i Identity) HasRole(role string) bool {
+	if len(i.Roles) == 0 {
+		return role == RoleUser
+	}
+	return slices.Contains(i.Roles, role)
+}
+
+// CanReadAll проверяет, может ли вызывающий читать данные всех пользователей
+func (i Identity) CanReadAll() bool {
+	return i.HasRole(RoleAdmin) || i.HasRole(RoleAnalyst) || i.HasRole(RoleWriter)
+}
+
+// CanRead проверяет, может ли вызывающий читать данные пользователя userID
+func (i Identity) CanRead(userID string) bool {
+	return i.CanReadAll() || (i.HasRole(RoleUser) && userID == i.Subject)
+}
+
+// CanWrite проверяет, может ли вызывающий изменять данные пользователя userID
+func (i Identity) CanWrite(userID string) bool {
+	return i.HasRole(RoleAdmin) || i.HasRole(RoleWriter) || (i.HasRole(RoleUser) && userID == i.Subject)
+}
+
+// IsPlatformAdmin проверяет, может ли вызывающий менять общие для всех арендаторов данные:
+// у него есть роль platform-admin, и его учётные данные не привязаны к арендатору
+func (i Identity) IsPlatformAdmin() bool {
+	return i.HasRole(RolePlatformAdmin) && i.TenantID == ""
+}
+
+// identityKey — ключ Identity в контексте запроса
+type identityKey struct{}
+
+// WithIdentity возвращает копию ctx с Identity вызывающего
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// FromContext возвращает Identity вызывающего из контекста запроса
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
+
+// setIdentity сохраняет Identity и в gin.Context, и в контексте http-запроса,
+// чтобы она была доступна как обработчикам, так и нижележащим слоям
+func setIdentity(c *gin.Context, identity Identity) {
+	c.Set(identityContextKey, identity)
+	c.Request = c.Request.WithContext(WithIdentity(c.Request.Context(), identity))
+}
+
+// identityContextKey — ключ Identity в gin.Context
+const identityContextKey = "auth.identity"
+
+// IdentityFrom возвращает Identity вызывающего из gin.Context
+func IdentityFrom(c *gin.Context) (Identity, bool) {
+	if value, ok := c.Get(identityContextKey); ok {
+		identity, ok := value.(Identity)
+		return identity, ok
+	}
+	return FromContext(c.Request.Context())
+}
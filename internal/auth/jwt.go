@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Ошибки проверки токена
+var (
+	ErrMissingToken  = errors.New("missing bearer token")
+	ErrInvalidToken  = errors.New("invalid token")
+	ErrInvalidAPIKey = errors.New("invalid api key")
+)
+
+// Authenticator — контракт проверки учётных данных вызывающего
+type Authenticator interface {
+	Authenticate(token string) (Identity, error)
+}
+
+// jwtAuthenticator — проверка JWT, подписанных HS256 общим секретом или RS256 ключом из JWKS
+type jwtAuthenticator struct {
+	secret      []byte
+	keys        map[string]*rsa.PublicKey
+	rolesClaim  string
+	tenantClaim string
+	parser      *jwt.Parser
+}
+
+// NewJWTAuthenticator — конструктор jwtAuthenticator
+func NewJWTAuthenticator(cfg Config) (Authenticator, error) {
+	a := &jwtAuthenticator{
+		secret:      []byte(cfg.Secret),
+		rolesClaim:  cfg.RolesClaim,
+		tenantClaim: cfg.TenantClaim,
+	}
+
+	var methods []string
+	if cfg.Secret != "" {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" {
+		keys, err := LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		a.keys = keys
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("auth.NewJWTAuthenticator: no signing keys configured")
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(options...)
+	return a, nil
+}
+
+// Authenticate проверяет подпись и срок действия токена и возвращает subject, роли и арендатора из него
+func (a *jwtAuthenticator) Authenticate(token string) (Identity, error) {
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, a.key); err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	subject, err := claims.GetSubject()
+	if err != nil || subject == "" {
+		return Identity{}, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	tenantID, _ := claims[a.tenantClaim].(string)
+	return Identity{Subject: subject, Roles: rolesOf(claims[a.rolesClaim]), TenantID: tenantID}, nil
+}
+
+// key выбирает ключ проверки подписи по алгоритму и kid из заголовка токена
+func (a *jwtAuthenticator) key(token *jwt.Token) (interface{}, error) {
+	switch token.Method.Alg() {
+	case jwt.SigningMethodHS256.Alg():
+		return a.secret, nil
+	case jwt.SigningMethodRS256.Alg():
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.keys[kid]; ok {
+			return key, nil
+		}
+		// Токен без kid допустим, если в JWKS единственный ключ
+		if kid == "" && len(a.keys) == 1 {
+			for _, key := range a.keys {
+				return key, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+}
+
+// rolesOf читает роли из claim: массив строк или строка с ролями через пробел
+func rolesOf(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return strings.Fields(value)
+	case []interface{}:
+		roles := make([]string, 0, len(value))
+		for _, item := range value {
+			if role, ok := item.(string); ok && role != "" {
+				roles = append(roles, role)
+			}
+		}
+		return roles
+	}
+	return nil
+}
@@ -0,0 +1,284 @@
+package auth_test
+
+import (
+	"app/internal/auth"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testSecret — секрет HS256, который проходит проверку длины
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// writeJWKS сохраняет открытые ключи keys (ключ map — kid) в JWKS-файл и возвращает путь к нему
+func writeJWKS(t *testing.T, keys map[string]*rsa.PrivateKey) string {
+	t.Helper()
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func rsaKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// sign подписывает claims методом method ключом key; непустой kid попадает в заголовок
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	signingKey, otherKey := rsaKey(t), rsaKey(t)
+	jwks := writeJWKS(t, map[string]*rsa.PrivateKey{"k1": signingKey})
+
+	newAuthenticator := func(cfg auth.Config) auth.Authenticator {
+		a, err := auth.NewJWTAuthenticator(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+	both := newAuthenticator(auth.Config{Secret: testSecret, JWKSFile: jwks, RolesClaim: "roles", TenantClaim: "tenant_id"})
+	rsOnly := newAuthenticator(auth.Config{JWKSFile: jwks, RolesClaim: "roles", TenantClaim: "tenant_id"})
+	strict := newAuthenticator(auth.Config{Secret: testSecret, Issuer: "subs-idp", Audience: "subs-api", RolesClaim: "roles", TenantClaim: "tenant_id"})
+	lenient := newAuthenticator(auth.Config{Secret: testSecret, Leeway: time.Minute, RolesClaim: "roles", TenantClaim: "tenant_id"})
+
+	now := time.Now()
+	claims := func(extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"sub": "alice", "exp": now.Add(time.Hour).Unix(), "roles": []string{"reader", "writer"}, "tenant_id": "acme"}
+		for k, v := range extra {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		auth  auth.Authenticator
+		token string
+		want  auth.Identity
+		err   string
+	}{
+		{
+			name:  "HS256 accepted",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(nil)),
+			want:  auth.Identity{Subject: "alice", Roles: []string{"reader", "writer"}, TenantID: "acme"},
+		},
+		{
+			name:  "RS256 with known kid accepted",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodRS256, signingKey, "k1", claims(nil)),
+			want:  auth.Identity{Subject: "alice", Roles: []string{"reader", "writer"}, TenantID: "acme"},
+		},
+		{
+			name:  "RS256 without kid uses the only key",
+			auth:  rsOnly,
+			token: sign(t, jwt.SigningMethodRS256, signingKey, "", claims(nil)),
+			want:  auth.Identity{Subject: "alice", Roles: []string{"reader", "writer"}, TenantID: "acme"},
+		},
+		{
+			name:  "space separated roles",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"roles": "admin reader", "tenant_id": nil})),
+			want:  auth.Identity{Subject: "alice", Roles: []string{"admin", "reader"}},
+		},
+		{
+			name:  "issuer and audience match",
+			auth:  strict,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"iss": "subs-idp", "aud": "subs-api"})),
+			want:  auth.Identity{Subject: "alice", Roles: []string{"reader", "writer"}, TenantID: "acme"},
+		},
+		{
+			name:  "expired within leeway accepted",
+			auth:  lenient,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()})),
+			want:  auth.Identity{Subject: "alice", Roles: []string{"reader", "writer"}, TenantID: "acme"},
+		},
+		{
+			name:  "expired",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()})),
+			err:   "token is expired",
+		},
+		{
+			name:  "expired beyond leeway",
+			auth:  lenient,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": now.Add(-2 * time.Minute).Unix()})),
+			err:   "token is expired",
+		},
+		{
+			name:  "missing exp",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"exp": nil})),
+			err:   "exp claim is required",
+		},
+		{
+			name:  "not valid yet",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"nbf": now.Add(time.Hour).Unix()})),
+			err:   "token is not valid yet",
+		},
+		{
+			name:  "wrong secret",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodHS256, []byte(strings.Repeat("x", 31)+"y"), "", claims(nil)),
+			err:   "signature is invalid",
+		},
+		{
+			name:  "wrong alg HS384",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodHS384, []byte(testSecret), "", claims(nil)),
+			err:   "signing method HS384 is invalid",
+		},
+		{
+			name:  "wrong alg none",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claims(nil)),
+			err:   "signing method none is invalid",
+		},
+		{
+			name:  "HS256 rejected without secret",
+			auth:  rsOnly,
+			token: sign(t, jwt.SigningMethodHS256, signingKey.PublicKey.N.Bytes(), "k1", claims(nil)),
+			err:   "signing method HS256 is invalid",
+		},
+		{
+			name:  "RS256 rejected without JWKS",
+			auth:  strict,
+			token: sign(t, jwt.SigningMethodRS256, signingKey, "k1", claims(jwt.MapClaims{"iss": "subs-idp", "aud": "subs-api"})),
+			err:   "signing method RS256 is invalid",
+		},
+		{
+			name:  "unknown kid",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodRS256, otherKey, "k2", claims(nil)),
+			err:   `unknown key id "k2"`,
+		},
+		{
+			name:  "known kid signed by another key",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodRS256, otherKey, "k1", claims(nil)),
+			err:   "verification error",
+		},
+		{
+			name:  "wrong issuer",
+			auth:  strict,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"iss": "evil", "aud": "subs-api"})),
+			err:   "token has invalid issuer",
+		},
+		{
+			name:  "wrong audience",
+			auth:  strict,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"iss": "subs-idp", "aud": "other-api"})),
+			err:   "token has invalid audience",
+		},
+		{
+			name:  "missing sub",
+			auth:  both,
+			token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "", claims(jwt.MapClaims{"sub": nil})),
+			err:   "missing sub claim",
+		},
+		{
+			name:  "malformed",
+			auth:  both,
+			token: "not.a.token",
+			err:   "token is malformed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.auth.Authenticate(tt.token)
+			if tt.err != "" {
+				if !errors.Is(err, auth.ErrInvalidToken) || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Authenticate() error = %v, want ErrInvalidToken with %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate(): %v", err)
+			}
+			if got.Subject != tt.want.Subject || got.TenantID != tt.want.TenantID || !slices.Equal(got.Roles, tt.want.Roles) {
+				t.Errorf("Authenticate() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFromSecret(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		err  string
+	}{
+		{name: "strong secret", env: map[string]string{"AUTH_JWT_SECRET": testSecret}},
+		{name: "jwks only", env: map[string]string{"AUTH_JWKS_FILE": "/etc/subs/jwks.json"}},
+		{name: "disabled without keys", env: map[string]string{"AUTH_ENABLED": "false"}},
+		{name: "weak secret ignored when disabled", env: map[string]string{"AUTH_ENABLED": "false", "AUTH_JWT_SECRET": "change-me"}},
+		{name: "no keys", env: map[string]string{}, err: "AUTH_JWT_SECRET or AUTH_JWKS_FILE is required"},
+		{name: "placeholder", env: map[string]string{"AUTH_JWT_SECRET": "change-me"}, err: "placeholder value"},
+		{name: "placeholder in upper case", env: map[string]string{"AUTH_JWT_SECRET": "ChangeMe"}, err: "placeholder value"},
+		{name: "repeated character", env: map[string]string{"AUTH_JWT_SECRET": strings.Repeat("x", 64)}, err: "placeholder value"},
+		{name: "too short", env: map[string]string{"AUTH_JWT_SECRET": "0123456789abcdef"}, err: "at least 32 bytes"},
+		{name: "invalid leeway", env: map[string]string{"AUTH_JWT_SECRET": testSecret, "AUTH_LEEWAY": "-1s"}, err: "invalid AUTH_LEEWAY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.ConfigFrom(func(key string) string { return tt.env[key] })
+			if tt.err == "" && err != nil {
+				t.Fatalf("ConfigFrom(): %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("ConfigFrom() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
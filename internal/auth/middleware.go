@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"app/internal/logging"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// APIKeyHeader — заголовок с API-ключом сервисного клиента
+const APIKeyHeader = "X-API-Key"
+
+// Middleware проверяет API-ключ из заголовка X-API-Key или токен из заголовка Authorization: Bearer <token>
+// и сохраняет Identity вызывающего в контексте запроса; без валидных учётных данных запрос отклоняется с 401.
+// apiKeys может быть nil, тогда принимаются только токены
+func Middleware(bearer, apiKeys Authenticator, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var identity Identity
+		var err error
+		if key := c.GetHeader(APIKeyHeader); key != "" && apiKeys != nil {
+			identity, err = apiKeys.Authenticate(key)
+		} else {
+			identity, err = authenticate(c, bearer)
+		}
+		if err != nil {
+			var unauthorized error
+			for _, sentinel := range []error{ErrMissingToken, ErrInvalidToken, ErrInvalidAPIKey} {
+				if errors.Is(err, sentinel) {
+					unauthorized = sentinel
+				}
+			}
+			if unauthorized == nil {
+				logging.From(c.Request.Context(), logger).Errorf("auth.Middleware: Failed to authenticate %s %s: %v", c.Request.Method, c.FullPath(), err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+				return
+			}
+			logging.From(c.Request.Context(), logger).Warnf("auth.Middleware: Rejected %s %s: %v", c.Request.Method, c.FullPath(), err)
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": unauthorized.Error()})
+			return
+		}
+
+		setIdentity(c, identity)
+		c.Next()
+	}
+}
+
+// authenticate извлекает bearer-токен из запроса и проверяет его
+func authenticate(c *gin.Context, authenticator Authenticator) (Identity, error) {
+	header := c.GetHeader("Authorization")
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return Identity{}, ErrMissingToken
+	}
+	return authenticator.Authenticate(strings.TrimSpace(token))
+}
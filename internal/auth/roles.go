@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"app/internal/logging"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RequireRole пропускает запрос, только если у вызывающего есть одна из ролей roles.
+// Без аутентификации (AUTH_ENABLED=false) Identity в контексте нет, и запрос пропускается
+func RequireRole(logger *logrus.Logger, roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := IdentityFrom(c)
+		if ok && !slices.ContainsFunc(roles, identity.HasRole) {
+			logging.From(c.Request.Context(), logger).Warnf("auth.RequireRole: %s is not allowed to %s %s", identity.Subject, c.Request.Method, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequirePlatformAdminForWrites пропускает чтение (GET, HEAD, OPTIONS) всем аутентифицированным вызывающим,
+// а изменяющие запросы — только администратору платформы (Identity.IsPlatformAdmin). Так данные, общие
+// для всех арендаторов, не может изменить администратор одного из них
+func RequirePlatformAdminForWrites(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := IdentityFrom(c)
+		switch {
+		case !ok, identity.IsPlatformAdmin():
+		case c.Request.Method == http.MethodGet, c.Request.Method == http.MethodHead, c.Request.Method == http.MethodOptions:
+		default:
+			logging.From(c.Request.Context(), logger).Warnf("auth.RequirePlatformAdminForWrites: %s of tenant %q is not allowed to %s %s",
+				identity.Subject, identity.TenantID, c.Request.Method, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// RequireUserAccess пропускает запрос к данным пользователя из параметра пути param, только если
+// вызывающий может их читать: это он сам или у него есть роль admin или analyst
+func RequireUserAccess(logger *logrus.Logger, param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		identity, ok := IdentityFrom(c)
+		if ok && !identity.CanRead(c.Param(param)) {
+			logging.From(c.Request.Context(), logger).Warnf("auth.RequireUserAccess: %s is not allowed to access user %s", identity.Subject, c.Param(param))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
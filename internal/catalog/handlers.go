@@ -0,0 +1,386 @@
+package catalog
+
+import (
+	"app/internal/logging"
+	"app/internal/models"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Handlers — контракт для HTTP-обработчиков каталога сервисов
+type Handlers interface {
+	CreateService(c *gin.Context)
+	GetServiceByID(c *gin.Context)
+	UpdateService(c *gin.Context)
+	DeleteService(c *gin.Context)
+	ListServices(c *gin.Context)
+	CreateCategory(c *gin.Context)
+	GetCategoryByID(c *gin.Context)
+	UpdateCategory(c *gin.Context)
+	DeleteCategory(c *gin.Context)
+	ListCategories(c *gin.Context)
+}
+
+// handlers — структура, реализующая интерфейс Handlers
+type handlers struct {
+	service Service
+	logger  *logrus.Logger
+}
+
+// NewHandlers — конструктор handlers
+func NewHandlers(service Service, logger *logrus.Logger) Handlers {
+	return &handlers{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateService godoc
+// @Summary Добавить сервис в каталог
+// @Description Создает запись каталога: каноническое название, алиасы, категория, цена по умолчанию и период списания (month, quarter, year)
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param service body models.Service true "Данные сервиса"
+// @Success 201 {object} models.Service
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /services [post]
+func (h *handlers) CreateService(c *gin.Context) {
+	h.log(c).Info("handlers.CreateService: Creating service")
+	var svc models.Service
+	if err := c.ShouldBindJSON(&svc); err != nil {
+		h.log(c).Errorf("handlers.CreateService: Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.CreateService(&svc); err != nil {
+		h.log(c).Errorf("handlers.CreateService: Failed to create service: %v", err)
+		h.writeError(c, err, "service", http.StatusBadRequest)
+		return
+	}
+
+	h.log(c).Infof("handlers.CreateService: Service created successfully with ID %d", svc.ID)
+	c.JSON(http.StatusCreated, svc)
+}
+
+// GetServiceByID godoc
+// @Summary Получить сервис по ID
+// @Description Возвращает запись каталога сервисов вместе с алиасами
+// @Tags services
+// @Produce json
+// @Param id path int true "ID сервиса"
+// @Success 200 {object} models.Service
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /services/{id} [get]
+func (h *handlers) GetServiceByID(c *gin.Context) {
+	h.log(c).Info("handlers.GetServiceByID: Fetching service by ID")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.GetServiceByID: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	svc, err := h.service.GetServiceByID(c.Request.Context(), uint(id))
+	if err != nil {
+		h.log(c).Warnf("handlers.GetServiceByID: Failed to fetch service with ID %d: %v", id, err)
+		h.writeError(c, err, "service", http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, svc)
+}
+
+// UpdateService godoc
+// @Summary Обновить сервис
+// @Description Обновляет запись каталога; список алиасов заменяется целиком, новое название распространяется на подписки
+// @Tags services
+// @Accept json
+// @Produce json
+// @Param id path int true "ID сервиса"
+// @Param service body models.Service true "Обновленные данные сервиса"
+// @Success 200 {object} models.Service
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /services/{id} [put]
+func (h *handlers) UpdateService(c *gin.Context) {
+	h.log(c).Info("handlers.UpdateService: Updating service")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.UpdateService: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var svc models.Service
+	if err := c.ShouldBindJSON(&svc); err != nil {
+		h.log(c).Errorf("handlers.UpdateService: Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Убеждаемся, что ID из URL используется, а не из тела запроса
+	svc.ID = uint(id)
+
+	if err := h.service.UpdateService(&svc); err != nil {
+		h.log(c).Errorf("handlers.UpdateService: Failed to update service with ID %d: %v", id, err)
+		h.writeError(c, err, "service", http.StatusBadRequest)
+		return
+	}
+
+	h.log(c).Infof("handlers.UpdateService: Service with ID %d updated successfully", id)
+	c.JSON(http.StatusOK, svc)
+}
+
+// DeleteService godoc
+// @Summary Удалить сервис
+// @Description Удаляет сервис из каталога, если на него не ссылается ни одна подписка
+// @Tags services
+// @Produce json
+// @Param id path int true "ID сервиса"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /services/{id} [delete]
+func (h *handlers) DeleteService(c *gin.Context) {
+	h.log(c).Info("handlers.DeleteService: Deleting service")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.DeleteService: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.service.DeleteService(uint(id)); err != nil {
+		h.log(c).Warnf("handlers.DeleteService: Failed to delete service with ID %d: %v", id, err)
+		h.writeError(c, err, "service", http.StatusInternalServerError)
+		return
+	}
+
+	h.log(c).Infof("handlers.DeleteService: Service with ID %d deleted successfully", id)
+	c.Status(http.StatusNoContent)
+}
+
+// ListServices godoc
+// @Summary Каталог сервисов
+// @Description Возвращает все сервисы каталога, отсортированные по названию
+// @Tags services
+// @Produce json
+// @Success 200 {array} models.Service
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /services [get]
+func (h *handlers) ListServices(c *gin.Context) {
+	h.log(c).Info("handlers.ListServices: Fetching service catalogue")
+	services, err := h.service.ListServices()
+	if err != nil {
+		h.log(c).Errorf("handlers.ListServices: Failed to fetch service catalogue: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.log(c).Infof("handlers.ListServices: Fetched %d services", len(services))
+	c.JSON(http.StatusOK, services)
+}
+
+// CreateCategory godoc
+// @Summary Создать категорию
+// @Description Создает категорию сервисов; название приводится к нижнему регистру
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param category body models.Category true "Данные категории"
+// @Success 201 {object} models.Category
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /categories [post]
+func (h *handlers) CreateCategory(c *gin.Context) {
+	h.log(c).Info("handlers.CreateCategory: Creating category")
+	var category models.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		h.log(c).Errorf("handlers.CreateCategory: Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.service.CreateCategory(&category); err != nil {
+		h.log(c).Errorf("handlers.CreateCategory: Failed to create category: %v", err)
+		h.writeError(c, err, "category", http.StatusBadRequest)
+		return
+	}
+
+	h.log(c).Infof("handlers.CreateCategory: Category created successfully with ID %d", category.ID)
+	c.JSON(http.StatusCreated, category)
+}
+
+// GetCategoryByID godoc
+// @Summary Получить категорию по ID
+// @Description Возвращает категорию сервисов по её идентификатору
+// @Tags categories
+// @Produce json
+// @Param id path int true "ID категории"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /categories/{id} [get]
+func (h *handlers) GetCategoryByID(c *gin.Context) {
+	h.log(c).Info("handlers.GetCategoryByID: Fetching category by ID")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.GetCategoryByID: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	category, err := h.service.GetCategoryByID(uint(id))
+	if err != nil {
+		h.log(c).Warnf("handlers.GetCategoryByID: Failed to fetch category with ID %d: %v", id, err)
+		h.writeError(c, err, "category", http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// UpdateCategory godoc
+// @Summary Обновить категорию
+// @Description Обновляет категорию; новое название распространяется на сервисы каталога
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Param id path int true "ID категории"
+// @Param category body models.Category true "Обновленные данные категории"
+// @Success 200 {object} models.Category
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /categories/{id} [put]
+func (h *handlers) UpdateCategory(c *gin.Context) {
+	h.log(c).Info("handlers.UpdateCategory: Updating category")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.UpdateCategory: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	var category models.Category
+	if err := c.ShouldBindJSON(&category); err != nil {
+		h.log(c).Errorf("handlers.UpdateCategory: Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Убеждаемся, что ID из URL используется, а не из тела запроса
+	category.ID = uint(id)
+
+	if err := h.service.UpdateCategory(&category); err != nil {
+		h.log(c).Errorf("handlers.UpdateCategory: Failed to update category with ID %d: %v", id, err)
+		h.writeError(c, err, "category", http.StatusBadRequest)
+		return
+	}
+
+	h.log(c).Infof("handlers.UpdateCategory: Category with ID %d updated successfully", id)
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Удалить категорию
+// @Description Удаляет категорию, если в ней нет ни одного сервиса
+// @Tags categories
+// @Produce json
+// @Param id path int true "ID категории"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /categories/{id} [delete]
+func (h *handlers) DeleteCategory(c *gin.Context) {
+	h.log(c).Info("handlers.DeleteCategory: Deleting category")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.DeleteCategory: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+
+	if err := h.service.DeleteCategory(uint(id)); err != nil {
+		h.log(c).Warnf("handlers.DeleteCategory: Failed to delete category with ID %d: %v", id, err)
+		h.writeError(c, err, "category", http.StatusInternalServerError)
+		return
+	}
+
+	h.log(c).Infof("handlers.DeleteCategory: Category with ID %d deleted successfully", id)
+	c.Status(http.StatusNoContent)
+}
+
+// ListCategories godoc
+// @Summary Список категорий
+// @Description Возвращает все категории сервисов
+// @Tags categories
+// @Produce json
+// @Success 200 {array} models.Category
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /categories [get]
+func (h *handlers) ListCategories(c *gin.Context) {
+	h.log(c).Info("handlers.ListCategories: Fetching list of categories")
+	categories, err := h.service.ListCategories()
+	if err != nil {
+		h.log(c).Errorf("handlers.ListCategories: Failed to fetch list of categories: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, categories)
+}
+
+// writeError отвечает статусом, соответствующим ошибке каталога; fallback — для ошибок валидации
+func (h *handlers) writeError(c *gin.Context, err error, entity string, fallback int) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": entity + " not found"})
+	case errors.Is(err, ErrNameTaken), errors.Is(err, ErrServiceInUse),
+		errors.Is(err, ErrCategoryTaken), errors.Is(err, ErrCategoryInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUnknownCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case fallback == http.StatusInternalServerError || strings.Contains(err.Error(), "database"):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	default:
+		c.JSON(fallback, gin.H{"error": err.Error()})
+	}
+}
+
+// log возвращает логгер с полями запроса
+func (h *handlers) log(c *gin.Context) *logrus.Entry {
+	return logging.From(c.Request.Context(), h.logger)
+}
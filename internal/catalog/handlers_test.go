@@ -0,0 +1,108 @@
+package catalog_test
+
+import (
+	"app/internal/auth"
+	"app/internal/catalog"
+	"app/internal/database"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter подключается к новой бд SQLite в памяти и собирает роутер каталога, как в main.go;
+// каждый запрос выполняется от имени identity
+func newRouter(t *testing.T, identity auth.Identity) (*gin.Engine, catalog.Service) {
+	t.Helper()
+	dbtest.SQLite(t)
+	service := catalog.NewService(catalog.NewRepository(dbtest.Logger()), dbtest.Logger())
+	handlers := catalog.NewHandlers(service, dbtest.Logger())
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), identity))
+	})
+	services := router.Group("/services", auth.RequirePlatformAdminForWrites(dbtest.Logger()))
+	services.GET("/:id", handlers.GetServiceByID)
+	services.PUT("/:id", handlers.UpdateService)
+	services.DELETE("/:id", handlers.DeleteService)
+	categories := router.Group("/categories", auth.RequirePlatformAdminForWrites(dbtest.Logger()))
+	categories.POST("", handlers.CreateCategory)
+	return router, service
+}
+
+func TestCatalogueWritesRequirePlatformAdmin(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity auth.Identity
+		want     int
+	}{
+		{name: "admin of another tenant", identity: auth.Identity{Subject: "alice", Roles: []string{auth.RoleAdmin}, TenantID: "tenant-a"}, want: http.StatusForbidden},
+		{name: "admin without tenant", identity: auth.Identity{Subject: "alice", Roles: []string{auth.RoleAdmin}}, want: http.StatusForbidden},
+		{name: "platform admin bound to a tenant", identity: auth.Identity{Subject: "alice", Roles: []string{auth.RolePlatformAdmin}, TenantID: "tenant-a"}, want: http.StatusForbidden},
+		{name: "platform admin", identity: auth.Identity{Subject: "root", Roles: []string{auth.RolePlatformAdmin}}, want: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, service := newRouter(t, tt.identity)
+			svc := models.Service{Name: "Netflix"}
+			if err := service.CreateService(&svc); err != nil {
+				t.Fatalf("CreateService: %v", err)
+			}
+			// Подписка арендатора tenant-b хранит название сервиса из общего каталога
+			sub := models.UserSubs{
+				TenantID: "tenant-b", ServiceName: svc.Name, ServiceID: &svc.ID, Price: 100,
+				UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			}
+			if err := database.Get().Create(&sub).Error; err != nil {
+				t.Fatal(err)
+			}
+			path := fmt.Sprintf("/services/%d", svc.ID)
+
+			got := serve(router, http.MethodPut, path, `{"name":"Netflix Premium"}`)
+			if got.Code != tt.want {
+				t.Fatalf("PUT %s: status %d, want %d (%s)", path, got.Code, tt.want, got.Body)
+			}
+			wantName := "Netflix"
+			if tt.want == http.StatusOK {
+				wantName = "Netflix Premium"
+			}
+			var stored models.UserSubs
+			if err := database.Get().First(&stored, sub.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+			if stored.ServiceName != wantName {
+				t.Errorf("subscription of tenant-b has service %q, want %q", stored.ServiceName, wantName)
+			}
+
+			if tt.want == http.StatusForbidden {
+				if got := serve(router, http.MethodDelete, path, ""); got.Code != http.StatusForbidden {
+					t.Errorf("DELETE %s: status %d, want %d", path, got.Code, http.StatusForbidden)
+				}
+				if got := serve(router, http.MethodPost, "/categories", `{"name":"video"}`); got.Code != http.StatusForbidden {
+					t.Errorf("POST /categories: status %d, want %d", got.Code, http.StatusForbidden)
+				}
+			}
+			// Читать каталог может любой вызывающий
+			if got := serve(router, http.MethodGet, path, ""); got.Code != http.StatusOK || !strings.Contains(got.Body.String(), wantName) {
+				t.Errorf("GET %s: status %d, body %s, want %d with %q", path, got.Code, got.Body, http.StatusOK, wantName)
+			}
+		})
+	}
+}
+
+// serve выполняет запрос и возвращает ответ
+func serve(router http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,330 @@
+package catalog
+
+import (
+	"app/internal/database"
+	"app/internal/models"
+	"app/internal/outbox"
+	"app/internal/tenant"
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Repository — контракт для работы с каталогом сервисов в бд
+type Repository interface {
+	Create(svc *models.Service) error
+	GetByID(ctx context.Context, id uint) (*models.Service, error)
+	GetByNormalizedName(ctx context.Context, name string) (*models.Service, error)
+	Update(svc *models.Service) error
+	Delete(id uint) error
+	List() ([]models.Service, error)
+	CountSubs(id uint) (int64, error)
+	ListUnlinkedServiceNames() ([]string, error)
+	LinkSubs(serviceName string, svc *models.Service) (int64, error)
+	CreateCategory(category *models.Category) error
+	GetCategoryByID(id uint) (*models.Category, error)
+	GetCategoryByName(name string) (*models.Category, error)
+	UpdateCategory(category *models.Category) error
+	DeleteCategory(id uint) error
+	ListCategories() ([]models.Category, error)
+	CountServicesInCategory(name string) (int64, error)
+	ListUnregisteredCategories() ([]string, error)
+}
+
+// repository — структура, реализующая интерфейс Repository
+type repository struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+}
+
+// NewRepository — конструктор repository
+func NewRepository(logger *logrus.Logger) Repository {
+	return &repository{
+		db:     database.Get(),
+		logger: logger,
+	}
+}
+
+// Create создает новую запись models.Service вместе с её алиасами
+func (r *repository) Create(svc *models.Service) error {
+	r.logger.Infof("repository.Create: Creating service %s", svc.Name)
+	if err := r.db.Create(svc).Error; err != nil {
+		r.logger.Errorf("repository.Create: Failed to create service: %v", err)
+		return err
+	}
+	r.logger.Infof("repository.Create: Service created successfully with ID %d", svc.ID)
+	return nil
+}
+
+// GetByID возвращает сервис по ID; запрос отменяется вместе с ctx
+func (r *repository) GetByID(ctx context.Context, id uint) (*models.Service, error) {
+	r.logger.Infof("repository.GetByID: Fetching service with ID %d", id)
+	var svc models.Service
+	if err := r.db.WithContext(ctx).Preload("Aliases").First(&svc, id).Error; err != nil {
+		r.logger.Warnf("repository.GetByID: Failed to fetch service with ID %d: %v", id, err)
+		return nil, err // GORM возвращает gorm.ErrRecordNotFound если запись не найдена
+	}
+	return &svc, nil
+}
+
+// GetByNormalizedName ищет сервис по нормализованному названию или алиасу; запрос отменяется вместе с ctx
+func (r *repository) GetByNormalizedName(ctx context.Context, name string) (*models.Service, error) {
+	r.logger.Infof("repository.GetByNormalizedName: Resolving service %q", name)
+	var svc models.Service
+	err := r.db.WithContext(ctx).Preload("Aliases").
+		Where("normalized_name = ?", name).
+		Or("id IN (?)", r.db.Model(&models.ServiceAlias{}).Select("service_id").Where("normalized_alias = ?", name)).
+		First(&svc).Error
+	if err != nil {
+		return nil, err
+	}
+	return &svc, nil
+}
+
+// Update обновляет сервис и полностью заменяет список его алиасов
+func (r *repository) Update(svc *models.Service) error {
+	r.logger.Infof("repository.Update: Updating service with ID %d", svc.ID)
+	var existing models.Service
+	if err := r.db.First(&existing, svc.ID).Error; err != nil {
+		r.logger.Warnf("repository.Update: Service with ID %d not found: %v", svc.ID, err)
+		return gorm.ErrRecordNotFound
+	}
+
+	// Подписки всех арендаторов ссылаются на общий каталог, поэтому транзакция видит их все
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		if err := tx.Omit("Aliases").Save(svc).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("service_id = ?", svc.ID).Delete(&models.ServiceAlias{}).Error; err != nil {
+			return err
+		}
+		for i := range svc.Aliases {
+			svc.Aliases[i].ID = 0
+			svc.Aliases[i].ServiceID = svc.ID
+		}
+		if len(svc.Aliases) > 0 {
+			if err := tx.Create(&svc.Aliases).Error; err != nil {
+				return err
+			}
+		}
+		// Подписки хранят каноническое название, поэтому переименование распространяем на них
+		_, err := updateSubs(tx, map[string]any{"service_name": svc.Name}, "service_id = ? AND service_name <> ?", svc.ID, svc.Name)
+		return err
+	})
+	if err != nil {
+		r.logger.Errorf("repository.Update: Failed to update service with ID %d: %v", svc.ID, err)
+		return err
+	}
+	r.logger.Infof("repository.Update: Service with ID %d updated successfully", svc.ID)
+	return nil
+}
+
+// Delete удаляет сервис по ID вместе с алиасами
+func (r *repository) Delete(id uint) error {
+	r.logger.Infof("repository.Delete: Deleting service with ID %d", id)
+	var existing models.Service
+	if err := r.db.First(&existing, id).Error; err != nil {
+		r.logger.Warnf("repository.Delete: Service with ID %d not found: %v", id, err)
+		return gorm.ErrRecordNotFound
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("service_id = ?", id).Delete(&models.ServiceAlias{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Service{}, id).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.Delete: Failed to delete service with ID %d: %v", id, err)
+		return err
+	}
+	r.logger.Infof("repository.Delete: Service with ID %d deleted successfully", id)
+	return nil
+}
+
+// List возвращает весь каталог сервисов
+func (r *repository) List() ([]models.Service, error) {
+	r.logger.Info("repository.List: Fetching list of all services")
+	var services []models.Service
+	if err := r.db.Preload("Aliases").Order("name").Find(&services).Error; err != nil {
+		r.logger.Errorf("repository.List: Failed to fetch list of services: %v", err)
+		return nil, err
+	}
+	r.logger.Infof("repository.List: Fetched %d services", len(services))
+	return services, nil
+}
+
+// CountSubs возвращает количество подписок, ссылающихся на сервис
+func (r *repository) CountSubs(id uint) (int64, error) {
+	var count int64
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		return tx.Model(&models.UserSubs{}).Where("service_id = ?", id).Count(&count).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.CountSubs: Failed to count subscriptions of service %d: %v", id, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListUnlinkedServiceNames возвращает названия сервисов из подписок, ещё не привязанных к каталогу
+func (r *repository) ListUnlinkedServiceNames() ([]string, error) {
+	var names []string
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		return tx.Model(&models.UserSubs{}).
+			Where("service_id IS NULL").
+			Distinct("service_name").
+			Pluck("service_name", &names).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.ListUnlinkedServiceNames: Failed to fetch service names: %v", err)
+		return nil, err
+	}
+	return names, nil
+}
+
+// LinkSubs привязывает к сервису каталога все непривязанные подписки с указанным названием;
+// о каждой привязанной подписке пишется событие subscription.updated
+func (r *repository) LinkSubs(serviceName string, svc *models.Service) (int64, error) {
+	var linked int64
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		var err error
+		linked, err = updateSubs(tx, map[string]any{"service_id": svc.ID, "service_name": svc.Name}, "service_id IS NULL AND service_name = ?", serviceName)
+		return err
+	})
+	if err != nil {
+		r.logger.Errorf("repository.LinkSubs: Failed to link subscriptions %q to service %d: %v", serviceName, svc.ID, err)
+		return 0, err
+	}
+	return linked, nil
+}
+
+// updateSubs изменяет поля updates у подписок, отобранных условием where, и в той же транзакции tx пишет
+// в outbox событие subscription.updated на каждую из них, как при изменении подписки через API.
+// Возвращает количество изменённых подписок
+func updateSubs(tx *gorm.DB, updates map[string]any, where string, args ...any) (int64, error) {
+	var ids []uint
+	if err := tx.Model(&models.UserSubs{}).Where(where, args...).Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := tx.Model(&models.UserSubs{}).Where("id IN ?", ids).Updates(updates).Error; err != nil {
+		return 0, err
+	}
+	var subs []models.UserSubs
+	if err := tx.Where("id IN ?", ids).Order("id").Find(&subs).Error; err != nil {
+		return 0, err
+	}
+	for _, sub := range subs {
+		if err := outbox.Write(tx, models.EventSubUpdated, sub); err != nil {
+			return 0, err
+		}
+	}
+	return int64(len(subs)), nil
+}
+
+// CreateCategory создает новую категорию
+func (r *repository) CreateCategory(category *models.Category) error {
+	r.logger.Infof("repository.CreateCategory: Creating category %s", category.Name)
+	if err := r.db.Create(category).Error; err != nil {
+		r.logger.Errorf("repository.CreateCategory: Failed to create category: %v", err)
+		return err
+	}
+	r.logger.Infof("repository.CreateCategory: Category created successfully with ID %d", category.ID)
+	return nil
+}
+
+// GetCategoryByID возвращает категорию по ID
+func (r *repository) GetCategoryByID(id uint) (*models.Category, error) {
+	var category models.Category
+	if err := r.db.First(&category, id).Error; err != nil {
+		r.logger.Warnf("repository.GetCategoryByID: Failed to fetch category with ID %d: %v", id, err)
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetCategoryByName возвращает категорию по названию
+func (r *repository) GetCategoryByName(name string) (*models.Category, error) {
+	var category models.Category
+	if err := r.db.Where("name = ?", name).First(&category).Error; err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// UpdateCategory обновляет категорию; переименование распространяется на сервисы каталога
+func (r *repository) UpdateCategory(category *models.Category) error {
+	r.logger.Infof("repository.UpdateCategory: Updating category with ID %d", category.ID)
+	var existing models.Category
+	if err := r.db.First(&existing, category.ID).Error; err != nil {
+		r.logger.Warnf("repository.UpdateCategory: Category with ID %d not found: %v", category.ID, err)
+		return gorm.ErrRecordNotFound
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(category).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Service{}).Where("category = ?", existing.Name).Update("category", category.Name).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.UpdateCategory: Failed to update category with ID %d: %v", category.ID, err)
+		return err
+	}
+	r.logger.Infof("repository.UpdateCategory: Category with ID %d updated successfully", category.ID)
+	return nil
+}
+
+// DeleteCategory удаляет категорию по ID
+func (r *repository) DeleteCategory(id uint) error {
+	r.logger.Infof("repository.DeleteCategory: Deleting category with ID %d", id)
+	res := r.db.Delete(&models.Category{}, id)
+	if res.Error != nil {
+		r.logger.Errorf("repository.DeleteCategory: Failed to delete category with ID %d: %v", id, res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		r.logger.Warnf("repository.DeleteCategory: Category with ID %d not found", id)
+		return gorm.ErrRecordNotFound
+	}
+	r.logger.Infof("repository.DeleteCategory: Category with ID %d deleted successfully", id)
+	return nil
+}
+
+// ListCategories возвращает все категории
+func (r *repository) ListCategories() ([]models.Category, error) {
+	var categories []models.Category
+	if err := r.db.Order("name").Find(&categories).Error; err != nil {
+		r.logger.Errorf("repository.ListCategories: Failed to fetch list of categories: %v", err)
+		return nil, err
+	}
+	return categories, nil
+}
+
+// CountServicesInCategory возвращает количество сервисов каталога в категории
+func (r *repository) CountServicesInCategory(name string) (int64, error) {
+	var count int64
+	if err := r.db.Model(&models.Service{}).Where("category = ?", name).Count(&count).Error; err != nil {
+		r.logger.Errorf("repository.CountServicesInCategory: Failed to count services in category %s: %v", name, err)
+		return 0, err
+	}
+	return count, nil
+}
+
+// ListUnregisteredCategories возвращает категории сервисов, которых нет в справочнике категорий
+func (r *repository) ListUnregisteredCategories() ([]string, error) {
+	var names []string
+	err := r.db.Model(&models.Service{}).
+		Where("category <> '' AND category NOT IN (?)", r.db.Model(&models.Category{}).Select("name")).
+		Distinct("category").
+		Pluck("category", &names).Error
+	if err != nil {
+		r.logger.Errorf("repository.ListUnregisteredCategories: Failed to fetch categories: %v", err)
+		return nil, err
+	}
+	return names, nil
+}
@@ -0,0 +1,98 @@
+package catalog_test
+
+import (
+	"app/internal/catalog"
+	"app/internal/database"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"app/internal/outbox"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestUpdateWritesEventPerRenamedSub(t *testing.T) {
+	dbtest.SQLite(t)
+	service := catalog.NewService(catalog.NewRepository(dbtest.Logger()), dbtest.Logger())
+	netflix := models.Service{Name: "Netflix"}
+	spotify := models.Service{Name: "Spotify"}
+	for _, svc := range []*models.Service{&netflix, &spotify} {
+		if err := service.CreateService(svc); err != nil {
+			t.Fatalf("CreateService(%s): %v", svc.Name, err)
+		}
+	}
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	subs := []models.UserSubs{
+		{TenantID: "tenant-a", ServiceName: "Netflix", ServiceID: &netflix.ID, Price: 100, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: start},
+		{TenantID: "tenant-b", ServiceName: "Netflix", ServiceID: &netflix.ID, Price: 100, UserID: "0b7ac2d4-4a5e-4c0e-9a8e-3f1f2b6d9e11", StartDate: start},
+		{TenantID: "tenant-a", ServiceName: "Spotify", ServiceID: &spotify.ID, Price: 100, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: start},
+	}
+	if err := database.Get().Create(&subs).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Изменение без переименования не трогает подписки
+	netflix.Aliases = []models.ServiceAlias{{Alias: "Нетфликс"}}
+	if err := service.UpdateService(&netflix); err != nil {
+		t.Fatalf("UpdateService: %v", err)
+	}
+	if events := outboxEvents(t); len(events) != 0 {
+		t.Fatalf("update without rename wrote %d events, want none", len(events))
+	}
+
+	netflix.Name = "Netflix Premium"
+	if err := service.UpdateService(&netflix); err != nil {
+		t.Fatalf("UpdateService: %v", err)
+	}
+	events := outboxEvents(t)
+	if len(events) != 2 {
+		t.Fatalf("rename wrote %d events, want one per subscription of the service (2)", len(events))
+	}
+	for i, event := range events {
+		want := subs[i]
+		if event.Type != models.EventSubUpdated || event.Data.ID != want.ID || event.Data.TenantID != want.TenantID || event.Data.ServiceName != "Netflix Premium" {
+			t.Errorf("event #%d = %s for subscription %d of %s with service %q, want %s for subscription %d of %s with the new name",
+				i+1, event.Type, event.Data.ID, event.Data.TenantID, event.Data.ServiceName, models.EventSubUpdated, want.ID, want.TenantID)
+		}
+	}
+}
+
+// outboxEvents возвращает события outbox всех арендаторов в порядке записи
+func outboxEvents(t *testing.T) []models.SubEvent {
+	t.Helper()
+	var rows []models.OutboxEvent
+	if err := database.Get().Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	events := make([]models.SubEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := outbox.Decode(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestLookupsHonourContext(t *testing.T) {
+	dbtest.SQLite(t)
+	service := catalog.NewService(catalog.NewRepository(dbtest.Logger()), dbtest.Logger())
+	svc := models.Service{Name: "Netflix"}
+	if err := service.CreateService(&svc); err != nil {
+		t.Fatalf("CreateService: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := service.GetServiceByID(ctx, svc.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetServiceByID with a canceled context: error %v, want %v", err, context.Canceled)
+	}
+	if _, err := service.FindService(ctx, "netflix"); !errors.Is(err, context.Canceled) {
+		t.Errorf("FindService with a canceled context: error %v, want %v", err, context.Canceled)
+	}
+	if _, err := service.FindService(context.Background(), "netflix"); err != nil {
+		t.Errorf("FindService: %v", err)
+	}
+}
@@ -0,0 +1,332 @@
+package catalog
+
+import (
+	"app/internal/models"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNameTaken — название или алиас уже принадлежит другому сервису
+	ErrNameTaken = errors.New("service name or alias is already taken")
+	// ErrServiceInUse — на сервис ссылаются подписки, удалить его нельзя
+	ErrServiceInUse = errors.New("service is referenced by subscriptions")
+	// ErrCategoryInUse — в категории есть сервисы, удалить её нельзя
+	ErrCategoryInUse = errors.New("category is assigned to services")
+	// ErrCategoryTaken — категория с таким названием уже существует
+	ErrCategoryTaken = errors.New("category name is already taken")
+	// ErrUnknownCategory — указанной категории нет в справочнике
+	ErrUnknownCategory = errors.New("unknown category")
+	// ErrUnknownService — сервиса нет в каталоге
+	ErrUnknownService = errors.New("unknown service")
+)
+
+// Normalize приводит название сервиса к виду, по которому сравниваются названия и алиасы
+func Normalize(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// Service — контракт для работы с каталогом сервисов
+type Service interface {
+	CreateService(svc *models.Service) error
+	GetServiceByID(ctx context.Context, id uint) (*models.Service, error)
+	UpdateService(svc *models.Service) error
+	DeleteService(id uint) error
+	ListServices() ([]models.Service, error)
+	FindService(ctx context.Context, name string) (*models.Service, error)
+	BackfillSubs() (int64, error)
+	CreateCategory(category *models.Category) error
+	GetCategoryByID(id uint) (*models.Category, error)
+	UpdateCategory(category *models.Category) error
+	DeleteCategory(id uint) error
+	ListCategories() ([]models.Category, error)
+	BackfillCategories() (int64, error)
+}
+
+// service — структура, реализующая интерфейс Service
+type service struct {
+	repo   Repository
+	logger *logrus.Logger
+}
+
+// NewService — конструктор service
+func NewService(repo Repository, logger *logrus.Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateService создает новый сервис в каталоге с валидацией
+func (s *service) CreateService(svc *models.Service) error {
+	s.logger.Infof("service.CreateService: Creating service %s", svc.Name)
+	if svc.ID != 0 {
+		s.logger.Warnf("service.CreateService: ID should not be provided when creating a service")
+		return errors.New("ID should not be provided when creating a service")
+	}
+	if err := s.prepare(svc); err != nil {
+		return err
+	}
+	return s.repo.Create(svc)
+}
+
+// GetServiceByID возвращает сервис по ID
+func (s *service) GetServiceByID(ctx context.Context, id uint) (*models.Service, error) {
+	s.logger.Infof("service.GetServiceByID: Fetching service with ID %d", id)
+	return s.repo.GetByID(ctx, id)
+}
+
+// UpdateService обновляет сервис каталога с валидацией
+func (s *service) UpdateService(svc *models.Service) error {
+	s.logger.Infof("service.UpdateService: Updating service with ID %d", svc.ID)
+	if svc.ID == 0 {
+		s.logger.Warnf("service.UpdateService: id is required for update")
+		return errors.New("id is required for update")
+	}
+	if err := s.prepare(svc); err != nil {
+		return err
+	}
+	return s.repo.Update(svc)
+}
+
+// DeleteService удаляет сервис, если на него не ссылается ни одна подписка
+func (s *service) DeleteService(id uint) error {
+	s.logger.Infof("service.DeleteService: Deleting service with ID %d", id)
+	count, err := s.repo.CountSubs(id)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		s.logger.Warnf("service.DeleteService: Service with ID %d is referenced by %d subscriptions", id, count)
+		return ErrServiceInUse
+	}
+	return s.repo.Delete(id)
+}
+
+// ListServices возвращает весь каталог сервисов
+func (s *service) ListServices() ([]models.Service, error) {
+	s.logger.Infof("service.ListServices: Fetching list of all services")
+	return s.repo.List()
+}
+
+// FindService ищет сервис по названию или алиасу без учёта регистра и лишних пробелов
+func (s *service) FindService(ctx context.Context, name string) (*models.Service, error) {
+	return s.repo.GetByNormalizedName(ctx, Normalize(name))
+}
+
+// registerService ищет сервис по названию или алиасу, а если его нет — регистрирует новый.
+// Используется только BackfillSubs для подписок, созданных до появления каталога:
+// новые подписки ссылаются лишь на сервисы, уже добавленные в каталог
+func (s *service) registerService(name string) (*models.Service, error) {
+	svc, err := s.FindService(context.Background(), name)
+	if err == nil {
+		return svc, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	s.logger.Infof("service.registerService: Service %q is not in the catalogue, registering it", name)
+	svc = &models.Service{Name: name}
+	if err := s.prepare(svc); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(svc); err != nil {
+		// Сервис мог быть создан параллельным запросом
+		if existing, findErr := s.FindService(context.Background(), name); findErr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	return svc, nil
+}
+
+// BackfillSubs привязывает к каталогу подписки, созданные до его появления
+func (s *service) BackfillSubs() (int64, error) {
+	s.logger.Info("service.BackfillSubs: Linking subscriptions to the service catalogue")
+	names, err := s.repo.ListUnlinkedServiceNames()
+	if err != nil {
+		return 0, err
+	}
+
+	var linked int64
+	for _, name := range names {
+		if Normalize(name) == "" {
+			continue
+		}
+		svc, err := s.registerService(name)
+		if err != nil {
+			s.logger.Errorf("service.BackfillSubs: Failed to resolve service %q: %v", name, err)
+			return linked, err
+		}
+		n, err := s.repo.LinkSubs(name, svc)
+		if err != nil {
+			return linked, err
+		}
+		linked += n
+	}
+
+	s.logger.Infof("service.BackfillSubs: Linked %d subscriptions", linked)
+	return linked, nil
+}
+
+// prepare валидирует сервис, нормализует название и алиасы и проверяет их уникальность
+func (s *service) prepare(svc *models.Service) error {
+	svc.Name = strings.Join(strings.Fields(svc.Name), " ")
+	if svc.Name == "" {
+		s.logger.Warnf("service.prepare: name is required")
+		return errors.New("name is required")
+	}
+	if svc.BillingPeriod == "" {
+		svc.BillingPeriod = models.BillingMonth
+	}
+	if !svc.BillingPeriod.Valid() {
+		s.logger.Warnf("service.prepare: invalid billing_period %q", svc.BillingPeriod)
+		return errors.New("billing_period must be one of: month, quarter, year")
+	}
+	svc.Category = Normalize(svc.Category)
+	if svc.Category != "" {
+		if _, err := s.repo.GetCategoryByName(svc.Category); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				s.logger.Warnf("service.prepare: unknown category %q", svc.Category)
+				return ErrUnknownCategory
+			}
+			return err
+		}
+	}
+	svc.NormalizedName = Normalize(svc.Name)
+
+	seen := map[string]bool{svc.NormalizedName: true}
+	aliases := make([]models.ServiceAlias, 0, len(svc.Aliases))
+	for _, a := range svc.Aliases {
+		normalized := Normalize(a.Alias)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		seen[normalized] = true
+		aliases = append(aliases, models.ServiceAlias{
+			Alias:           strings.TrimSpace(a.Alias),
+			NormalizedAlias: normalized,
+		})
+	}
+	svc.Aliases = aliases
+
+	// Название и алиасы не должны совпадать с названиями и алиасами других сервисов
+	for normalized := range seen {
+		other, err := s.repo.GetByNormalizedName(context.Background(), normalized)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if other.ID != svc.ID {
+			s.logger.Warnf("service.prepare: %q is already used by service %d", normalized, other.ID)
+			return ErrNameTaken
+		}
+	}
+	return nil
+}
+
+// CreateCategory создает новую категорию с валидацией
+func (s *service) CreateCategory(category *models.Category) error {
+	s.logger.Infof("service.CreateCategory: Creating category %s", category.Name)
+	if category.ID != 0 {
+		s.logger.Warnf("service.CreateCategory: ID should not be provided when creating a category")
+		return errors.New("ID should not be provided when creating a category")
+	}
+	if err := s.prepareCategory(category); err != nil {
+		return err
+	}
+	return s.repo.CreateCategory(category)
+}
+
+// GetCategoryByID возвращает категорию по ID
+func (s *service) GetCategoryByID(id uint) (*models.Category, error) {
+	s.logger.Infof("service.GetCategoryByID: Fetching category with ID %d", id)
+	return s.repo.GetCategoryByID(id)
+}
+
+// UpdateCategory обновляет категорию с валидацией
+func (s *service) UpdateCategory(category *models.Category) error {
+	s.logger.Infof("service.UpdateCategory: Updating category with ID %d", category.ID)
+	if category.ID == 0 {
+		s.logger.Warnf("service.UpdateCategory: id is required for update")
+		return errors.New("id is required for update")
+	}
+	if err := s.prepareCategory(category); err != nil {
+		return err
+	}
+	return s.repo.UpdateCategory(category)
+}
+
+// DeleteCategory удаляет категорию, если в ней нет ни одного сервиса
+func (s *service) DeleteCategory(id uint) error {
+	s.logger.Infof("service.DeleteCategory: Deleting category with ID %d", id)
+	category, err := s.repo.GetCategoryByID(id)
+	if err != nil {
+		return err
+	}
+	count, err := s.repo.CountServicesInCategory(category.Name)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		s.logger.Warnf("service.DeleteCategory: Category %s is assigned to %d services", category.Name, count)
+		return ErrCategoryInUse
+	}
+	return s.repo.DeleteCategory(id)
+}
+
+// ListCategories возвращает все категории
+func (s *service) ListCategories() ([]models.Category, error) {
+	s.logger.Infof("service.ListCategories: Fetching list of all categories")
+	return s.repo.ListCategories()
+}
+
+// BackfillCategories регистрирует в справочнике категории, уже указанные у сервисов каталога
+func (s *service) BackfillCategories() (int64, error) {
+	s.logger.Info("service.BackfillCategories: Registering categories used by services")
+	names, err := s.repo.ListUnregisteredCategories()
+	if err != nil {
+		return 0, err
+	}
+
+	var created int64
+	for _, name := range names {
+		if err := s.repo.CreateCategory(&models.Category{Name: name}); err != nil {
+			return created, err
+		}
+		created++
+	}
+
+	s.logger.Infof("service.BackfillCategories: Registered %d categories", created)
+	return created, nil
+}
+
+// prepareCategory валидирует категорию и проверяет уникальность её названия
+func (s *service) prepareCategory(category *models.Category) error {
+	category.Name = Normalize(category.Name)
+	if category.Name == "" {
+		s.logger.Warnf("service.prepareCategory: name is required")
+		return errors.New("name is required")
+	}
+	category.Description = strings.TrimSpace(category.Description)
+
+	other, err := s.repo.GetCategoryByName(category.Name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if other.ID != category.ID {
+		s.logger.Warnf("service.prepareCategory: category %q already exists with ID %d", category.Name, other.ID)
+		return ErrCategoryTaken
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"app/internal/auth"
+	"app/internal/database"
+	"app/internal/idempotency"
+	"app/internal/logging"
+	"app/internal/metrics"
+	"app/internal/notify"
+	"app/internal/outbox"
+	"app/internal/ratelimit"
+	"app/internal/server"
+	"app/internal/timeout"
+	"app/internal/tracing"
+	"app/internal/webhooks"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Config — настройки сервиса, собранные из значений по умолчанию, файла конфигурации,
+// переменных окружения и флагов командной строки
+type Config struct {
+	Server      server.Config      `config:"server"`
+	Database    database.Config    `config:"database"`
+	Log         logging.Config     `config:"log"`
+	Auth        auth.Config        `config:"auth"`
+	Timeout     timeout.Config     `config:"timeout"`
+	RateLimit   ratelimit.Config   `config:"ratelimit"`
+	Idempotency idempotency.Config `config:"idempotency"`
+	Notify      notify.Config      `config:"notify"`
+	Webhooks    webhooks.Config    `config:"webhooks"`
+	Outbox      outbox.Config      `config:"outbox"`
+	Metrics     metrics.Config     `config:"metrics"`
+	Tracing     tracing.Config     `config:"tracing"`
+
+	// values — итоговые значения настроек, из которых собран Config
+	values *values
+}
+
+// setting — настройка: ключ в файле и флаге (<раздел>.<имя>) и переменная окружения
+type setting struct {
+	Key    string
+	Env    string
+	Secret bool
+}
+
+// settings — все известные настройки в порядке полей Config
+var settings = collectSettings()
+
+// collectSettings собирает настройки из тегов config, env и secret полей разделов Config
+func collectSettings() []setting {
+	var result []setting
+	sections := reflect.TypeOf(Config{})
+	for i := 0; i < sections.NumField(); i++ {
+		section := sections.Field(i)
+		prefix := section.Tag.Get("config")
+		if prefix == "" {
+			continue
+		}
+		for j := 0; j < section.Type.NumField(); j++ {
+			field := section.Type.Field(j)
+			name, env := field.Tag.Get("config"), field.Tag.Get("env")
+			if name == "" || env == "" {
+				continue
+			}
+			result = append(result, setting{
+				Key:    prefix + "." + name,
+				Env:    env,
+				Secret: field.Tag.Get("secret") == "true",
+			})
+		}
+	}
+	return result
+}
+
+// build разбирает значения настроек каждого раздела и проверяет их согласованность
+func build(v *values) (*Config, error) {
+	cfg := &Config{values: v}
+	var errs []error
+	collect := func(err error) {
+		if err != nil {
+			errs = append(errs, v.explain(err))
+		}
+	}
+
+	var err error
+	cfg.Server, err = server.ConfigFrom(v.get)
+	collect(err)
+	cfg.Database, err = database.ConfigFrom(v.get)
+	collect(err)
+	cfg.Log, err = logging.ConfigFrom(v.get)
+	collect(err)
+	cfg.Auth, err = auth.ConfigFrom(v.get)
+	collect(err)
+	cfg.Timeout, err = timeout.ConfigFrom(v.get)
+	collect(err)
+	cfg.RateLimit, err = ratelimit.ConfigFrom(v.get)
+	collect(err)
+	cfg.Idempotency, err = idempotency.ConfigFrom(v.get)
+	collect(err)
+	cfg.Notify, err = notify.ConfigFrom(v.get)
+	collect(err)
+	cfg.Webhooks, err = webhooks.ConfigFrom(v.get)
+	collect(err)
+	cfg.Outbox, err = outbox.ConfigFrom(v.get)
+	collect(err)
+	cfg.Metrics, err = metrics.ConfigFrom(v.get)
+	collect(err)
+	cfg.Tracing, err = tracing.ConfigFrom(v.get)
+	collect(err)
+
+	if len(errs) == 0 {
+		collect(cfg.validate())
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// validate проверяет настройки, которые зависят друг от друга
+func (c *Config) validate() error {
+	var errs []error
+	if c.Server.WriteTimeout > 0 && c.Timeout.Default >= c.Server.WriteTimeout {
+		errs = append(errs, fmt.Errorf("config: REQUEST_TIMEOUT (%s) must be shorter than SERVER_WRITE_TIMEOUT (%s), otherwise responses to slow requests are cut off", c.Timeout.Default, c.Server.WriteTimeout))
+	}
+	if c.Timeout.Default > 0 && c.Idempotency.Lease <= c.Timeout.Default {
+		errs = append(errs, fmt.Errorf("config: IDEMPOTENCY_LEASE (%s) must be longer than REQUEST_TIMEOUT (%s), otherwise a retry may run while the first request is still in progress", c.Idempotency.Lease, c.Timeout.Default))
+	}
+	if c.Server.DrainDelay > 0 && c.Server.DrainDelay >= c.Server.ShutdownGracePeriod {
+		errs = append(errs, fmt.Errorf("config: SHUTDOWN_DRAIN_DELAY (%s) must be shorter than SHUTDOWN_GRACE_PERIOD (%s)", c.Server.DrainDelay, c.Server.ShutdownGracePeriod))
+	}
+	if c.Notify.Enabled {
+		for _, channel := range c.Notify.Channels {
+			switch strings.TrimSpace(channel) {
+			case "webhook":
+				if c.Notify.WebhookURL == "" {
+					errs = append(errs, errors.New("config: NOTIFY_WEBHOOK_URL is required for the webhook notification channel"))
+				}
+			case "smtp":
+				if c.Notify.SMTPHost == "" || c.Notify.SMTPFrom == "" || len(c.Notify.SMTPTo) == 0 {
+					errs = append(errs, errors.New("config: NOTIFY_SMTP_HOST, NOTIFY_SMTP_FROM and NOTIFY_SMTP_TO are required for the smtp notification channel"))
+				}
+			}
+		}
+	}
+	if c.Outbox.Publisher == "nats" && c.Outbox.NATSURL == "" {
+		errs = append(errs, errors.New("config: NATS_URL is required when OUTBOX_PUBLISHER=nats"))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,273 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joho/godotenv"
+	toml "github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Источники значений настроек в порядке возрастания приоритета; незаданные настройки берут значения по умолчанию
+const (
+	originFile   = "file"
+	originDotenv = ".env"
+	originEnv    = "env"
+	originFlag   = "flag"
+)
+
+// FileEnv — переменная окружения с путём к файлу конфигурации, если он не задан флагом --config
+const FileEnv = "CONFIG_FILE"
+
+// dotenvFile — файл с переменными окружения для локального запуска
+const dotenvFile = ".env"
+
+// values — значения настроек по имени переменной окружения и их источники
+type values struct {
+	byEnv  map[string]string
+	origin map[string]string
+	// file — путь к прочитанному файлу конфигурации
+	file string
+}
+
+// get возвращает значение настройки env; незаданная настройка — пустая строка, и раздел берёт значение по умолчанию
+func (v *values) get(env string) string {
+	return v.byEnv[env]
+}
+
+// set записывает значение настройки env из источника origin
+func (v *values) set(env, value, origin string) {
+	v.byEnv[env] = value
+	v.origin[env] = origin
+}
+
+// source описывает, откуда взято значение настройки s
+func (v *values) source(s setting) string {
+	switch v.origin[s.Env] {
+	case originFile:
+		return fmt.Sprintf("%s in %s", s.Key, v.file)
+	case originDotenv:
+		return fmt.Sprintf("%s in %s", s.Env, dotenvFile)
+	case originEnv:
+		return fmt.Sprintf("environment variable %s", s.Env)
+	case originFlag:
+		return fmt.Sprintf("flag --%s", s.Key)
+	}
+	return ""
+}
+
+// explain дополняет ошибку раздела источниками упомянутых в ней настроек
+func (v *values) explain(err error) error {
+	var sources []string
+	for _, s := range settings {
+		if mentions(err.Error(), s.Env) {
+			if source := v.source(s); source != "" {
+				sources = append(sources, source)
+			}
+		}
+	}
+	if len(sources) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (set by %s)", err, strings.Join(sources, ", "))
+}
+
+// mentions проверяет, что в сообщении message упомянута переменная env целиком, а не как часть более длинного имени
+func mentions(message, env string) bool {
+	for rest := message; ; {
+		i := strings.Index(rest, env)
+		if i < 0 {
+			return false
+		}
+		end := i + len(env)
+		if (i == 0 || !isNameChar(rest[i-1])) && (end == len(rest) || !isNameChar(rest[end])) {
+			return true
+		}
+		rest = rest[end:]
+	}
+}
+
+// isNameChar проверяет, может ли символ входить в имя переменной окружения
+func isNameChar(c byte) bool {
+	return c == '_' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// Load собирает настройки по возрастанию приоритета: значения по умолчанию, файл конфигурации
+// (--config или CONFIG_FILE, YAML или TOML), файл .env, переменные окружения и флаги --<раздел>.<имя>.
+// На -h/--help печатает справку по флагам и возвращает flag.ErrHelp
+func Load(args []string, output io.Writer) (*Config, error) {
+	v := &values{byEnv: map[string]string{}, origin: map[string]string{}}
+
+	program := filepath.Base(os.Args[0])
+	flags := flag.NewFlagSet(program, flag.ContinueOnError)
+	flags.SetOutput(output)
+	configFile := flags.String("config", os.Getenv(FileEnv), "path to a YAML or TOML config file")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.Key] = flags.String(s.Key, "", "overrides "+s.Env)
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [config print] [--config file] [--<section>.<name> value ...]\n", program)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("config: unexpected arguments %q", flags.Args())
+	}
+
+	if *configFile != "" {
+		if err := v.readFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := os.Stat(dotenvFile); err == nil {
+		dotenv, err := godotenv.Read(dotenvFile)
+		if err != nil {
+			return nil, fmt.Errorf("config: failed to read %s: %w", dotenvFile, err)
+		}
+		for _, s := range settings {
+			if value, ok := dotenv[s.Env]; ok {
+				v.set(s.Env, value, originDotenv)
+			}
+		}
+	}
+
+	for _, s := range settings {
+		if value, ok := os.LookupEnv(s.Env); ok {
+			v.set(s.Env, value, originEnv)
+		}
+	}
+
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.Key == f.Name {
+				v.set(s.Env, *flagValues[s.Key], originFlag)
+				return
+			}
+		}
+	})
+
+	return build(v)
+}
+
+// readFile читает настройки из файла конфигурации path; формат определяется расширением
+func (v *values) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config: failed to read config file: %w", err)
+	}
+
+	tree := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return fmt.Errorf("config: unsupported config file %s: expected .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: failed to parse %s: %w", path, err)
+	}
+
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.Key] = s
+	}
+	v.file = path
+	var errs []error
+	flatten("", tree, func(key string, value any) {
+		s, ok := byKey[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config: unknown setting %q in %s", key, path))
+			return
+		}
+		text, err := scalar(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config: invalid %s in %s: %w", key, path, err))
+			return
+		}
+		v.set(s.Env, text, originFile)
+	})
+	return errors.Join(errs...)
+}
+
+// flatten обходит дерево настроек файла и вызывает visit для каждого значения с ключом вида <раздел>.<имя>.
+// Вложенные таблицы известных настроек (например, timeout.routes) передаются целиком
+func flatten(prefix string, tree map[string]any, visit func(key string, value any)) {
+	keys := make([]string, 0, len(tree))
+	for key := range tree {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		full := key
+		if prefix != "" {
+			full = prefix + "." + key
+		}
+		if nested, ok := tree[key].(map[string]any); ok && !known(full) {
+			flatten(full, nested, visit)
+			continue
+		}
+		visit(full, tree[key])
+	}
+}
+
+// known проверяет, есть ли настройка с ключом key
+func known(key string) bool {
+	for _, s := range settings {
+		if s.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// scalar приводит значение из файла к строке в формате переменной окружения:
+// списки — через запятую, таблицы маршрутов — "<METHOD> <path>=<значение>" через запятую
+func scalar(value any) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(value), nil
+	case []any:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			text, err := scalar(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, text)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(value))
+		for _, key := range keys {
+			text, err := scalar(value[key])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, key+"="+text)
+		}
+		return strings.Join(items, ","), nil
+	}
+	return "", fmt.Errorf("unsupported value %v", value)
+}
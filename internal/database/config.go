@@ -0,0 +1,235 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Драйверы хранилища
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// Config — настройки подключения к бд и пула соединений
+type Config struct {
+	// Driver — хранилище: postgres или sqlite
+	Driver string `config:"driver" env:"DB_DRIVER"`
+	// SQLitePath — файл бд SQLite, ":memory:" — бд в памяти процесса
+	SQLitePath string `config:"sqlite_path" env:"DB_SQLITE_PATH"`
+	Host       string `config:"host" env:"DB_HOST"`
+	Port       string `config:"port" env:"DB_PORT"`
+	User       string `config:"user" env:"DB_USER"`
+	Password   string `config:"password" env:"DB_PASSWORD" secret:"true"`
+	Name       string `config:"name" env:"DB_NAME"`
+	// WorkerRole — роль с BYPASSRLS, выданная DB_USER: от её имени фоновые задачи работают со строками всех арендаторов
+	WorkerRole string `config:"worker_role" env:"DB_WORKER_ROLE"`
+	// MaxOpenConns — предел открытых соединений, 0 — без ограничения
+	MaxOpenConns int `config:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	// MaxIdleConns — сколько простаивающих соединений держать в пуле
+	MaxIdleConns int `config:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	// ConnMaxLifetime — через сколько соединение закрывается и открывается заново, 0 — без ограничения
+	ConnMaxLifetime time.Duration `config:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	// ConnMaxIdleTime — через сколько простоя соединение закрывается, 0 — без ограничения
+	ConnMaxIdleTime time.Duration `config:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+	// SSLMode — режим TLS libpq: disable, allow, prefer, require, verify-ca или verify-full
+	SSLMode string `config:"sslmode" env:"DB_SSLMODE"`
+	// SSLRootCert — CA-сертификат для проверки сервера в режимах verify-ca и verify-full
+	SSLRootCert string `config:"sslrootcert" env:"DB_SSLROOTCERT"`
+	// SSLCert и SSLKey — клиентский сертификат и ключ для аутентификации по сертификату
+	SSLCert string `config:"sslcert" env:"DB_SSLCERT"`
+	SSLKey  string `config:"sslkey" env:"DB_SSLKEY"`
+	// StatementTimeout — предел выполнения одного запроса на стороне сервера, 0 — без ограничения
+	StatementTimeout time.Duration `config:"statement_timeout" env:"DB_STATEMENT_TIMEOUT"`
+	// ReplicaDSN — строка подключения к реплике для чтения списков и отчётов; пустая — все запросы идут в основную бд
+	ReplicaDSN string `config:"replica_dsn" env:"DB_REPLICA_DSN" secret:"true"`
+}
+
+// sslModes — допустимые режимы TLS
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// ConfigFrom читает настройки бд из переменных DB_*. Для Postgres обязательны DB_HOST, DB_PORT, DB_USER, DB_PASSWORD и DB_NAME
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Driver:          getenv("DB_DRIVER"),
+		SQLitePath:      getenv("DB_SQLITE_PATH"),
+		Host:            getenv("DB_HOST"),
+		Port:            getenv("DB_PORT"),
+		User:            getenv("DB_USER"),
+		Password:        getenv("DB_PASSWORD"),
+		Name:            getenv("DB_NAME"),
+		WorkerRole:      getenv("DB_WORKER_ROLE"),
+		MaxOpenConns:    25,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+		SSLMode:         getenv("DB_SSLMODE"),
+		SSLRootCert:     getenv("DB_SSLROOTCERT"),
+		SSLCert:         getenv("DB_SSLCERT"),
+		SSLKey:          getenv("DB_SSLKEY"),
+		ReplicaDSN:      getenv("DB_REPLICA_DSN"),
+	}
+	if cfg.SSLMode == "" {
+		cfg.SSLMode = "disable"
+	}
+	if cfg.Driver == "" {
+		cfg.Driver = DriverPostgres
+	}
+	if cfg.SQLitePath == "" {
+		cfg.SQLitePath = "subs.db"
+	}
+	if cfg.WorkerRole == "" {
+		cfg.WorkerRole = "subs_worker"
+	}
+
+	switch cfg.Driver {
+	case DriverPostgres:
+	case DriverSQLite:
+		if cfg.ReplicaDSN != "" {
+			return cfg, fmt.Errorf("database.ConfigFrom: DB_REPLICA_DSN is not supported with DB_DRIVER %s", DriverSQLite)
+		}
+		return cfg, cfg.parseTuning(getenv)
+	default:
+		return cfg, fmt.Errorf("database.ConfigFrom: invalid DB_DRIVER %q: expected %s or %s", cfg.Driver, DriverPostgres, DriverSQLite)
+	}
+
+	var missing []string
+	for key, value := range map[string]string{
+		"DB_HOST":     cfg.Host,
+		"DB_PORT":     cfg.Port,
+		"DB_USER":     cfg.User,
+		"DB_PASSWORD": cfg.Password,
+		"DB_NAME":     cfg.Name,
+	} {
+		if value == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return cfg, fmt.Errorf("database.ConfigFrom: %s required", strings.Join(missing, ", "))
+	}
+	if err := cfg.parseTuning(getenv); err != nil {
+		return cfg, err
+	}
+
+	if !slices.Contains(sslModes, cfg.SSLMode) {
+		return cfg, fmt.Errorf("database.ConfigFrom: invalid DB_SSLMODE %q: expected one of %s", cfg.SSLMode, strings.Join(sslModes, ", "))
+	}
+	if (cfg.SSLCert == "") != (cfg.SSLKey == "") {
+		return cfg, fmt.Errorf("database.ConfigFrom: DB_SSLCERT and DB_SSLKEY must be set together")
+	}
+	if cfg.SSLMode == "disable" && (cfg.SSLRootCert != "" || cfg.SSLCert != "") {
+		return cfg, fmt.Errorf("database.ConfigFrom: DB_SSLROOTCERT, DB_SSLCERT and DB_SSLKEY require DB_SSLMODE other than disable")
+	}
+	for key, path := range map[string]string{
+		"DB_SSLROOTCERT": cfg.SSLRootCert,
+		"DB_SSLCERT":     cfg.SSLCert,
+		"DB_SSLKEY":      cfg.SSLKey,
+	} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			return cfg, fmt.Errorf("database.ConfigFrom: invalid %s: %w", key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// parseTuning читает настройки пула соединений и statement_timeout
+func (c *Config) parseTuning(getenv func(string) string) error {
+	for key, dst := range map[string]*int{
+		"DB_MAX_OPEN_CONNS": &c.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS": &c.MaxIdleConns,
+	} {
+		value := getenv(key)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("database.ConfigFrom: invalid %s %q", key, value)
+		}
+		*dst = n
+	}
+	for key, dst := range map[string]*time.Duration{
+		"DB_CONN_MAX_LIFETIME":  &c.ConnMaxLifetime,
+		"DB_CONN_MAX_IDLE_TIME": &c.ConnMaxIdleTime,
+		"DB_STATEMENT_TIMEOUT":  &c.StatementTimeout,
+	} {
+		value := getenv(key)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return fmt.Errorf("database.ConfigFrom: invalid %s %q", key, value)
+		}
+		*dst = d
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		return fmt.Errorf("database.ConfigFrom: DB_MAX_IDLE_CONNS (%d) must not exceed DB_MAX_OPEN_CONNS (%d)", c.MaxIdleConns, c.MaxOpenConns)
+	}
+	return nil
+}
+
+// dsn возвращает строку подключения к Postgres с настройками TLS и statement_timeout
+func (c Config) dsn() string {
+	params := [][2]string{
+		{"host", c.Host},
+		{"user", c.User},
+		{"password", c.Password},
+		{"dbname", c.Name},
+		{"port", c.Port},
+		{"sslmode", c.SSLMode},
+		{"sslrootcert", c.SSLRootCert},
+		{"sslcert", c.SSLCert},
+		{"sslkey", c.SSLKey},
+	}
+	parts := make([]string, 0, len(params)+1)
+	for _, param := range params {
+		if param[1] != "" {
+			parts = append(parts, param[0]+"="+quoteDSNValue(param[1]))
+		}
+	}
+	if c.StatementTimeout > 0 {
+		parts = append(parts, "statement_timeout="+strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10))
+	}
+	return strings.Join(parts, " ")
+}
+
+// replicaDSN возвращает строку подключения к реплике, дополненную statement_timeout, если он не задан в ней самой
+func (c Config) replicaDSN() (string, error) {
+	if c.StatementTimeout <= 0 || strings.Contains(c.ReplicaDSN, "statement_timeout") {
+		return c.ReplicaDSN, nil
+	}
+	timeout := strconv.FormatInt(c.StatementTimeout.Milliseconds(), 10)
+	if !strings.Contains(c.ReplicaDSN, "://") {
+		return c.ReplicaDSN + " statement_timeout=" + timeout, nil
+	}
+	u, err := url.Parse(c.ReplicaDSN)
+	if err != nil {
+		return "", fmt.Errorf("invalid DB_REPLICA_DSN: %w", err)
+	}
+	query := u.Query()
+	query.Set("statement_timeout", timeout)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// quoteDSNValue заключает значение в кавычки, если без них строка подключения разберётся неверно
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -2,10 +2,11 @@ package database
 
 import (
 	"app/internal/models"
+	"app/internal/tenant"
+	"context"
+	"errors"
 	"fmt"
-	"os"
 
-	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -13,55 +14,173 @@ import (
 
 var (
 	db *gorm.DB
+	// logger — логгер пакета; до вызова Init используется стандартный логгер logrus
+	logger = logrus.StandardLogger()
 )
 
-func Init() (*gorm.DB, error) {
-	if _, err := os.Stat(".env"); err == nil {
-		if err := godotenv.Load(".env"); err != nil {
-			logrus.Warnf("database.Init: Error loading .env file: %v", err)
+// schemaModels — модели, таблицы которых создаются и проверяются при запуске
+var schemaModels = []interface{}{
+	&models.Category{}, &models.Service{}, &models.ServiceAlias{}, &models.UserSubs{}, &models.PriceChange{},
+	&models.Notification{}, &models.Webhook{}, &models.WebhookDelivery{}, &models.OutboxEvent{}, &models.APIKey{},
+	&models.IdempotencyKey{},
+}
+
+// Init подключается к бд по cfg, настраивает пул соединений, метрики и трассировку запросов и применяет миграции
+func Init(cfg Config, log *logrus.Logger) (*gorm.DB, error) {
+	logger = log
+	var dialector gorm.Dialector
+	switch cfg.Driver {
+	case DriverSQLite:
+		logger.Infof("database.Init: Opening SQLite database %s", cfg.SQLitePath)
+		var err error
+		if dialector, err = openSQLite(cfg); err != nil {
+			return nil, fmt.Errorf("database.Init: Failed to open SQLite database: %w", err)
 		}
+	default:
+		logger.Infof("database.Init: Connecting to %s@%s:%s/%s (sslmode %s)", cfg.User, cfg.Host, cfg.Port, cfg.Name, cfg.SSLMode)
+		//dsn := "host=localhost user=postgres password=discolover dbname=botans port=8001 sslmode=disable"
+		dialector = postgres.Open(cfg.dsn())
 	}
 
-	host := getEnvOrFail("DB_HOST")
-	port := getEnvOrFail("DB_PORT")
-	user := getEnvOrFail("DB_USER")
-	password := getEnvOrFail("DB_PASSWORD")
-	dbname := getEnvOrFail("DB_NAME")
+	conn, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("database.Init: Failed to connect database: %w", err)
+	}
 
-	if host == "" || port == "" || user == "" || password == "" || dbname == "" {
-		logrus.Fatal("database.Init: Не все переменные окружения для подключения к БД установлены")
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return nil, fmt.Errorf("database.Init: Failed to get connection pool: %w", err)
 	}
+	maxOpen, maxIdle, lifetime, idleTime := cfg.poolSettings()
+	sqlDB.SetMaxOpenConns(maxOpen)
+	sqlDB.SetMaxIdleConns(maxIdle)
+	sqlDB.SetConnMaxLifetime(lifetime)
+	sqlDB.SetConnMaxIdleTime(idleTime)
 
-	logrus.Infof("database.Init: Connecting to %s@%s:%s/%s", user, host, port, dbname)
-	// Формируем строку подключения
-	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", host, user, password, dbname, port)
+	if cfg.ReplicaDSN != "" {
+		if err := useReplica(conn, cfg); err != nil {
+			return nil, fmt.Errorf("database.Init: Failed to set up read replica: %w", err)
+		}
+		logger.Info("database.Init: List and report queries are routed to the read replica")
+	}
 
-	//dsn := "host=localhost user=postgres password=discolover dbname=botans port=8001 sslmode=disable"
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-	if err != nil {
-		logrus.Fatalf("database.Init: Failed to connect to database: %v", err)
-		return nil, fmt.Errorf("database.Init: Failed to connect database: %v", err)
+	if err := instrument(conn); err != nil {
+		return nil, fmt.Errorf("database.Init: Failed to set up metrics: %w", err)
+	}
+	if err := traceQueries(conn); err != nil {
+		return nil, fmt.Errorf("database.Init: Failed to set up tracing: %w", err)
 	}
 
-	if err := db.AutoMigrate(&models.UserSubs{}); err != nil {
+	if err := conn.AutoMigrate(schemaModels...); err != nil {
 		return nil, fmt.Errorf("database.Init: Failed to auto-migrate: %w", err)
 	}
 
-	logrus.Info("database.Init: Successfully connected and migrated")
+	if err := tenant.EnableRowLevelSecurity(conn, "user_subs", "price_changes", "outbox_events"); err != nil {
+		return nil, fmt.Errorf("database.Init: Failed to enable row level security: %w", err)
+	}
+	if err := useWorkerRole(conn, cfg); err != nil {
+		return nil, fmt.Errorf("database.Init: Failed to set up worker role: %w", err)
+	}
+
+	logger.Info("database.Init: Successfully connected and migrated")
+	db = conn
 	return db, nil
 }
 
-func Get() *gorm.DB { //  нужно добавить Once из пакета sync, для потокобезопасности
+// useWorkerRole проверяет, что фоновым задачам доступна роль cfg.WorkerRole, и передаёт её tenant.AllTenants.
+// Пользователь, обходящий row-level security, в роли не нуждается, но политики для его запросов не действуют
+func useWorkerRole(conn *gorm.DB, cfg Config) error {
+	tenant.SetWorkerRole("")
+	if conn.Dialector.Name() != "postgres" {
+		return nil
+	}
+	var bypass bool
+	if err := conn.Raw("SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user").Scan(&bypass).Error; err != nil {
+		return err
+	}
+	if bypass {
+		logger.Warnf("database.Init: User %s bypasses row-level security; connect as a role without SUPERUSER and BYPASSRLS", cfg.User)
+		return nil
+	}
+
+	var granted int64
+	err := conn.Raw(
+		"SELECT COUNT(*) FROM pg_roles WHERE rolname = ? AND rolbypassrls AND pg_has_role(current_user, oid, 'MEMBER')", cfg.WorkerRole,
+	).Scan(&granted).Error
+	if err != nil {
+		return err
+	}
+	if granted == 0 {
+		return fmt.Errorf("role %s must exist, have BYPASSRLS and be granted to %s", cfg.WorkerRole, cfg.User)
+	}
+	tenant.SetWorkerRole(cfg.WorkerRole)
+	logger.Infof("database.Init: Background workers use role %s to access all tenants", cfg.WorkerRole)
+	return nil
+}
+
+// Get возвращает подключение, открытое Init
+func Get() *gorm.DB {
 	if db == nil {
-		db, _ = Init()
+		logger.Error("database.Get: Database is not initialized")
 	}
 	return db
 }
 
-func getEnvOrFail(key string) string {
-	value, exists := os.LookupEnv(key)
-	if !exists || value == "" {
-		logrus.Fatalf("database.gerEnvOrFail: Environment variable %s is required", key)
+// Ping проверяет, что бд доступна
+func Ping(ctx context.Context) error {
+	if db == nil {
+		return errors.New("database is not initialized")
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+// CheckSchema проверяет, что миграции применены: у каждой модели есть таблица со всеми её столбцами
+func CheckSchema(ctx context.Context) error {
+	if db == nil {
+		return errors.New("database is not initialized")
+	}
+	conn := db.WithContext(ctx)
+	for _, model := range schemaModels {
+		stmt := &gorm.Statement{DB: conn}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if !conn.Migrator().HasTable(model) {
+			return fmt.Errorf("table %s does not exist", stmt.Schema.Table)
+		}
+		columns, err := conn.Migrator().ColumnTypes(model)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", stmt.Schema.Table, err)
+		}
+		existing := make(map[string]bool, len(columns))
+		for _, column := range columns {
+			existing[column.Name()] = true
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !field.IgnoreMigration && !existing[field.DBName] {
+				return fmt.Errorf("table %s is missing column %s", stmt.Schema.Table, field.DBName)
+			}
+		}
+	}
+	return nil
+}
+
+// Close закрывает пул соединений с бд
+func Close() error {
+	if db == nil {
+		return nil
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("database.Close: %w", err)
+	}
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("database.Close: %w", err)
 	}
-	return value
+	db = nil
+	return nil
 }
@@ -0,0 +1,46 @@
+package dbtest
+
+import (
+	"app/internal/database"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SQLite подключает database к новой бд SQLite в памяти со схемой сервиса и закрывает подключение в конце теста
+func SQLite(t testing.TB) *gorm.DB {
+	t.Helper()
+	return Open(t, map[string]string{
+		"DB_DRIVER":      database.DriverSQLite,
+		"DB_SQLITE_PATH": ":memory:",
+	})
+}
+
+// Open подключает database к бд с настройками env (как переменные окружения DB_*)
+// и закрывает подключение в конце теста
+func Open(t testing.TB, env map[string]string) *gorm.DB {
+	t.Helper()
+	cfg, err := database.ConfigFrom(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := database.Init(cfg, Logger())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+	return db
+}
+
+// Logger возвращает логгер, не засоряющий вывод тестов
+func Logger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	return logger
+}
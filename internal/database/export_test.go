@@ -0,0 +1,18 @@
+package database
+
+import "gorm.io/gorm"
+
+// DSN возвращает строку подключения к Postgres, которую Init строит из cfg
+func DSN(cfg Config) string {
+	return cfg.dsn()
+}
+
+// ReplicaDSN возвращает строку подключения к реплике, которую Init строит из cfg
+func ReplicaDSN(cfg Config) (string, error) {
+	return cfg.replicaDSN()
+}
+
+// UseReplica подключает к conn реплику из cfg.ReplicaDSN так же, как Init
+func UseReplica(conn *gorm.DB, cfg Config) error {
+	return useReplica(conn, cfg)
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"app/internal/metrics"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
+)
+
+// Метрики запросов GORM по операции и таблице
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "subs",
+		Name:      "db_query_duration_seconds",
+		Help:      "GORM query latency by operation and table.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "subs",
+		Name:      "db_query_errors_total",
+		Help:      "Failed GORM queries by operation and table; not found is not an error.",
+	}, []string{"operation", "table"})
+)
+
+// startKey — ключ времени начала запроса в экземпляре *gorm.DB
+const startKey = "metrics:start"
+
+// instrument подключает к conn замеры длительности запросов и статистику пула соединений
+func instrument(conn *gorm.DB) error {
+	for _, collector := range []prometheus.Collector{queryDuration, queryErrors} {
+		if err := metrics.Register(collector); err != nil {
+			return err
+		}
+	}
+	sqlDB, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	if err := metrics.Register(collectors.NewDBStatsCollector(sqlDB, conn.Dialector.Name())); err != nil {
+		return err
+	}
+
+	callbacks := conn.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:before_create", startQuery),
+		callbacks.Create().After("gorm:create").Register("metrics:after_create", observeQuery("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:before_query", startQuery),
+		callbacks.Query().After("gorm:query").Register("metrics:after_query", observeQuery("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:before_update", startQuery),
+		callbacks.Update().After("gorm:update").Register("metrics:after_update", observeQuery("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:before_delete", startQuery),
+		callbacks.Delete().After("gorm:delete").Register("metrics:after_delete", observeQuery("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:before_row", startQuery),
+		callbacks.Row().After("gorm:row").Register("metrics:after_row", observeQuery("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:before_raw", startQuery),
+		callbacks.Raw().After("gorm:raw").Register("metrics:after_raw", observeQuery("raw")),
+	)
+}
+
+// startQuery запоминает время начала запроса
+func startQuery(db *gorm.DB) {
+	db.InstanceSet(startKey, time.Now())
+}
+
+// observeQuery возвращает callback, записывающий длительность и ошибку запроса операции operation
+func observeQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := db.Statement.Table
+		if table == "" {
+			table = "raw"
+		}
+		queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package database_test
+
+import (
+	"app/internal/database/dbtest"
+	"database/sql"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+)
+
+// sqliteMigrations — каталог миграций SQLite относительно пакета
+const sqliteMigrations = "../../migrations/sqlite"
+
+// column — столбец таблицы SQLite
+type column struct {
+	name    string
+	notNull bool
+}
+
+// TestSQLiteMigrationsMatchSchema проверяет, что миграции migrations/sqlite, применённые по порядку,
+// дают те же таблицы и столбцы, что создаёт сервис при запуске, а откат миграций удаляет их все.
+// Миграции могут быть строже схемы сервиса: например, created_at в них NOT NULL со значением по умолчанию
+func TestSQLiteMigrationsMatchSchema(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join(sqliteMigrations, "*.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations in %s: %v", sqliteMigrations, err)
+	}
+	slices.Sort(files)
+
+	migrated, err := sql.Open(sqlite.DriverName, filepath.Join(t.TempDir(), "migrated.db")+"?_pragma=foreign_keys(1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrated.Close()
+	for _, file := range files {
+		apply(t, migrated, file, "Up")
+	}
+	want := schemaOf(t, autoMigrated(t))
+	got := schemaOf(t, migrated)
+
+	for table, columns := range want {
+		if _, ok := got[table]; !ok {
+			t.Errorf("migrations do not create table %s", table)
+			continue
+		}
+		for _, c := range columns {
+			i := slices.IndexFunc(got[table], func(g column) bool { return g.name == c.name })
+			switch {
+			case i < 0:
+				t.Errorf("migrations do not create column %s.%s", table, c.name)
+			case c.notNull && !got[table][i].notNull:
+				t.Errorf("column %s.%s is NOT NULL at startup but nullable in migrations", table, c.name)
+			}
+		}
+	}
+	for table, columns := range got {
+		if _, ok := want[table]; !ok {
+			t.Errorf("migrations create table %s that the service does not use", table)
+			continue
+		}
+		for _, c := range columns {
+			if !slices.ContainsFunc(want[table], func(w column) bool { return w.name == c.name }) {
+				t.Errorf("migrations create column %s.%s that the service does not use", table, c.name)
+			}
+		}
+	}
+
+	for i := len(files) - 1; i >= 0; i-- {
+		apply(t, migrated, files[i], "Down")
+	}
+	if left := schemaOf(t, migrated); len(left) > 0 {
+		t.Errorf("tables left after rolling back all migrations: %v", slices.Sorted(maps.Keys(left)))
+	}
+}
+
+// apply выполняет раздел direction (Up или Down) миграции file в формате sql-migrate
+func apply(t *testing.T, db *sql.DB, file, direction string) {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var section strings.Builder
+	current := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +migrate "); ok {
+			current = rest
+			continue
+		}
+		if current == direction {
+			section.WriteString(line + "\n")
+		}
+	}
+	if _, err := db.Exec(section.String()); err != nil {
+		t.Fatalf("%s %s: %v", filepath.Base(file), direction, err)
+	}
+}
+
+// autoMigrated возвращает соединение с бд SQLite в памяти, схему которой создал database.Init
+func autoMigrated(t *testing.T) *sql.DB {
+	t.Helper()
+	conn := dbtest.SQLite(t)
+	db, err := conn.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// schemaOf возвращает столбцы каждой таблицы бд, кроме служебных таблиц SQLite
+func schemaOf(t *testing.T, db *sql.DB) map[string][]column {
+	t.Helper()
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	schema := make(map[string][]column, len(tables))
+	for _, table := range tables {
+		rows, err := db.Query("SELECT name, \"notnull\" FROM pragma_table_info(?)", table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for rows.Next() {
+			var c column
+			if err := rows.Scan(&c.name, &c.notNull); err != nil {
+				t.Fatal(err)
+			}
+			schema[table] = append(schema[table], c)
+		}
+		rows.Close()
+	}
+	return schema
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// replicaResolver — имя резолвера реплики. Реплика зарегистрирована только под этим именем,
+// поэтому запросы без Replica, в том числе чтения фоновых задач, идут в основную бд
+const replicaResolver = "read-replica"
+
+// useReplica подключает к conn реплику для чтения из cfg.ReplicaDSN с теми же настройками пула
+func useReplica(conn *gorm.DB, cfg Config) error {
+	dsn, err := cfg.replicaDSN()
+	if err != nil {
+		return err
+	}
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: []gorm.Dialector{postgres.Open(dsn)},
+	}, replicaResolver).
+		SetMaxOpenConns(cfg.MaxOpenConns).
+		SetMaxIdleConns(cfg.MaxIdleConns).
+		SetConnMaxLifetime(cfg.ConnMaxLifetime).
+		SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return conn.Use(resolver)
+}
+
+// Replica направляет запросы через db на реплику для чтения; без настроенной реплики запросы идут в основную бд.
+// Транзакция, начатая на возвращённом подключении, тоже открывается на реплике. Реплика отстаёт от основной бд,
+// поэтому через неё читаются только списки и отчёты, но не данные, которые нужно сразу изменить
+func Replica(db *gorm.DB) *gorm.DB {
+	return db.Clauses(dbresolver.Use(replicaResolver), dbresolver.Read)
+}
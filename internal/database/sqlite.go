@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// memoryPath — путь SQLite, при котором бд живёт в памяти процесса
+const memoryPath = ":memory:"
+
+// openSQLite открывает бд SQLite по cfg.SQLitePath драйвером на чистом Go.
+// SQLite хранит время текстом и сравнивает его как строки, поэтому все значения времени
+// в запросах приводятся к UTC: иначе сравнение дат с разными часовыми поясами было бы неверным
+func openSQLite(cfg Config) (gorm.Dialector, error) {
+	sqlDB, err := sql.Open(sqlite.DriverName, cfg.sqliteDSN())
+	if err != nil {
+		return nil, err
+	}
+	return sqlite.Dialector{Conn: &utcPool{db: sqlDB}}, nil
+}
+
+// sqliteDSN возвращает строку подключения к SQLite: время записывается в формате, который SQLite умеет сравнивать,
+// внешние ключи включены, а конкурентная запись ждёт блокировку вместо ошибки SQLITE_BUSY
+func (c Config) sqliteDSN() string {
+	query := url.Values{}
+	query.Set("_time_format", "sqlite")
+	query.Add("_pragma", "foreign_keys(1)")
+	query.Add("_pragma", "busy_timeout(5000)")
+	if c.SQLitePath != memoryPath {
+		query.Add("_pragma", "journal_mode(WAL)")
+	}
+	return c.SQLitePath + "?" + query.Encode()
+}
+
+// poolSettings возвращает настройки пула с учётом драйвера: у бд SQLite в памяти каждое соединение —
+// отдельная пустая бд, поэтому соединение одно и никогда не закрывается
+func (c Config) poolSettings() (maxOpen, maxIdle int, lifetime, idleTime time.Duration) {
+	if c.Driver == DriverSQLite && c.SQLitePath == memoryPath {
+		return 1, 1, 0, 0
+	}
+	return c.MaxOpenConns, c.MaxIdleConns, c.ConnMaxLifetime, c.ConnMaxIdleTime
+}
+
+// utcPool — пул соединений GORM, приводящий аргументы-время к UTC
+type utcPool struct {
+	db *sql.DB
+}
+
+func (p *utcPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.db.PrepareContext(ctx, query)
+}
+
+func (p *utcPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.db.ExecContext(ctx, query, inUTC(args)...)
+}
+
+func (p *utcPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.db.QueryContext(ctx, query, inUTC(args)...)
+}
+
+func (p *utcPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.db.QueryRowContext(ctx, query, inUTC(args)...)
+}
+
+// BeginTx начинает транзакцию, в которой аргументы тоже приводятся к UTC
+func (p *utcPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	tx, err := p.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &utcTx{tx: tx}, nil
+}
+
+// GetDBConn возвращает пул database/sql для настройки, проверки и закрытия соединений
+func (p *utcPool) GetDBConn() (*sql.DB, error) {
+	return p.db, nil
+}
+
+// utcTx — транзакция, приводящая аргументы-время к UTC
+type utcTx struct {
+	tx *sql.Tx
+}
+
+func (t *utcTx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
+
+func (t *utcTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, inUTC(args)...)
+}
+
+func (t *utcTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, inUTC(args)...)
+}
+
+func (t *utcTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, inUTC(args)...)
+}
+
+func (t *utcTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *utcTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// inUTC возвращает аргументы запроса, в которых значения времени переведены в UTC
+func inUTC(args []interface{}) []interface{} {
+	var converted []interface{}
+	for i, arg := range args {
+		var t time.Time
+		switch v := arg.(type) {
+		case time.Time:
+			t = v
+		case *time.Time:
+			if v == nil {
+				continue
+			}
+			t = *v
+		default:
+			continue
+		}
+		if converted == nil {
+			converted = append([]interface{}(nil), args...)
+		}
+		converted[i] = t.UTC()
+	}
+	if converted == nil {
+		return args
+	}
+	return converted
+}
@@ -0,0 +1,58 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Handlers — контракт для HTTP-обработчиков проверок состояния
+type Handlers interface {
+	Liveness(c *gin.Context)
+	Readiness(c *gin.Context)
+}
+
+// handlers — структура, реализующая интерфейс Handlers
+type handlers struct {
+	service Service
+	logger  *logrus.Logger
+}
+
+// NewHandlers — конструктор handlers
+func NewHandlers(service Service, logger *logrus.Logger) Handlers {
+	return &handlers{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// Liveness godoc
+// @Summary Проверка живости
+// @Description Отвечает 200, пока процесс жив; зависимости не проверяются
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Router /healthz [get]
+func (h *handlers) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, h.service.Live())
+}
+
+// Readiness godoc
+// @Summary Проверка готовности
+// @Description Проверяет доступность бд, применённые миграции и работу фоновых задач.
+// @Description degraded (200) — сервис обслуживает запросы, но часть фоновых задач не работает;
+// @Description unavailable (503) — бд недоступна, схема устарела или сервис останавливается
+// @Tags health
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /readyz [get]
+func (h *handlers) Readiness(c *gin.Context) {
+	report := h.service.Ready(c.Request.Context())
+	status := http.StatusOK
+	if report.Status == StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Состояния сервиса и отдельных проверок
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// checkTimeout — сколько ждать одну проверку
+const checkTimeout = 2 * time.Second
+
+// Check — проверка зависимости. Провал критичной проверки делает сервис неготовым,
+// некритичной — переводит его в состояние degraded
+type Check struct {
+	Name     string
+	Critical bool
+	Run      func(ctx context.Context) error
+}
+
+// CheckResult — результат одной проверки
+type CheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Report — состояние сервиса с результатами проверок
+type Report struct {
+	Status string                 `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+	Checks map[string]CheckResult `json:"checks,omitempty"`
+}
+
+// Service — контракт проверки живости и готовности сервиса
+type Service interface {
+	Live() Report
+	Ready(ctx context.Context) Report
+	SetShuttingDown()
+}
+
+// service — структура, реализующая интерфейс Service
+type service struct {
+	checks       []Check
+	shuttingDown atomic.Bool
+	logger       *logrus.Logger
+}
+
+// NewService — конструктор service
+func NewService(logger *logrus.Logger, checks ...Check) Service {
+	return &service{
+		checks: checks,
+		logger: logger,
+	}
+}
+
+// Live сообщает, что процесс жив и обрабатывает запросы
+func (s *service) Live() Report {
+	return Report{Status: StatusOK}
+}
+
+// Ready выполняет проверки параллельно; во время остановки сервис неготов без проверок
+func (s *service) Ready(ctx context.Context) Report {
+	if s.shuttingDown.Load() {
+		return Report{Status: StatusUnavailable, Reason: "shutting down"}
+	}
+
+	report := Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(s.checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range s.checks {
+		wg.Add(1)
+		go func(check Check) {
+			defer wg.Done()
+			result := run(ctx, check)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status == StatusOK {
+				return
+			}
+			s.logger.Warnf("health.Ready: Check %s failed: %s", check.Name, result.Error)
+			if check.Critical {
+				report.Status = StatusUnavailable
+			} else if report.Status == StatusOK {
+				report.Status = StatusDegraded
+			}
+		}(check)
+	}
+	wg.Wait()
+	return report
+}
+
+// SetShuttingDown переводит сервис в неготовое состояние на время остановки
+func (s *service) SetShuttingDown() {
+	s.shuttingDown.Store(true)
+}
+
+// run выполняет проверку с ограничением по времени
+func run(ctx context.Context, check Check) CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := CheckResult{Status: StatusOK, DurationMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = StatusUnavailable
+		if !check.Critical {
+			result.Status = StatusDegraded
+		}
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,107 @@
+package health_test
+
+import (
+	"app/internal/database/dbtest"
+	"app/internal/health"
+	"context"
+	"errors"
+	"testing"
+)
+
+// check возвращает проверку, которая завершается ошибкой err
+func check(name string, critical bool, err error) health.Check {
+	return health.Check{Name: name, Critical: critical, Run: func(context.Context) error { return err }}
+}
+
+func TestReady(t *testing.T) {
+	failure := errors.New("connection refused")
+	tests := []struct {
+		name   string
+		checks []health.Check
+		want   string
+		// results — ожидаемые состояния отдельных проверок
+		results map[string]string
+	}{
+		{
+			name:    "all checks pass",
+			checks:  []health.Check{check("database", true, nil), check("workers", false, nil)},
+			want:    health.StatusOK,
+			results: map[string]string{"database": health.StatusOK, "workers": health.StatusOK},
+		},
+		{
+			name:    "critical failure",
+			checks:  []health.Check{check("database", true, failure), check("workers", false, nil)},
+			want:    health.StatusUnavailable,
+			results: map[string]string{"database": health.StatusUnavailable, "workers": health.StatusOK},
+		},
+		{
+			name:    "non-critical failure",
+			checks:  []health.Check{check("database", true, nil), check("workers", false, failure)},
+			want:    health.StatusDegraded,
+			results: map[string]string{"database": health.StatusOK, "workers": health.StatusDegraded},
+		},
+		{
+			name:    "critical failure outweighs non-critical",
+			checks:  []health.Check{check("database", true, failure), check("workers", false, failure)},
+			want:    health.StatusUnavailable,
+			results: map[string]string{"database": health.StatusUnavailable, "workers": health.StatusDegraded},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := health.NewService(dbtest.Logger(), tt.checks...).Ready(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("Status = %q, want %q", report.Status, tt.want)
+			}
+			for name, want := range tt.results {
+				got := report.Checks[name]
+				if got.Status != want {
+					t.Errorf("check %s = %q, want %q", name, got.Status, want)
+				}
+				if (want == health.StatusOK) != (got.Error == "") {
+					t.Errorf("check %s error = %q with status %q", name, got.Error, got.Status)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyWhileShuttingDown(t *testing.T) {
+	called := false
+	service := health.NewService(dbtest.Logger(), health.Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(context.Context) error {
+			called = true
+			return nil
+		},
+	})
+	service.SetShuttingDown()
+
+	report := service.Ready(context.Background())
+	if report.Status != health.StatusUnavailable || report.Reason != "shutting down" {
+		t.Errorf("Ready = %+v, want unavailable while shutting down", report)
+	}
+	if called {
+		t.Error("checks ran while shutting down, want them skipped")
+	}
+	if live := service.Live(); live.Status != health.StatusOK {
+		t.Errorf("Live = %+v while shutting down, want ok", live)
+	}
+}
+
+func TestWorkersCheck(t *testing.T) {
+	workers := health.NewWorkers()
+	workers.Started("relay")
+	workers.Started("webhooks")
+	service := health.NewService(dbtest.Logger(), workers.Check())
+
+	if report := service.Ready(context.Background()); report.Status != health.StatusOK {
+		t.Errorf("Ready with running workers = %+v, want ok", report)
+	}
+	workers.Stopped("webhooks")
+	report := service.Ready(context.Background())
+	if report.Status != health.StatusDegraded || report.Checks["workers"].Error != "not running: webhooks" {
+		t.Errorf("Ready with a stopped worker = %+v, want degraded with webhooks not running", report)
+	}
+}
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Workers — реестр фоновых задач: задача считается работающей от Started до Stopped
+type Workers struct {
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+// NewWorkers — конструктор Workers
+func NewWorkers() *Workers {
+	return &Workers{running: make(map[string]bool)}
+}
+
+// Started отмечает задачу name запущенной
+func (w *Workers) Started(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running[name] = true
+}
+
+// Stopped отмечает задачу name остановленной
+func (w *Workers) Stopped(name string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.running[name] = false
+}
+
+// Check возвращает некритичную проверку, которая проваливается, если какая-то из задач остановилась
+func (w *Workers) Check() Check {
+	return Check{
+		Name: "workers",
+		Run: func(context.Context) error {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			var stopped []string
+			for name, running := range w.running {
+				if !running {
+					stopped = append(stopped, name)
+				}
+			}
+			if len(stopped) == 0 {
+				return nil
+			}
+			sort.Strings(stopped)
+			return fmt.Errorf("not running: %s", strings.Join(stopped, ", "))
+		},
+	}
+}
@@ -0,0 +1,29 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RunCleanup каждые cfg.CleanupInterval удаляет ключи с истёкшим сроком хранения, пока не отменён ctx
+func RunCleanup(ctx context.Context, repo Repository, cfg Config, logger *logrus.Logger) {
+	ticker := time.NewTicker(cfg.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := repo.DeleteExpired(time.Now())
+			if err != nil {
+				logger.Errorf("idempotency.RunCleanup: Failed to delete expired keys: %v", err)
+				continue
+			}
+			if deleted > 0 {
+				logger.Infof("idempotency.RunCleanup: Deleted %d expired keys", deleted)
+			}
+		}
+	}
+}
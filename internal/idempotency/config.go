@@ -0,0 +1,55 @@
+package idempotency
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Config — настройки хранения ключей идемпотентности
+type Config struct {
+	// TTL — сколько хранится ответ на запрос с ключом
+	TTL time.Duration `config:"ttl" env:"IDEMPOTENCY_TTL"`
+	// Lease — на сколько ключ занимается выполняющимся запросом; если процесс упал, не освободив ключ,
+	// повтор снова возможен через Lease, а не через TTL. Должен быть больше срока обработки запроса
+	Lease           time.Duration `config:"lease" env:"IDEMPOTENCY_LEASE"`
+	CleanupInterval time.Duration `config:"cleanup_interval" env:"IDEMPOTENCY_CLEANUP_INTERVAL"`
+	// MaxBodySize — наибольший размер тела запроса с ключом в байтах: тело читается в память для хеша
+	MaxBodySize int `config:"max_body_size" env:"IDEMPOTENCY_MAX_BODY_SIZE"`
+}
+
+// ConfigFrom читает настройки из переменных IDEMPOTENCY_*
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		TTL:             24 * time.Hour,
+		Lease:           time.Minute,
+		CleanupInterval: time.Hour,
+		MaxBodySize:     1 << 20,
+	}
+	for key, dst := range map[string]*time.Duration{
+		"IDEMPOTENCY_TTL":              &cfg.TTL,
+		"IDEMPOTENCY_LEASE":            &cfg.Lease,
+		"IDEMPOTENCY_CLEANUP_INTERVAL": &cfg.CleanupInterval,
+	} {
+		value := getenv(key)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("idempotency.ConfigFrom: invalid %s %q", key, value)
+		}
+		*dst = d
+	}
+	if value := getenv("IDEMPOTENCY_MAX_BODY_SIZE"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("idempotency.ConfigFrom: invalid IDEMPOTENCY_MAX_BODY_SIZE %q", value)
+		}
+		cfg.MaxBodySize = n
+	}
+	if cfg.Lease > cfg.TTL {
+		return cfg, fmt.Errorf("idempotency.ConfigFrom: IDEMPOTENCY_LEASE (%s) must not exceed IDEMPOTENCY_TTL (%s)", cfg.Lease, cfg.TTL)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,186 @@
+package idempotency
+
+import (
+	"app/internal/auth"
+	"app/internal/logging"
+	"app/internal/models"
+	"app/internal/tenant"
+	"app/internal/timeout"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Header — заголовок, которым клиент помечает повторы одного и того же запроса
+const Header = "Idempotency-Key"
+
+// ReplayedHeader — заголовок ответа, взятого из сохранённых
+const ReplayedHeader = "Idempotent-Replayed"
+
+// maxKeyLength — максимальная длина ключа идемпотентности
+const maxKeyLength = 255
+
+// reserveAttempts — сколько раз пытаться занять ключ, который одновременно освобождают другие запросы
+const reserveAttempts = 3
+
+// Middleware делает запросы с заголовком Idempotency-Key идемпотентными: первый ответ сохраняется вместе
+// с хешем запроса, повтор с тем же ключом и телом получает сохранённый ответ, повтор с другим телом — 422,
+// а пока первый запрос выполняется — 409. Ответы 5xx и запросы, завершившиеся паникой, не сохраняются,
+// такой запрос можно повторить. Выполняющийся запрос занимает ключ на cfg.Lease, сохранённый ответ хранится cfg.TTL.
+// Ключи различаются по арендатору и вызывающему, тело запроса ограничено cfg.MaxBodySize
+func Middleware(repo Repository, cfg Config, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "idempotency key is too long"})
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, int64(cfg.MaxBodySize)))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		now := time.Now()
+		record := &models.IdempotencyKey{
+			TenantID:    tenant.From(c),
+			Owner:       owner(c),
+			Key:         key,
+			RequestHash: requestHash(c.Request.Method, c.Request.URL.Path, body),
+			ExpiresAt:   now.Add(cfg.Lease),
+		}
+		existing, err := reserve(repo, record, now)
+		if err != nil {
+			logging.From(c.Request.Context(), logger).Errorf("idempotency.Middleware: Failed to reserve key %q: %v", key, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+			return
+		}
+		if existing != nil {
+			replay(c, existing, record.RequestHash, logger)
+			return
+		}
+
+		release := func() {
+			if err := repo.Delete(record.ID); err != nil {
+				logging.From(c.Request.Context(), logger).Errorf("idempotency.Middleware: Failed to release key %q: %v", key, err)
+			}
+		}
+		// При панике обработчика ключ тоже освобождается, иначе повтор получал бы 409 до конца аренды;
+		// сама паника передаётся дальше, в gin.Recovery
+		defer func() {
+			if p := recover(); p != nil {
+				release()
+				panic(p)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// Ответы 5xx и запросы, прерванные отключением клиента, не сохраняются — их можно повторить
+		if status := recorder.Status(); status >= http.StatusInternalServerError || status == timeout.StatusClientClosedRequest {
+			release()
+			return
+		}
+		if err := repo.Complete(record.ID, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.String(), time.Now().Add(cfg.TTL)); err != nil {
+			logging.From(c.Request.Context(), logger).Errorf("idempotency.Middleware: Failed to store response for key %q: %v", key, err)
+		}
+	}
+}
+
+// reserve занимает ключ record. Если ключ уже занят, возвращает существующую запись;
+// запись с истёкшим сроком удаляется, и ключ занимается заново
+func reserve(repo Repository, record *models.IdempotencyKey, now time.Time) (*models.IdempotencyKey, error) {
+	for attempt := 0; attempt < reserveAttempts; attempt++ {
+		reserved, err := repo.Reserve(record)
+		if err != nil || reserved {
+			return nil, err
+		}
+		existing, err := repo.Get(record.TenantID, record.Owner, record.Key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// ключ успели освободить — пробуем занять снова
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if existing.ExpiresAt.After(now) {
+			return existing, nil
+		}
+		if err := repo.Delete(existing.ID); err != nil {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("key %q is contended", record.Key)
+}
+
+// replay отвечает на повтор запроса сохранённым ответом existing
+func replay(c *gin.Context, existing *models.IdempotencyKey, hash string, logger *logrus.Logger) {
+	switch {
+	case existing.RequestHash != hash:
+		logging.From(c.Request.Context(), logger).Warnf("idempotency.Middleware: Key %q reused with a different request", existing.Key)
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "idempotency key was used with a different request"})
+	case existing.StatusCode == 0:
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "a request with this idempotency key is in progress"})
+	default:
+		logging.From(c.Request.Context(), logger).Infof("idempotency.Middleware: Replaying response for key %q", existing.Key)
+		c.Header(ReplayedHeader, "true")
+		c.Data(existing.StatusCode, existing.ContentType, []byte(existing.Body))
+		c.Abort()
+	}
+}
+
+// owner возвращает вызывающего, которому принадлежит ключ: субъект Identity или пустую строку для анонимных запросов
+func owner(c *gin.Context) string {
+	if identity, ok := auth.IdentityFrom(c); ok {
+		return identity.Subject
+	}
+	return ""
+}
+
+// requestHash возвращает SHA-256 метода, пути и тела запроса
+func requestHash(method, path string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(method + " " + path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder копирует тело ответа, чтобы сохранить его для повторов
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+// Write пишет ответ клиенту и в буфер
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+// WriteString пишет ответ клиенту и в буфер
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,254 @@
+package idempotency_test
+
+import (
+	"app/internal/auth"
+	"app/internal/database"
+	"app/internal/database/dbtest"
+	"app/internal/idempotency"
+	"app/internal/models"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testConfig — настройки middleware в тестах: тело запроса не больше 64 байт
+var testConfig = idempotency.Config{TTL: time.Hour, Lease: time.Minute, CleanupInterval: time.Hour, MaxBodySize: 64}
+
+// server — роутер с middleware и счётчиком вызовов обработчиков
+type server struct {
+	router  *gin.Engine
+	repo    idempotency.Repository
+	calls   atomic.Int64
+	release chan struct{}
+	started chan struct{}
+}
+
+// newServer подключается к новой бд SQLite в памяти и собирает роутер. Обработчики: POST /subs отвечает 201
+// с номером вызова, /fail — 500, /panic паникует, /slow ждёт release. Заголовок X-Subject задаёт Identity вызывающего
+func newServer(t *testing.T) *server {
+	t.Helper()
+	dbtest.SQLite(t)
+
+	s := &server{
+		repo:    idempotency.NewRepository(dbtest.Logger()),
+		release: make(chan struct{}),
+		started: make(chan struct{}, 1),
+	}
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	s.router.Use(func(c *gin.Context) {
+		if subject := c.GetHeader("X-Subject"); subject != "" {
+			c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), auth.Identity{Subject: subject}))
+		}
+	})
+	s.router.Use(idempotency.Middleware(s.repo, testConfig, dbtest.Logger()))
+	s.router.POST("/subs", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.JSON(http.StatusCreated, gin.H{"call": s.calls.Add(1), "body": string(body)})
+	})
+	s.router.POST("/fail", func(c *gin.Context) {
+		s.calls.Add(1)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	})
+	s.router.POST("/panic", func(c *gin.Context) {
+		s.calls.Add(1)
+		panic("handler failed")
+	})
+	s.router.POST("/slow", func(c *gin.Context) {
+		s.calls.Add(1)
+		s.started <- struct{}{}
+		<-s.release
+		c.Status(http.StatusNoContent)
+	})
+	return s
+}
+
+// step — запрос и ожидаемый ответ
+type step struct {
+	path, key, subject, body string
+	status                   int
+	replayed                 bool
+}
+
+func (s *server) do(st step) *httptest.ResponseRecorder {
+	path := st.path
+	if path == "" {
+		path = "/subs"
+	}
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(st.body))
+	req.Header.Set("Content-Type", "application/json")
+	if st.key != "" {
+		req.Header.Set(idempotency.Header, st.key)
+	}
+	if st.subject != "" {
+		req.Header.Set("X-Subject", st.subject)
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddleware(t *testing.T) {
+	body := `{"service_name":"Netflix"}`
+	tests := []struct {
+		name  string
+		steps []step
+		calls int64
+	}{
+		{
+			name: "replay returns stored response",
+			steps: []step{
+				{key: "k1", body: body, status: http.StatusCreated},
+				{key: "k1", body: body, status: http.StatusCreated, replayed: true},
+				{key: "k1", body: body, status: http.StatusCreated, replayed: true},
+			},
+			calls: 1,
+		},
+		{
+			name: "different body is rejected with 422",
+			steps: []step{
+				{key: "k1", body: body, status: http.StatusCreated},
+				{key: "k1", body: `{"service_name":"Spotify"}`, status: http.StatusUnprocessableEntity},
+			},
+			calls: 1,
+		},
+		{
+			name: "different path is rejected with 422",
+			steps: []step{
+				{key: "k1", body: body, status: http.StatusCreated},
+				{path: "/fail", key: "k1", body: body, status: http.StatusUnprocessableEntity},
+			},
+			calls: 1,
+		},
+		{
+			name: "keys are separate per caller",
+			steps: []step{
+				{key: "k1", subject: "alice", body: body, status: http.StatusCreated},
+				{key: "k1", subject: "bob", body: body, status: http.StatusCreated},
+				{key: "k1", subject: "alice", body: body, status: http.StatusCreated, replayed: true},
+			},
+			calls: 2,
+		},
+		{
+			name: "different keys run separately",
+			steps: []step{
+				{key: "k1", body: body, status: http.StatusCreated},
+				{key: "k2", body: body, status: http.StatusCreated},
+			},
+			calls: 2,
+		},
+		{
+			name: "without key every request runs",
+			steps: []step{
+				{body: body, status: http.StatusCreated},
+				{body: body, status: http.StatusCreated},
+			},
+			calls: 2,
+		},
+		{
+			name: "server errors are not stored",
+			steps: []step{
+				{path: "/fail", key: "k1", body: body, status: http.StatusInternalServerError},
+				{path: "/fail", key: "k1", body: body, status: http.StatusInternalServerError},
+			},
+			calls: 2,
+		},
+		{
+			name: "panic releases the key",
+			steps: []step{
+				{path: "/panic", key: "k1", body: body, status: http.StatusInternalServerError},
+				{path: "/panic", key: "k1", body: body, status: http.StatusInternalServerError},
+			},
+			calls: 2,
+		},
+		{
+			name:  "key is too long",
+			steps: []step{{key: strings.Repeat("k", 256), body: body, status: http.StatusBadRequest}},
+			calls: 0,
+		},
+		{
+			name:  "body is too large",
+			steps: []step{{key: "k1", body: strings.Repeat("x", testConfig.MaxBodySize+1), status: http.StatusRequestEntityTooLarge}},
+			calls: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newServer(t)
+			var first *httptest.ResponseRecorder
+			for i, st := range tt.steps {
+				got := s.do(st)
+				if got.Code != st.status {
+					t.Fatalf("request #%d: status %d, want %d (%s)", i+1, got.Code, st.status, got.Body)
+				}
+				if replayed := got.Header().Get(idempotency.ReplayedHeader) == "true"; replayed != st.replayed {
+					t.Errorf("request #%d: replayed %t, want %t", i+1, replayed, st.replayed)
+				}
+				if st.replayed {
+					if got.Body.String() != first.Body.String() || got.Header().Get("Content-Type") != first.Header().Get("Content-Type") {
+						t.Errorf("request #%d: replayed %q (%s), want %q (%s)", i+1,
+							got.Body, got.Header().Get("Content-Type"), first.Body, first.Header().Get("Content-Type"))
+					}
+				} else if first == nil {
+					first = got
+				}
+			}
+			if calls := s.calls.Load(); calls != tt.calls {
+				t.Errorf("handlers ran %d times, want %d", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestMiddlewareInProgressConflict(t *testing.T) {
+	s := newServer(t)
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- s.do(step{path: "/slow", key: "k1"}) }()
+	<-s.started
+
+	if got := s.do(step{path: "/slow", key: "k1"}); got.Code != http.StatusConflict {
+		t.Errorf("concurrent request: status %d, want %d", got.Code, http.StatusConflict)
+	}
+
+	close(s.release)
+	if got := <-done; got.Code != http.StatusNoContent {
+		t.Fatalf("first request: status %d, want %d", got.Code, http.StatusNoContent)
+	}
+	if got := s.do(step{path: "/slow", key: "k1"}); got.Code != http.StatusNoContent || got.Header().Get(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("request after completion: status %d, replayed %q, want replayed %d", got.Code, got.Header().Get(idempotency.ReplayedHeader), http.StatusNoContent)
+	}
+	if calls := s.calls.Load(); calls != 1 {
+		t.Errorf("handler ran %d times, want 1", calls)
+	}
+}
+
+func TestMiddlewareReclaimsExpiredLease(t *testing.T) {
+	s := newServer(t)
+	body := `{"service_name":"Netflix"}`
+	first := s.do(step{key: "k1", body: body})
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d, want %d", first.Code, http.StatusCreated)
+	}
+	// Превращаем ключ в занятый упавшим процессом: ответа нет, аренда истекла
+	if err := database.Get().Model(&models.IdempotencyKey{}).Where("key = ?", "k1").
+		Updates(map[string]interface{}{"status_code": 0, "expires_at": time.Now().Add(-time.Second)}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.do(step{key: "k1", body: body})
+	if got.Code != http.StatusCreated || got.Header().Get(idempotency.ReplayedHeader) != "" {
+		t.Fatalf("request after expired lease: status %d, replayed %q, want a new %d", got.Code, got.Header().Get(idempotency.ReplayedHeader), http.StatusCreated)
+	}
+	if !strings.Contains(got.Body.String(), `"call":2`) {
+		t.Errorf("body %s, want the handler to run again", got.Body)
+	}
+}
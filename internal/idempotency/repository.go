@@ -0,0 +1,87 @@
+package idempotency
+
+import (
+	"app/internal/database"
+	"app/internal/models"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository — контракт для работы с ключами идемпотентности в бд
+type Repository interface {
+	Reserve(record *models.IdempotencyKey) (bool, error)
+	Get(tenantID, owner, key string) (*models.IdempotencyKey, error)
+	Complete(id uint, statusCode int, contentType, body string, expiresAt time.Time) error
+	Delete(id uint) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// repository — структура, реализующая интерфейс Repository
+type repository struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+}
+
+// NewRepository — конструктор repository
+func NewRepository(logger *logrus.Logger) Repository {
+	return &repository{
+		db:     database.Get(),
+		logger: logger,
+	}
+}
+
+// Reserve сохраняет ключ как выполняющийся; false — ключ уже занят другим запросом
+func (r *repository) Reserve(record *models.IdempotencyKey) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		r.logger.Errorf("repository.Reserve: Failed to reserve idempotency key %q: %v", record.Key, result.Error)
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Get возвращает ключ клиента owner арендатора tenantID
+func (r *repository) Get(tenantID, owner, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.db.Where(&models.IdempotencyKey{TenantID: tenantID, Owner: owner, Key: key}).First(&record).Error; err != nil {
+		r.logger.Warnf("repository.Get: Failed to fetch idempotency key %q: %v", key, err)
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Complete сохраняет ответ на запрос с ключом id и продлевает хранение ключа до expiresAt
+func (r *repository) Complete(id uint, statusCode int, contentType, body string, expiresAt time.Time) error {
+	err := r.db.Model(&models.IdempotencyKey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status_code":  statusCode,
+		"content_type": contentType,
+		"body":         body,
+		"expires_at":   expiresAt,
+	}).Error
+	if err != nil {
+		r.logger.Errorf("repository.Complete: Failed to store response for idempotency key %d: %v", id, err)
+	}
+	return err
+}
+
+// Delete удаляет ключ, освобождая его для повторного запроса
+func (r *repository) Delete(id uint) error {
+	if err := r.db.Delete(&models.IdempotencyKey{}, id).Error; err != nil {
+		r.logger.Errorf("repository.Delete: Failed to delete idempotency key %d: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// DeleteExpired удаляет ключи, срок хранения которых истёк к моменту now
+func (r *repository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	if result.Error != nil {
+		r.logger.Errorf("repository.DeleteExpired: Failed to delete expired idempotency keys: %v", result.Error)
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,43 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Форматы вывода логов
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config — настройки логирования
+type Config struct {
+	Level  logrus.Level `config:"level" env:"LOG_LEVEL"`
+	Format string       `config:"format" env:"LOG_FORMAT"`
+}
+
+// ConfigFrom читает настройки логирования из переменных LOG_LEVEL и LOG_FORMAT
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Level:  logrus.InfoLevel,
+		Format: FormatText,
+	}
+	if value := getenv("LOG_LEVEL"); value != "" {
+		level, err := logrus.ParseLevel(value)
+		if err != nil {
+			return cfg, fmt.Errorf("logging.ConfigFrom: invalid LOG_LEVEL %q", value)
+		}
+		cfg.Level = level
+	}
+	if value := getenv("LOG_FORMAT"); value != "" {
+		format := strings.ToLower(value)
+		if format != FormatText && format != FormatJSON {
+			return cfg, fmt.Errorf("logging.ConfigFrom: invalid LOG_FORMAT %q: expected text or json", value)
+		}
+		cfg.Format = format
+	}
+	return cfg, nil
+}
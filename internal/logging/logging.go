@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// New создаёт логгер с уровнем и форматом из cfg
+func New(cfg Config) *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(cfg.Level)
+	if cfg.Format == FormatJSON {
+		logger.SetFormatter(&logrus.JSONFormatter{
+			TimestampFormat: time.RFC3339Nano,
+			FieldMap: logrus.FieldMap{
+				logrus.FieldKeyMsg: "message",
+			},
+		})
+	} else {
+		logger.SetFormatter(&logrus.TextFormatter{
+			FullTimestamp: true,
+		})
+	}
+	return logger
+}
+
+// fieldsKey — ключ полей логов запроса в контексте
+type fieldsKey struct{}
+
+// WithFields возвращает контекст, записи из которого дополнительно получают поля fields
+func WithFields(ctx context.Context, fields logrus.Fields) context.Context {
+	merged := make(logrus.Fields, len(fields))
+	if existing, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		for key, value := range existing {
+			merged[key] = value
+		}
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// From возвращает запись логгера logger с полями запроса из ctx: request_id, trace_id, sub_id и т.д.
+func From(ctx context.Context, logger *logrus.Logger) *logrus.Entry {
+	entry := logger.WithContext(ctx)
+	if fields, ok := ctx.Value(fieldsKey{}).(logrus.Fields); ok {
+		entry = entry.WithFields(fields)
+	}
+	return entry
+}
+
+// Annotate добавляет поля fields ко всем последующим записям текущего запроса, включая записи сервисов и репозиториев
+func Annotate(c *gin.Context, fields logrus.Fields) {
+	c.Request = c.Request.WithContext(WithFields(c.Request.Context(), fields))
+}
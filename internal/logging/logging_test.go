@@ -0,0 +1,190 @@
+package logging_test
+
+import (
+	"app/internal/logging"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// jsonLogger возвращает логгер в формате JSON, пишущий в buf
+func jsonLogger(buf *bytes.Buffer) *logrus.Logger {
+	logger := logging.New(logging.Config{Level: logrus.InfoLevel, Format: logging.FormatJSON})
+	logger.SetOutput(buf)
+	return logger
+}
+
+// entries разбирает записи JSON-логгера из buf
+func entries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var result []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+func TestConfigFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want logging.Config
+		err  string
+	}{
+		{name: "defaults", env: map[string]string{}, want: logging.Config{Level: logrus.InfoLevel, Format: logging.FormatText}},
+		{name: "json in upper case", env: map[string]string{"LOG_LEVEL": "debug", "LOG_FORMAT": "JSON"}, want: logging.Config{Level: logrus.DebugLevel, Format: logging.FormatJSON}},
+		{name: "invalid level", env: map[string]string{"LOG_LEVEL": "loud"}, err: "invalid LOG_LEVEL"},
+		{name: "invalid format", env: map[string]string{"LOG_FORMAT": "xml"}, err: "expected text or json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := logging.ConfigFrom(func(key string) string { return tt.env[key] })
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ConfigFrom() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConfigFrom(): %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ConfigFrom() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger := jsonLogger(&buf)
+	logger.Debug("hidden")
+
+	ctx := logging.WithFields(context.Background(), logrus.Fields{"request_id": "req-1"})
+	ctx = logging.WithFields(ctx, logrus.Fields{"sub_id": 7})
+	logging.From(ctx, logger).Info("subs.Service: Created")
+
+	got := entries(t, &buf)
+	if len(got) != 1 {
+		t.Fatalf("logged %v, want only the info entry", got)
+	}
+	// Поля контекста накапливаются, сообщение пишется в поле message
+	entry := got[0]
+	if entry["message"] != "subs.Service: Created" || entry["level"] != "info" || entry["request_id"] != "req-1" || entry["sub_id"] != float64(7) {
+		t.Errorf("entry = %v, want message, level, request_id and sub_id", entry)
+	}
+	if _, ok := entry["time"]; !ok {
+		t.Errorf("entry = %v, want a time field", entry)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	traceID := trace.TraceID{0x4b, 0xf9, 0x2f, 0x35}
+	tests := []struct {
+		name   string
+		header string
+		// keep — сохраняется ли идентификатор клиента
+		keep bool
+	}{
+		{name: "client id", header: "client-request-1", keep: true},
+		{name: "no header"},
+		{name: "control characters", header: "bad\tid"},
+		{name: "too long", header: strings.Repeat("a", 129)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := jsonLogger(&buf)
+			gin.SetMode(gin.TestMode)
+			router := gin.New()
+			// Спан запроса открывается раньше RequestID, как tracing.Middleware в main
+			router.Use(func(c *gin.Context) {
+				span := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{1}})
+				c.Request = c.Request.WithContext(trace.ContextWithSpanContext(c.Request.Context(), span))
+			}, logging.RequestID(), logging.AccessLog(logger))
+			router.GET("/subs/:id", func(c *gin.Context) {
+				logging.From(c.Request.Context(), logger).Info("subs.Handlers: Handled")
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/subs/1", nil)
+			if tt.header != "" {
+				req.Header.Set(logging.RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			id := rec.Header().Get(logging.RequestIDHeader)
+			if tt.keep && id != tt.header {
+				t.Errorf("%s = %q, want the client id %q", logging.RequestIDHeader, id, tt.header)
+			}
+			if !tt.keep && (id == tt.header || len(id) != 32) {
+				t.Errorf("%s = %q, want a generated id", logging.RequestIDHeader, id)
+			}
+			// Идентификатор и trace_id попадают и в записи обработчика, и в журнал доступа
+			got := entries(t, &buf)
+			if len(got) != 2 {
+				t.Fatalf("logged %v, want the handler and access log entries", got)
+			}
+			for _, entry := range got {
+				if entry["request_id"] != id || entry["trace_id"] != traceID.String() {
+					t.Errorf("entry = %v, want request_id %s and trace_id %s", entry, id, traceID)
+				}
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := jsonLogger(&buf)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(logging.AccessLog(logger))
+	router.GET("/subs/:id", func(c *gin.Context) { c.String(http.StatusOK, "ok") })
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	for _, path := range []string{"/subs/5", "/missing", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	want := []struct {
+		level, message, path, route string
+		status                      float64
+	}{
+		{"info", "http: Request completed", "/subs/5", "/subs/:id", 200},
+		{"warning", "http: Request rejected", "/missing", "", 404},
+		{"error", "http: Request failed", "/fail", "/fail", 500},
+	}
+	got := entries(t, &buf)
+	if len(got) != len(want) {
+		t.Fatalf("logged %v, want %d entries", got, len(want))
+	}
+	for i, w := range want {
+		entry := got[i]
+		if entry["level"] != w.level || entry["message"] != w.message || entry["path"] != w.path || entry["route"] != w.route || entry["status"] != w.status {
+			t.Errorf("entry %d = %v, want %+v", i, entry, w)
+		}
+		if entry["method"] != http.MethodGet {
+			t.Errorf("entry %d method = %v, want GET", i, entry["method"])
+		}
+	}
+	if got[0]["bytes"] != float64(2) {
+		t.Errorf("bytes = %v, want 2", got[0]["bytes"])
+	}
+}
@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength — максимальная длина идентификатора, принимаемого от клиента
+const maxRequestIDLength = 128
+
+// RequestID присваивает запросу идентификатор: берёт его из заголовка X-Request-ID или генерирует новый,
+// возвращает в ответе и добавляет к записям логов запроса вместе с trace_id текущего спана
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+
+		fields := logrus.Fields{"request_id": id}
+		if span := trace.SpanContextFromContext(c.Request.Context()); span.IsValid() {
+			fields["trace_id"] = span.TraceID().String()
+		}
+		Annotate(c, fields)
+		c.Next()
+	}
+}
+
+// AccessLog пишет по записи на каждый завершённый запрос: метод, маршрут, статус и длительность
+func AccessLog(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		entry := From(c.Request.Context(), logger).WithFields(logrus.Fields{
+			"method":      c.Request.Method,
+			"path":        c.Request.URL.Path,
+			"route":       c.FullPath(),
+			"status":      status,
+			"duration_ms": time.Since(start).Milliseconds(),
+			"client_ip":   c.ClientIP(),
+			"bytes":       max(c.Writer.Size(), 0),
+		})
+		switch {
+		case status >= 500:
+			entry.Error("http: Request failed")
+		case status >= 400:
+			entry.Warn("http: Request rejected")
+		default:
+			entry.Info("http: Request completed")
+		}
+	}
+}
+
+// validRequestID проверяет, что идентификатор клиента непустой, не слишком длинный и состоит из печатных ASCII-символов
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID генерирует случайный идентификатор запроса
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
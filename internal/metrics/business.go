@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"app/internal/models"
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+)
+
+// Бизнес-метрики по арендаторам и сервисам, пересчитываются периодически
+var (
+	activeSubscriptions = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_subscriptions",
+		Help:      "Subscriptions active right now by tenant and service.",
+	}, []string{"tenant", "service"})
+
+	monthlyRecurringRevenue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "monthly_recurring_revenue_rubles",
+		Help:      "Current monthly price of active subscriptions by tenant and service, in RUB.",
+	}, []string{"tenant", "service"})
+
+	businessUpdated = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "business_metrics_updated_timestamp_seconds",
+		Help:      "Unix time of the last successful business metrics refresh.",
+	})
+)
+
+func init() {
+	registry.MustRegister(activeSubscriptions, monthlyRecurringRevenue, businessUpdated)
+}
+
+// StatsSource возвращает статистику активных подписок на момент at
+type StatsSource func(ctx context.Context, at time.Time) ([]models.ServiceStats, error)
+
+// RunBusiness каждые interval пересчитывает бизнес-метрики из source, пока не отменён ctx
+func RunBusiness(ctx context.Context, source StatsSource, interval time.Duration, logger *logrus.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := refreshBusiness(ctx, source); err != nil && ctx.Err() == nil {
+			logger.Errorf("metrics.RunBusiness: Failed to refresh business metrics: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshBusiness заменяет значения бизнес-метрик, чтобы исчезнувшие сервисы не оставались с прежними значениями
+func refreshBusiness(ctx context.Context, source StatsSource) error {
+	stats, err := source(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	activeSubscriptions.Reset()
+	monthlyRecurringRevenue.Reset()
+	for _, s := range stats {
+		activeSubscriptions.WithLabelValues(s.TenantID, s.ServiceName).Set(float64(s.ActiveSubs))
+		monthlyRecurringRevenue.WithLabelValues(s.TenantID, s.ServiceName).Set(float64(s.MonthlyRevenue))
+	}
+	businessUpdated.SetToCurrentTime()
+	return nil
+}
@@ -0,0 +1,8 @@
+package metrics
+
+import "context"
+
+// RefreshBusiness выполняет одну итерацию RunBusiness: пересчёт бизнес-метрик из source
+func RefreshBusiness(ctx context.Context, source StatsSource) error {
+	return refreshBusiness(ctx, source)
+}
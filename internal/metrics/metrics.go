@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace — префикс всех метрик сервиса
+const namespace = "subs"
+
+// registry — реестр метрик сервиса, который отдаёт /metrics
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Register регистрирует коллектор в реестре сервиса; повторная регистрация того же коллектора не ошибка
+func Register(collector prometheus.Collector) error {
+	err := registry.Register(collector)
+	var already prometheus.AlreadyRegisteredError
+	if errors.As(err, &already) {
+		return nil
+	}
+	return err
+}
+
+// Handler отдаёт метрики в формате Prometheus
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// Config — настройки метрик
+type Config struct {
+	Enabled          bool          `config:"enabled" env:"METRICS_ENABLED"`
+	BusinessInterval time.Duration `config:"business_interval" env:"METRICS_BUSINESS_INTERVAL"`
+}
+
+// ConfigFrom читает настройки метрик из переменных METRICS_*
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Enabled:          true,
+		BusinessInterval: time.Minute,
+	}
+	if value := getenv("METRICS_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("metrics.ConfigFrom: invalid METRICS_ENABLED %q", value)
+		}
+		cfg.Enabled = enabled
+	}
+	if value := getenv("METRICS_BUSINESS_INTERVAL"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("metrics.ConfigFrom: invalid METRICS_BUSINESS_INTERVAL %q", value)
+		}
+		cfg.BusinessInterval = d
+	}
+	return cfg, nil
+}
@@ -0,0 +1,126 @@
+package metrics_test
+
+import (
+	"app/internal/metrics"
+	"app/internal/models"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// scrape возвращает строки ответа /metrics, начинающиеся с name
+func scrape(t *testing.T, name string) []string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(body), "\n") {
+		if strings.HasPrefix(line, name+"{") || strings.HasPrefix(line, name+" ") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// contains сообщает, есть ли среди lines строка want
+func contains(lines []string, want string) bool {
+	for _, line := range lines {
+		if line == want {
+			return true
+		}
+	}
+	return false
+}
+
+func TestMiddlewareRouteLabel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(metrics.Middleware())
+	router.GET("/metrics-test/subs/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/metrics-test/subs/1", "/metrics-test/subs/2", "/metrics-test/missing/3"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/metrics-test/missing/4", nil))
+
+	requests := scrape(t, "subs_http_requests_total")
+	// Запросы к одному маршруту с разными ID попадают в один ряд с шаблоном маршрута
+	if want := `subs_http_requests_total{method="GET",route="/metrics-test/subs/:id",status="204"} 2`; !contains(requests, want) {
+		t.Errorf("http_requests_total = %v, want %s", requests, want)
+	}
+	for _, line := range requests {
+		if strings.Contains(line, "/metrics-test/subs/1") || strings.Contains(line, "/metrics-test/missing") {
+			t.Errorf("http_requests_total has a series with a raw path: %s", line)
+		}
+	}
+	// Несуществующие маршруты получают метку unmatched вместо пути
+	for _, want := range []string{
+		`subs_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`subs_http_requests_total{method="POST",route="unmatched",status="404"} 1`,
+	} {
+		if !contains(requests, want) {
+			t.Errorf("http_requests_total = %v, want %s", requests, want)
+		}
+	}
+
+	durations := scrape(t, "subs_http_request_duration_seconds_count")
+	if want := `subs_http_request_duration_seconds_count{method="GET",route="/metrics-test/subs/:id"} 2`; !contains(durations, want) {
+		t.Errorf("http_request_duration_seconds = %v, want %s", durations, want)
+	}
+	if inFlight := scrape(t, "subs_http_requests_in_flight"); !contains(inFlight, "subs_http_requests_in_flight 0") {
+		t.Errorf("http_requests_in_flight = %v, want 0 after requests completed", inFlight)
+	}
+}
+
+func TestRefreshBusinessResetsRemovedSeries(t *testing.T) {
+	stats := []models.ServiceStats{
+		{TenantID: "tenant-a", ServiceName: "Netflix", ActiveSubs: 2, MonthlyRevenue: 800},
+		{TenantID: "tenant-a", ServiceName: "Okko", ActiveSubs: 1, MonthlyRevenue: 300},
+	}
+	source := func(context.Context, time.Time) ([]models.ServiceStats, error) { return stats, nil }
+	if err := metrics.RefreshBusiness(context.Background(), source); err != nil {
+		t.Fatal(err)
+	}
+	active := scrape(t, "subs_active_subscriptions")
+	for _, want := range []string{
+		`subs_active_subscriptions{service="Netflix",tenant="tenant-a"} 2`,
+		`subs_active_subscriptions{service="Okko",tenant="tenant-a"} 1`,
+	} {
+		if !contains(active, want) {
+			t.Errorf("active_subscriptions = %v, want %s", active, want)
+		}
+	}
+
+	// У Okko не осталось активных подписок: его ряды пропадают, а не остаются с прежними значениями
+	stats = stats[:1]
+	stats[0].ActiveSubs = 3
+	if err := metrics.RefreshBusiness(context.Background(), source); err != nil {
+		t.Fatal(err)
+	}
+	if active := scrape(t, "subs_active_subscriptions"); len(active) != 1 || active[0] != `subs_active_subscriptions{service="Netflix",tenant="tenant-a"} 3` {
+		t.Errorf("active_subscriptions = %v, want only Netflix with 3", active)
+	}
+	if revenue := scrape(t, "subs_monthly_recurring_revenue_rubles"); len(revenue) != 1 || revenue[0] != `subs_monthly_recurring_revenue_rubles{service="Netflix",tenant="tenant-a"} 800` {
+		t.Errorf("monthly_recurring_revenue_rubles = %v, want only Netflix with 800", revenue)
+	}
+
+	// Ошибка источника оставляет прежние значения
+	failing := func(context.Context, time.Time) ([]models.ServiceStats, error) { return nil, errors.New("db is down") }
+	if err := metrics.RefreshBusiness(context.Background(), failing); err == nil {
+		t.Error("RefreshBusiness with a failing source = nil, want the error")
+	}
+	if active := scrape(t, "subs_active_subscriptions"); len(active) != 1 {
+		t.Errorf("active_subscriptions after a failed refresh = %v, want the previous values kept", active)
+	}
+}
@@ -7,12 +7,283 @@ import (
 // rubles — алиас для валюты
 type rubles uint
 
+// BillingPeriod — периодичность списания оплаты за сервис
+type BillingPeriod string
+
+const (
+	BillingMonth   BillingPeriod = "month"
+	BillingQuarter BillingPeriod = "quarter"
+	BillingYear    BillingPeriod = "year"
+)
+
+// Valid проверяет, что период списания входит в список поддерживаемых
+func (p BillingPeriod) Valid() bool {
+	switch p {
+	case BillingMonth, BillingQuarter, BillingYear:
+		return true
+	}
+	return false
+}
+
+// Months возвращает длительность периода списания в месяцах
+func (p BillingPeriod) Months() int {
+	switch p {
+	case BillingQuarter:
+		return 3
+	case BillingYear:
+		return 12
+	}
+	return 1
+}
+
 // UserSubs — структура подписки пользователя на сервис
 type UserSubs struct {
+	ID          uint       `json:"id" gorm:"primaryKey; column:id"`
+	TenantID    string     `json:"tenant_id" gorm:"not null; default:default; index; column:tenant_id"`
+	ServiceID   *uint      `json:"service_id" gorm:"index; column:service_id"`
+	ServiceName string     `json:"service_name" gorm:"not null; column:service_name"`
+	Price       rubles     `json:"price" gorm:"not null; column:price"`
+	UserID      string     `json:"user_id" gorm:"not null; column:user_id"`
+	StartDate   time.Time  `json:"start_date" gorm:"not null; column:start_date"`
+	EndDate     *time.Time `json:"end_date" gorm:"column:end_date"`
+}
+
+// ActiveAt проверяет, действует ли подписка в момент t; подписка без end_date бессрочная
+func (s UserSubs) ActiveAt(t time.Time) bool {
+	return !t.Before(s.StartDate) && (s.EndDate == nil || !t.After(*s.EndDate))
+}
+
+// PricedAt возвращает копию подписки с ценой, действующей в момент t:
+// последним из изменений changes (по возрастанию даты), вступившим в силу к этому моменту
+func (s UserSubs) PricedAt(changes []PriceChange, t time.Time) UserSubs {
+	for _, change := range changes {
+		if change.EffectiveFrom.After(t) {
+			break
+		}
+		s.Price = change.Price
+	}
+	return s
+}
+
+// PriceChange — запланированное изменение ежемесячной цены подписки
+type PriceChange struct {
+	ID            uint      `json:"id" gorm:"primaryKey; column:id"`
+	TenantID      string    `json:"tenant_id" gorm:"not null; default:default; index; column:tenant_id"`
+	SubID         uint      `json:"sub_id" gorm:"not null; index; column:sub_id"`
+	Price         rubles    `json:"price" gorm:"not null; column:price"`
+	EffectiveFrom time.Time `json:"effective_from" gorm:"not null; column:effective_from"`
+}
+
+// Category — категория сервисов для отчётов по расходам (video, music, cloud storage)
+type Category struct {
+	ID          uint   `json:"id" gorm:"primaryKey; column:id"`
+	Name        string `json:"name" gorm:"not null; uniqueIndex; column:name"`
+	Description string `json:"description" gorm:"column:description"`
+}
+
+// Service — запись каталога сервисов, на которые оформляются подписки
+type Service struct {
+	ID             uint           `json:"id" gorm:"primaryKey; column:id"`
+	Name           string         `json:"name" gorm:"not null; column:name"`
+	NormalizedName string         `json:"-" gorm:"not null; uniqueIndex; column:normalized_name"`
+	Category       string         `json:"category" gorm:"index; column:category"`
+	DefaultPrice   rubles         `json:"default_price" gorm:"not null; default:0; column:default_price"`
+	BillingPeriod  BillingPeriod  `json:"billing_period" gorm:"not null; default:month; column:billing_period"`
+	Aliases        []ServiceAlias `json:"aliases" gorm:"foreignKey:ServiceID; constraint:OnDelete:CASCADE"`
+}
+
+// ServiceAlias — альтернативное название сервиса (другой регистр, язык, написание)
+type ServiceAlias struct {
+	ID              uint   `json:"-" gorm:"primaryKey; column:id"`
+	ServiceID       uint   `json:"-" gorm:"not null; index; column:service_id"`
+	Alias           string `json:"alias" gorm:"not null; column:alias"`
+	NormalizedAlias string `json:"-" gorm:"not null; uniqueIndex; column:normalized_alias"`
+}
+
+// TotalByGroup — сумма подписок за период в разрезе категории или сервиса
+type TotalByGroup struct {
+	Group string `json:"group"`
+	Total uint   `json:"total"`
+}
+
+// TimeseriesPoint — стоимость и количество активных подписок за один интервал временного ряда
+type TimeseriesPoint struct {
+	Period      time.Time `json:"period"`
+	Total       uint      `json:"total"`
+	ActiveCount int       `json:"active_count"`
+}
+
+// ForecastPoint — прогноз списаний за один месяц
+type ForecastPoint struct {
+	Period time.Time `json:"period"`
+	Total  uint      `json:"total"`
+}
+
+// Forecast — прогноз расходов на подписки на несколько месяцев вперёд
+type Forecast struct {
+	From            time.Time       `json:"from"`
+	To              time.Time       `json:"to"`
+	Months          []ForecastPoint `json:"months"`
+	ProjectedTotal  uint            `json:"projected_total"`
+	HistoricalTotal uint            `json:"historical_total"`
+}
+
+// UserSubStatus — подписка пользователя с признаком активности и датой следующего списания
+type UserSubStatus struct {
+	UserSubs
+	Active      bool       `json:"active"`
+	NextRenewal *time.Time `json:"next_renewal,omitempty"`
+}
+
+// Renewal — предстоящее списание по подписке
+type Renewal struct {
+	SubID       uint      `json:"sub_id"`
+	ServiceName string    `json:"service_name"`
+	Price       rubles    `json:"price"`
+	Date        time.Time `json:"date"`
+}
+
+// UserSummary — сводка по расходам пользователя на подписки
+type UserSummary struct {
+	UserID        string    `json:"user_id"`
+	MonthlySpend  uint      `json:"monthly_spend"`
+	LifetimeSpend uint      `json:"lifetime_spend"`
+	ActiveSubs    int       `json:"active_subs"`
+	NextRenewals  []Renewal `json:"next_renewals"`
+}
+
+// NotificationKind — тип уведомления о подписке
+type NotificationKind string
+
+const (
+	NotificationRenewal NotificationKind = "renewal"
+	NotificationExpiry  NotificationKind = "expiry"
+)
+
+// Notification — отправленное уведомление о предстоящем списании или окончании подписки.
+// Уникальность (sub_id, kind, due_date) защищает от повторной отправки.
+type Notification struct {
+	ID          uint             `json:"id" gorm:"primaryKey; column:id"`
+	TenantID    string           `json:"tenant_id" gorm:"not null; default:default; index; column:tenant_id"`
+	SubID       uint             `json:"sub_id" gorm:"not null; uniqueIndex:idx_notifications_unique; column:sub_id"`
+	Kind        NotificationKind `json:"kind" gorm:"not null; uniqueIndex:idx_notifications_unique; column:kind"`
+	DueDate     time.Time        `json:"due_date" gorm:"not null; uniqueIndex:idx_notifications_unique; column:due_date"`
+	UserID      string           `json:"user_id" gorm:"not null; column:user_id"`
+	ServiceName string           `json:"service_name" gorm:"not null; column:service_name"`
+	Price       rubles           `json:"price" gorm:"not null; column:price"`
+	SentAt      time.Time        `json:"sent_at" gorm:"not null; column:sent_at"`
+}
+
+// Типы событий жизненного цикла подписки
+const (
+	EventSubCreated = "subscription.created"
+	EventSubUpdated = "subscription.updated"
+	EventSubDeleted = "subscription.deleted"
+	EventSubExpired = "subscription.expired"
+)
+
+// SubEvent — событие жизненного цикла подписки.
+// Seq — порядковый номер публикации события, по нему клиенты продолжают поток событий.
+type SubEvent struct {
+	ID         string    `json:"id"`
+	Seq        uint64    `json:"seq,omitempty"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       UserSubs  `json:"data"`
+}
+
+// OutboxEvent — событие, записанное в одной транзакции с изменением подписки и ожидающее публикации.
+// Seq назначается при публикации: ID выдаются до фиксации транзакций и могут фиксироваться не по порядку
+type OutboxEvent struct {
+	ID        uint64     `json:"id" gorm:"primaryKey; column:id"`
+	Seq       *uint64    `json:"seq" gorm:"uniqueIndex; column:seq"`
+	TenantID  string     `json:"tenant_id" gorm:"not null; default:default; index; column:tenant_id"`
+	EventID   string     `json:"event_id" gorm:"not null; uniqueIndex; column:event_id"`
+	Type      string     `json:"type" gorm:"not null; column:type"`
+	Payload   string     `json:"payload" gorm:"not null; type:text; column:payload"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null; column:created_at"`
+	SentAt    *time.Time `json:"sent_at" gorm:"index; column:sent_at"`
+}
+
+// Webhook — зарегистрированный получатель событий о подписках
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primaryKey; column:id"`
+	TenantID  string    `json:"tenant_id" gorm:"not null; default:default; index; column:tenant_id"`
+	URL       string    `json:"url" gorm:"not null; column:url"`
+	Secret    string    `json:"secret,omitempty" gorm:"not null; column:secret"`
+	Events    []string  `json:"events" gorm:"not null; serializer:json; column:events"`
+	Active    bool      `json:"active" gorm:"not null; default:true; column:active"`
+	CreatedAt time.Time `json:"created_at" gorm:"column:created_at"`
+}
+
+// DeliveryStatus — состояние доставки события вебхуку
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// WebhookDelivery — попытки доставки одного события одному вебхуку
+type WebhookDelivery struct {
+	ID             uint           `json:"id" gorm:"primaryKey; column:id"`
+	TenantID       string         `json:"tenant_id" gorm:"not null; default:default; index; column:tenant_id"`
+	WebhookID      uint           `json:"webhook_id" gorm:"not null; uniqueIndex:idx_webhook_deliveries_event; column:webhook_id"`
+	EventID        string         `json:"event_id" gorm:"not null; uniqueIndex:idx_webhook_deliveries_event; column:event_id"`
+	Event          string         `json:"event" gorm:"not null; column:event"`
+	Payload        string         `json:"payload" gorm:"not null; type:text; column:payload"`
+	Status         DeliveryStatus `json:"status" gorm:"not null; index; column:status"`
+	Attempts       int            `json:"attempts" gorm:"not null; default:0; column:attempts"`
+	NextAttemptAt  time.Time      `json:"next_attempt_at" gorm:"not null; index; column:next_attempt_at"`
+	ResponseStatus int            `json:"response_status" gorm:"column:response_status"`
+	LastError      string         `json:"last_error" gorm:"column:last_error"`
+	CreatedAt      time.Time      `json:"created_at" gorm:"column:created_at"`
+	DeliveredAt    *time.Time     `json:"delivered_at" gorm:"column:delivered_at"`
+}
+
+// Области доступа API-ключа
+const (
+	APIKeyScopeRead  = "read"
+	APIKeyScopeWrite = "write"
+	APIKeyScopeAdmin = "admin"
+)
+
+// APIKey — ключ доступа к API для сервисных клиентов. В бд хранится только SHA-256 ключа,
+// сам ключ возвращается один раз при создании
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey; column:id"`
+	TenantID   string     `json:"tenant_id" gorm:"not null; default:default; index; column:tenant_id"`
+	Name       string     `json:"name" gorm:"not null; column:name"`
+	Prefix     string     `json:"prefix" gorm:"not null; column:prefix"`
+	Hash       string     `json:"-" gorm:"not null; uniqueIndex; column:hash"`
+	Scopes     []string   `json:"scopes" gorm:"not null; serializer:json; column:scopes"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"column:expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:"column:revoked_at"`
+	LastUsedAt *time.Time `json:"last_used_at" gorm:"column:last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"column:created_at"`
+	Key        string     `json:"key,omitempty" gorm:"-"`
+}
+
+// IdempotencyKey — сохранённый ответ на запрос с заголовком Idempotency-Key.
+// Пока запрос выполняется, StatusCode равен 0; RequestHash отличает повтор запроса от другого запроса с тем же ключом
+type IdempotencyKey struct {
 	ID          uint      `json:"id" gorm:"primaryKey; column:id"`
-	ServiceName string    `json:"service_name" gorm:"not null; column:service_name"`
-	Price       rubles    `json:"price" gorm:"not null; column:price"`
-	UserID      string    `json:"user_id" gorm:"not null; column:user_id"`
-	StartDate   time.Time `json:"start_date" gorm:"not null; column:start_date"`
-	EndDate     time.Time `json:"end_date" gorm:"not null; column:end_date"`
+	TenantID    string    `json:"tenant_id" gorm:"not null; default:default; uniqueIndex:idx_idempotency_keys_key; column:tenant_id"`
+	Owner       string    `json:"owner" gorm:"not null; uniqueIndex:idx_idempotency_keys_key; column:owner"`
+	Key         string    `json:"key" gorm:"not null; uniqueIndex:idx_idempotency_keys_key; column:key"`
+	RequestHash string    `json:"request_hash" gorm:"not null; column:request_hash"`
+	StatusCode  int       `json:"status_code" gorm:"not null; default:0; column:status_code"`
+	ContentType string    `json:"content_type" gorm:"column:content_type"`
+	Body        string    `json:"body" gorm:"type:text; column:body"`
+	CreatedAt   time.Time `json:"created_at" gorm:"column:created_at"`
+	ExpiresAt   time.Time `json:"expires_at" gorm:"not null; index; column:expires_at"`
+}
+
+// ServiceStats — активные подписки сервиса у арендатора и их текущая ежемесячная стоимость
+type ServiceStats struct {
+	TenantID       string `json:"tenant_id"`
+	ServiceName    string `json:"service_name"`
+	ActiveSubs     int64  `json:"active_subs"`
+	MonthlyRevenue uint   `json:"monthly_revenue"`
 }
@@ -0,0 +1,104 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config — настройки планировщика и каналов уведомлений
+type Config struct {
+	Enabled        bool          `config:"enabled" env:"NOTIFY_ENABLED"`
+	Interval       time.Duration `config:"interval" env:"NOTIFY_INTERVAL"`
+	LeadTime       time.Duration `config:"lead_time" env:"NOTIFY_LEAD_TIME"`
+	Channels       []string      `config:"channels" env:"NOTIFY_CHANNELS"`
+	WebhookURL     string        `config:"webhook_url" env:"NOTIFY_WEBHOOK_URL"`
+	WebhookTimeout time.Duration `config:"webhook_timeout" env:"NOTIFY_WEBHOOK_TIMEOUT"`
+	SMTPHost       string        `config:"smtp_host" env:"NOTIFY_SMTP_HOST"`
+	SMTPPort       string        `config:"smtp_port" env:"NOTIFY_SMTP_PORT"`
+	SMTPUser       string        `config:"smtp_user" env:"NOTIFY_SMTP_USER"`
+	SMTPPassword   string        `config:"smtp_password" env:"NOTIFY_SMTP_PASSWORD" secret:"true"`
+	SMTPFrom       string        `config:"smtp_from" env:"NOTIFY_SMTP_FROM"`
+	SMTPTo         []string      `config:"smtp_to" env:"NOTIFY_SMTP_TO"`
+}
+
+// ConfigFrom читает настройки уведомлений из переменных NOTIFY_*
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Enabled:        getenv("NOTIFY_ENABLED") == "true",
+		Interval:       time.Hour,
+		LeadTime:       72 * time.Hour,
+		Channels:       splitList(getenv("NOTIFY_CHANNELS")),
+		WebhookURL:     getenv("NOTIFY_WEBHOOK_URL"),
+		WebhookTimeout: 10 * time.Second,
+		SMTPHost:       getenv("NOTIFY_SMTP_HOST"),
+		SMTPPort:       getenv("NOTIFY_SMTP_PORT"),
+		SMTPUser:       getenv("NOTIFY_SMTP_USER"),
+		SMTPPassword:   getenv("NOTIFY_SMTP_PASSWORD"),
+		SMTPFrom:       getenv("NOTIFY_SMTP_FROM"),
+		SMTPTo:         splitList(getenv("NOTIFY_SMTP_TO")),
+	}
+	if len(cfg.Channels) == 0 {
+		cfg.Channels = []string{"log"}
+	}
+	if cfg.SMTPPort == "" {
+		cfg.SMTPPort = "25"
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"NOTIFY_INTERVAL":        &cfg.Interval,
+		"NOTIFY_LEAD_TIME":       &cfg.LeadTime,
+		"NOTIFY_WEBHOOK_TIMEOUT": &cfg.WebhookTimeout,
+	} {
+		value := getenv(key)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("notify.ConfigFrom: invalid %s %q", key, value)
+		}
+		*dst = d
+	}
+	return cfg, nil
+}
+
+// NewNotifier собирает Notifier из каналов, перечисленных в конфигурации (log, webhook, smtp)
+func NewNotifier(cfg Config, logger *logrus.Logger) (Notifier, error) {
+	notifiers := make([]Notifier, 0, len(cfg.Channels))
+	for _, channel := range cfg.Channels {
+		switch channel {
+		case "log":
+			notifiers = append(notifiers, NewLogNotifier(logger))
+		case "webhook":
+			if cfg.WebhookURL == "" {
+				return nil, fmt.Errorf("notify.NewNotifier: NOTIFY_WEBHOOK_URL is required for webhook channel")
+			}
+			notifiers = append(notifiers, NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookTimeout))
+		case "smtp":
+			if cfg.SMTPHost == "" || cfg.SMTPFrom == "" || len(cfg.SMTPTo) == 0 {
+				return nil, fmt.Errorf("notify.NewNotifier: NOTIFY_SMTP_HOST, NOTIFY_SMTP_FROM and NOTIFY_SMTP_TO are required for smtp channel")
+			}
+			notifiers = append(notifiers, NewSMTPNotifier(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUser, cfg.SMTPPassword, cfg.SMTPFrom, cfg.SMTPTo))
+		default:
+			return nil, fmt.Errorf("notify.NewNotifier: unknown channel %q", channel)
+		}
+	}
+	if len(notifiers) == 1 {
+		return notifiers[0], nil
+	}
+	return NewMultiNotifier(notifiers...), nil
+}
+
+// splitList разбирает список значений, разделённых запятыми
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"app/internal/models"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Notifier — контракт канала доставки уведомлений о подписках
+type Notifier interface {
+	Notify(ctx context.Context, n models.Notification) error
+}
+
+// message формирует текст уведомления
+func message(n models.Notification) string {
+	date := n.DueDate.Format("2006-01-02")
+	if n.Kind == models.NotificationExpiry {
+		return fmt.Sprintf("Подписка %s пользователя %s заканчивается %s", n.ServiceName, n.UserID, date)
+	}
+	return fmt.Sprintf("Подписка %s пользователя %s будет продлена %s, цена %d руб. в месяц", n.ServiceName, n.UserID, date, n.Price)
+}
+
+// logNotifier — канал, который пишет уведомления в лог
+type logNotifier struct {
+	logger *logrus.Logger
+}
+
+// NewLogNotifier — конструктор logNotifier
+func NewLogNotifier(logger *logrus.Logger) Notifier {
+	return &logNotifier{logger: logger}
+}
+
+// Notify пишет уведомление в лог
+func (l *logNotifier) Notify(_ context.Context, n models.Notification) error {
+	l.logger.WithFields(logrus.Fields{
+		"sub_id":   n.SubID,
+		"user_id":  n.UserID,
+		"kind":     n.Kind,
+		"due_date": n.DueDate,
+	}).Info("notify.Notify: " + message(n))
+	return nil
+}
+
+// multiNotifier — рассылает уведомление по нескольким каналам
+type multiNotifier struct {
+	notifiers []Notifier
+}
+
+// NewMultiNotifier — конструктор multiNotifier
+func NewMultiNotifier(notifiers ...Notifier) Notifier {
+	return &multiNotifier{notifiers: notifiers}
+}
+
+// Notify отправляет уведомление во все каналы и возвращает объединённую ошибку
+func (m *multiNotifier) Notify(ctx context.Context, n models.Notification) error {
+	var errs []error
+	for _, notifier := range m.notifiers {
+		if err := notifier.Notify(ctx, n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,75 @@
+package notify
+
+import (
+	"app/internal/database"
+	"app/internal/models"
+	"app/internal/tenant"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpcomingSub — подписка вместе с периодом списания сервиса из каталога
+type UpcomingSub struct {
+	models.UserSubs
+	BillingPeriod models.BillingPeriod
+}
+
+// Repository — контракт для работы с уведомлениями в бд
+type Repository interface {
+	ListActiveSubs(from, to time.Time) ([]UpcomingSub, error)
+	Claim(n *models.Notification) (bool, error)
+	Release(n *models.Notification) error
+}
+
+// repository — структура, реализующая интерфейс Repository
+type repository struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+}
+
+// NewRepository — конструктор repository
+func NewRepository(logger *logrus.Logger) Repository {
+	return &repository{
+		db:     database.Get(),
+		logger: logger,
+	}
+}
+
+// ListActiveSubs возвращает подписки всех арендаторов, действующие в какой-либо момент отрезка [from, to]
+func (r *repository) ListActiveSubs(from, to time.Time) ([]UpcomingSub, error) {
+	var subs []UpcomingSub
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		return tx.Table("user_subs").
+			Select("user_subs.*, COALESCE(services.billing_period, 'month') AS billing_period").
+			Joins("LEFT JOIN services ON services.id = user_subs.service_id").
+			Where("user_subs.start_date <= ? AND (user_subs.end_date IS NULL OR user_subs.end_date >= ?)", to, from).
+			Find(&subs).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.ListActiveSubs: Failed to fetch subscriptions: %v", err)
+		return nil, err
+	}
+	return subs, nil
+}
+
+// Claim записывает уведомление как отправленное; false — такое уведомление уже было отправлено
+func (r *repository) Claim(n *models.Notification) (bool, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(n)
+	if res.Error != nil {
+		r.logger.Errorf("repository.Claim: Failed to record notification for subscription %d: %v", n.SubID, res.Error)
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// Release удаляет запись об уведомлении, которое не удалось доставить, чтобы повторить отправку
+func (r *repository) Release(n *models.Notification) error {
+	if err := r.db.Delete(&models.Notification{}, n.ID).Error; err != nil {
+		r.logger.Errorf("repository.Release: Failed to release notification %d: %v", n.ID, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package notify
+
+import (
+	"app/internal/models"
+	"app/internal/subs"
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Scheduler — фоновый планировщик уведомлений о продлении и окончании подписок
+type Scheduler interface {
+	Run(ctx context.Context)
+	Scan(ctx context.Context) (int, error)
+}
+
+// scheduler — структура, реализующая интерфейс Scheduler
+type scheduler struct {
+	repo     Repository
+	notifier Notifier
+	interval time.Duration
+	leadTime time.Duration
+	logger   *logrus.Logger
+}
+
+// NewScheduler — конструктор scheduler
+func NewScheduler(repo Repository, notifier Notifier, interval, leadTime time.Duration, logger *logrus.Logger) Scheduler {
+	return &scheduler{
+		repo:     repo,
+		notifier: notifier,
+		interval: interval,
+		leadTime: leadTime,
+		logger:   logger,
+	}
+}
+
+// Run сканирует подписки сразу и затем каждые interval, пока не отменён ctx
+func (s *scheduler) Run(ctx context.Context) {
+	s.logger.Infof("scheduler.Run: Starting notifications scheduler (interval %s, lead time %s)", s.interval, s.leadTime)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.Scan(ctx); err != nil {
+			s.logger.Errorf("scheduler.Run: Scan failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			s.logger.Info("scheduler.Run: Notifications scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan находит подписки, у которых дата окончания или следующего списания попадает
+// в ближайшие leadTime, и отправляет по ним уведомления, которые ещё не отправлялись
+func (s *scheduler) Scan(ctx context.Context) (int, error) {
+	now := time.Now()
+	horizon := now.Add(s.leadTime)
+
+	upcoming, err := s.repo.ListActiveSubs(now, horizon)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, sub := range upcoming {
+		for _, n := range dueNotifications(sub, now, horizon) {
+			if ctx.Err() != nil {
+				return sent, ctx.Err()
+			}
+			ok, err := s.dispatch(ctx, n)
+			if err != nil {
+				s.logger.Errorf("scheduler.Scan: Failed to notify about subscription %d: %v", n.SubID, err)
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+
+	if sent > 0 {
+		s.logger.Infof("scheduler.Scan: Sent %d notifications", sent)
+	}
+	return sent, nil
+}
+
+// dispatch отправляет уведомление, если оно ещё не было отправлено
+func (s *scheduler) dispatch(ctx context.Context, n models.Notification) (bool, error) {
+	claimed, err := s.repo.Claim(&n)
+	if err != nil || !claimed {
+		return false, err
+	}
+	if err := s.notifier.Notify(ctx, n); err != nil {
+		// Снимаем отметку, чтобы повторить отправку при следующем сканировании
+		if releaseErr := s.repo.Release(&n); releaseErr != nil {
+			s.logger.Errorf("scheduler.dispatch: Failed to release notification %d: %v", n.ID, releaseErr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// dueNotifications возвращает уведомления по подписке, срок которых наступает до horizon
+func dueNotifications(sub UpcomingSub, now, horizon time.Time) []models.Notification {
+	base := models.Notification{
+		TenantID:    sub.TenantID,
+		SubID:       sub.ID,
+		UserID:      sub.UserID,
+		ServiceName: sub.ServiceName,
+		Price:       sub.Price,
+		SentAt:      now,
+	}
+
+	var result []models.Notification
+	if sub.EndDate != nil && !sub.EndDate.Before(now) && !sub.EndDate.After(horizon) {
+		n := base
+		n.Kind = models.NotificationExpiry
+		n.DueDate = *sub.EndDate
+		result = append(result, n)
+	}
+	if next, ok := subs.NextBillingDate(sub.UserSubs, sub.BillingPeriod, now); ok && !next.After(horizon) {
+		n := base
+		n.Kind = models.NotificationRenewal
+		n.DueDate = next
+		result = append(result, n)
+	}
+	return result
+}
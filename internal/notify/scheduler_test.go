@@ -0,0 +1,114 @@
+package notify_test
+
+import (
+	"app/internal/database"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"app/internal/notify"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// leadTime — за сколько до события планировщик в тестах отправляет уведомление
+const leadTime = 7 * 24 * time.Hour
+
+// recordingNotifier — канал, который запоминает уведомления или возвращает err
+type recordingNotifier struct {
+	mu   sync.Mutex
+	sent []models.Notification
+	err  error
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, n models.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.sent = append(r.sent, n)
+	return nil
+}
+
+// seedSubs создаёт подписку, которая продлевается через два дня, и подписку, которая заканчивается через три
+func seedSubs(t *testing.T) {
+	t.Helper()
+	now := time.Now().UTC()
+	ends := now.AddDate(0, 0, 3)
+	subs := []models.UserSubs{
+		{ServiceName: "Netflix", Price: 399, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: now.AddDate(0, -1, 2)},
+		{ServiceName: "Spotify", Price: 199, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: now.AddDate(-1, 0, 20), EndDate: &ends},
+	}
+	if err := database.Get().Create(&subs).Error; err != nil {
+		t.Fatalf("failed to create subscriptions: %v", err)
+	}
+}
+
+func countNotifications(t *testing.T) int64 {
+	t.Helper()
+	var count int64
+	if err := database.Get().Model(&models.Notification{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count notifications: %v", err)
+	}
+	return count
+}
+
+func TestSchedulerScanSendsOnce(t *testing.T) {
+	dbtest.SQLite(t)
+	seedSubs(t)
+	notifier := &recordingNotifier{}
+	scheduler := notify.NewScheduler(notify.NewRepository(dbtest.Logger()), notifier, time.Hour, leadTime, dbtest.Logger())
+
+	for i, want := range []int{2, 0, 0} {
+		sent, err := scheduler.Scan(context.Background())
+		if err != nil {
+			t.Fatalf("Scan #%d: %v", i+1, err)
+		}
+		if sent != want {
+			t.Errorf("Scan #%d sent %d notifications, want %d", i+1, sent, want)
+		}
+	}
+
+	if len(notifier.sent) != 2 {
+		t.Fatalf("notifier received %d notifications, want 2", len(notifier.sent))
+	}
+	kinds := map[models.NotificationKind]string{}
+	for _, n := range notifier.sent {
+		kinds[n.Kind] = n.ServiceName
+	}
+	if kinds[models.NotificationRenewal] != "Netflix" || kinds[models.NotificationExpiry] != "Spotify" {
+		t.Errorf("notifications = %v, want Netflix renewal and Spotify expiry", kinds)
+	}
+	if got := countNotifications(t); got != 2 {
+		t.Errorf("notifications table has %d rows, want 2", got)
+	}
+}
+
+func TestSchedulerScanRetriesFailedDelivery(t *testing.T) {
+	dbtest.SQLite(t)
+	seedSubs(t)
+	notifier := &recordingNotifier{err: errors.New("channel is down")}
+	scheduler := notify.NewScheduler(notify.NewRepository(dbtest.Logger()), notifier, time.Hour, leadTime, dbtest.Logger())
+
+	sent, err := scheduler.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("Scan with failing channel sent %d notifications, want 0", sent)
+	}
+	if got := countNotifications(t); got != 0 {
+		t.Errorf("failed notifications left %d claims, want 0", got)
+	}
+
+	notifier.err = nil
+	sent, err = scheduler.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("Scan after recovery sent %d notifications, want 2", sent)
+	}
+}
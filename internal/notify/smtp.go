@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"app/internal/models"
+	"context"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// smtpNotifier — канал, который отправляет уведомления письмом через SMTP-сервер
+type smtpNotifier struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+}
+
+// NewSMTPNotifier — конструктор smtpNotifier; без user аутентификация не выполняется
+func NewSMTPNotifier(host, port, user, password, from string, to []string) Notifier {
+	var auth smtp.Auth
+	if user != "" {
+		auth = smtp.PlainAuth("", user, password, host)
+	}
+	return &smtpNotifier{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+		to:   to,
+	}
+}
+
+// Notify отправляет уведомление письмом всем получателям
+func (s *smtpNotifier) Notify(_ context.Context, n models.Notification) error {
+	subject := "Продление подписки " + n.ServiceName
+	if n.Kind == models.NotificationExpiry {
+		subject = "Окончание подписки " + n.ServiceName
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(message(n) + "\r\n")
+
+	if err := smtp.SendMail(s.addr, s.auth, s.from, s.to, []byte(msg.String())); err != nil {
+		return fmt.Errorf("notify.smtp: failed to send mail: %w", err)
+	}
+	return nil
+}
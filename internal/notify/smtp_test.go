@@ -0,0 +1,211 @@
+package notify_test
+
+import (
+	"app/internal/models"
+	"app/internal/notify"
+	"bufio"
+	"context"
+	"encoding/base64"
+	"mime"
+	"net"
+	"strings"
+	"testing"
+)
+
+// smtpSession — то, что заглушка SMTP-сервера получила за одно соединение
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// smtpStub — заглушка SMTP-сервера, которая принимает одно письмо и отклоняет получателей из reject
+type smtpStub struct {
+	listener net.Listener
+	auth     bool
+	reject   string
+	sessions chan smtpSession
+}
+
+// startSMTPStub запускает заглушку на свободном порту 127.0.0.1 и останавливает её в конце теста
+func startSMTPStub(t *testing.T, auth bool, reject string) *smtpStub {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	stub := &smtpStub{listener: listener, auth: auth, reject: reject, sessions: make(chan smtpSession, 1)}
+	t.Cleanup(func() { listener.Close() })
+	go stub.serve()
+	return stub
+}
+
+func (s *smtpStub) port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+func (s *smtpStub) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	var session smtpSession
+	defer func() { s.sessions <- session }()
+
+	reply("220 localhost ESMTP stub")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case verb == "EHLO":
+			if s.auth {
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			} else {
+				reply("250 localhost")
+			}
+		case verb == "AUTH":
+			fields := strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			session.auth = string(decoded)
+			reply("235 2.7.0 Authentication successful")
+		case strings.HasPrefix(strings.ToUpper(line), "MAIL FROM:"):
+			session.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(strings.ToUpper(line), "RCPT TO:"):
+			rcpt := strings.Trim(line[len("RCPT TO:"):], "<>")
+			if rcpt == s.reject {
+				reply("550 5.1.1 No such user")
+				continue
+			}
+			session.to = append(session.to, rcpt)
+			reply("250 OK")
+		case verb == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			session.data = data.String()
+			reply("250 OK")
+		case verb == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPNotifierSendsMail(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		n        models.Notification
+		wantAuth string
+		subject  string
+	}{
+		{
+			name:    "renewal without auth",
+			n:       renewal(),
+			subject: "Продление подписки Netflix",
+		},
+		{
+			name:     "expiry with plain auth",
+			user:     "mailer",
+			n:        func() models.Notification { n := renewal(); n.Kind = models.NotificationExpiry; return n }(),
+			wantAuth: "\x00mailer\x00secret",
+			subject:  "Окончание подписки Netflix",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := startSMTPStub(t, tt.user != "", "")
+			to := []string{"alice@example.com", "bob@example.com"}
+			notifier := notify.NewSMTPNotifier("127.0.0.1", stub.port(), tt.user, "secret", "subs@example.com", to)
+
+			if err := notifier.Notify(context.Background(), tt.n); err != nil {
+				t.Fatalf("Notify: %v", err)
+			}
+
+			got := <-stub.sessions
+			if got.auth != tt.wantAuth {
+				t.Errorf("auth = %q, want %q", got.auth, tt.wantAuth)
+			}
+			if got.from != "subs@example.com" {
+				t.Errorf("MAIL FROM = %q, want subs@example.com", got.from)
+			}
+			if strings.Join(got.to, ",") != strings.Join(to, ",") {
+				t.Errorf("RCPT TO = %v, want %v", got.to, to)
+			}
+			header, body, _ := strings.Cut(got.data, "\r\n\r\n")
+			if !strings.Contains(header, "To: alice@example.com, bob@example.com\r\n") {
+				t.Errorf("headers %q miss To", header)
+			}
+			if !strings.Contains(header, "Content-Type: text/plain; charset=UTF-8") {
+				t.Errorf("headers %q miss Content-Type", header)
+			}
+			if subject := headerValue(header, "Subject"); subject != tt.subject {
+				t.Errorf("Subject = %q, want %q", subject, tt.subject)
+			}
+			if !strings.Contains(body, "Netflix") || !strings.Contains(body, "2025-03-01") {
+				t.Errorf("body = %q, want service and date", body)
+			}
+		})
+	}
+}
+
+// headerValue возвращает декодированное значение заголовка письма
+func headerValue(header, name string) string {
+	for _, line := range strings.Split(header, "\r\n") {
+		if value, ok := strings.CutPrefix(line, name+": "); ok {
+			decoded, err := new(mime.WordDecoder).DecodeHeader(value)
+			if err != nil {
+				return value
+			}
+			return decoded
+		}
+	}
+	return ""
+}
+
+func TestSMTPNotifierRejectedRecipient(t *testing.T) {
+	stub := startSMTPStub(t, false, "ghost@example.com")
+	notifier := notify.NewSMTPNotifier("127.0.0.1", stub.port(), "", "", "subs@example.com", []string{"ghost@example.com"})
+
+	err := notifier.Notify(context.Background(), renewal())
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Fatalf("Notify() error = %v, want 550 rejection", err)
+	}
+}
+
+func TestSMTPNotifierUnreachableServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+	listener.Close()
+
+	notifier := notify.NewSMTPNotifier("127.0.0.1", port, "", "", "subs@example.com", []string{"alice@example.com"})
+	if err := notifier.Notify(context.Background(), renewal()); err == nil {
+		t.Fatalf("Notify() to closed port %s succeeded, want error", port)
+	}
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"app/internal/models"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// webhookNotifier — канал, который отправляет уведомления POST-запросом на заданный URL
+type webhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier — конструктор webhookNotifier
+func NewWebhookNotifier(url string, timeout time.Duration) Notifier {
+	return &webhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+// webhookPayload — тело запроса вебхука
+type webhookPayload struct {
+	models.Notification
+	Message string `json:"message"`
+}
+
+// Notify отправляет уведомление в формате JSON; любой ответ кроме 2xx считается ошибкой
+func (w *webhookNotifier) Notify(ctx context.Context, n models.Notification) error {
+	body, err := json.Marshal(webhookPayload{Notification: n, Message: message(n)})
+	if err != nil {
+		return fmt.Errorf("notify.webhook: failed to marshal payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("notify.webhook: failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify.webhook: failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notify.webhook: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
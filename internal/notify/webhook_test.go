@@ -0,0 +1,121 @@
+package notify_test
+
+import (
+	"app/internal/models"
+	"app/internal/notify"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// renewal — уведомление о продлении, которое отправляют тесты каналов
+func renewal() models.Notification {
+	return models.Notification{
+		TenantID:    "default",
+		SubID:       7,
+		Kind:        models.NotificationRenewal,
+		DueDate:     time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC),
+		UserID:      "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+		ServiceName: "Netflix",
+		Price:       399,
+	}
+}
+
+func TestWebhookNotifierSendsJSON(t *testing.T) {
+	type request struct {
+		method, contentType string
+		payload             map[string]any
+	}
+	received := make(chan request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		received <- request{method: r.Method, contentType: r.Header.Get("Content-Type"), payload: payload}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := notify.NewWebhookNotifier(server.URL, time.Second).Notify(context.Background(), renewal()); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+
+	got := <-received
+	if got.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", got.method)
+	}
+	if got.contentType != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got.contentType)
+	}
+	for field, want := range map[string]any{
+		"sub_id":       float64(7),
+		"kind":         "renewal",
+		"service_name": "Netflix",
+		"price":        float64(399),
+		"due_date":     "2025-03-01T00:00:00Z",
+	} {
+		if got.payload[field] != want {
+			t.Errorf("payload[%q] = %v, want %v", field, got.payload[field], want)
+		}
+	}
+	message, _ := got.payload["message"].(string)
+	if !strings.Contains(message, "Netflix") || !strings.Contains(message, "2025-03-01") || !strings.Contains(message, "399") {
+		t.Errorf("payload message = %q, want service, date and price", message)
+	}
+}
+
+func TestWebhookNotifierErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		timeout time.Duration
+		want    string
+	}{
+		{
+			name:    "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			want:    "unexpected status 500",
+		},
+		{
+			name:    "not modified",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotModified) },
+			want:    "unexpected status 304",
+		},
+		{
+			name:    "client error",
+			handler: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusGone) },
+			want:    "unexpected status 410",
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			},
+			timeout: 50 * time.Millisecond,
+			want:    "failed to send request",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.handler)
+			defer server.Close()
+			timeout := tt.timeout
+			if timeout == 0 {
+				timeout = time.Second
+			}
+
+			err := notify.NewWebhookNotifier(server.URL, timeout).Notify(context.Background(), renewal())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Notify() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package outbox
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Config — настройки публикации событий из outbox
+type Config struct {
+	PollInterval   time.Duration `config:"poll_interval" env:"OUTBOX_POLL_INTERVAL"`
+	BatchSize      int
+	ExpiryLookback time.Duration `config:"expiry_lookback" env:"OUTBOX_EXPIRY_LOOKBACK"`
+	Publisher      string        `config:"publisher" env:"OUTBOX_PUBLISHER"`
+	NATSURL        string        `config:"nats_url" env:"NATS_URL"`
+	NATSSubject    string        `config:"nats_subject_prefix" env:"NATS_SUBJECT_PREFIX"`
+}
+
+// ConfigFrom читает настройки outbox из переменных OUTBOX_* и NATS_*
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		PollInterval:   time.Second,
+		BatchSize:      100,
+		ExpiryLookback: 24 * time.Hour,
+		Publisher:      getenv("OUTBOX_PUBLISHER"),
+		NATSURL:        getenv("NATS_URL"),
+		NATSSubject:    getenv("NATS_SUBJECT_PREFIX"),
+	}
+	if cfg.Publisher == "" {
+		cfg.Publisher = "channel"
+	}
+	if cfg.NATSSubject == "" {
+		cfg.NATSSubject = "subs.events"
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"OUTBOX_POLL_INTERVAL":   &cfg.PollInterval,
+		"OUTBOX_EXPIRY_LOOKBACK": &cfg.ExpiryLookback,
+	} {
+		value := getenv(key)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("outbox.ConfigFrom: invalid %s %q", key, value)
+		}
+		*dst = d
+	}
+	return cfg, nil
+}
+
+// NewPublisher собирает Publisher из внутрипроцессных получателей local
+// и, если OUTBOX_PUBLISHER=nats, публикации в NATS
+func NewPublisher(cfg Config, logger *logrus.Logger, local ...Publisher) (Publisher, error) {
+	switch cfg.Publisher {
+	case "channel":
+		return NewMultiPublisher(local...), nil
+	case "nats":
+		if cfg.NATSURL == "" {
+			return nil, fmt.Errorf("outbox.NewPublisher: NATS_URL is required for nats publisher")
+		}
+		natsPublisher, err := NewNATSPublisher(cfg.NATSURL, cfg.NATSSubject)
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("outbox.NewPublisher: Publishing events to NATS %s (subject prefix %s)", cfg.NATSURL, cfg.NATSSubject)
+		return NewMultiPublisher(append(local, natsPublisher)...), nil
+	}
+	return nil, fmt.Errorf("outbox.NewPublisher: unknown publisher %q", cfg.Publisher)
+}
@@ -0,0 +1,60 @@
+package outbox
+
+import (
+	"app/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// flushTimeout — сколько ждать подтверждения сервера, если у ctx публикации нет дедлайна
+const flushTimeout = 5 * time.Second
+
+// natsPublisher — Publisher, отправляющий события в NATS в subject "<prefix>.<type>".
+// ID события передаётся в заголовке Nats-Msg-Id, что позволяет JetStream отбрасывать повторы.
+type natsPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSPublisher — конструктор natsPublisher; подключается к серверу по url
+func NewNATSPublisher(url, prefix string) (Publisher, error) {
+	conn, err := nats.Connect(url, nats.Name("online-subs-outbox"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("outbox.NewNATSPublisher: failed to connect to %s: %w", url, err)
+	}
+	return &natsPublisher{conn: conn, prefix: prefix}, nil
+}
+
+// Publish отправляет событие и дожидается подтверждения сервера через Flush.
+// FlushWithContext требует дедлайн, поэтому без него ожидание ограничивается flushTimeout
+func (p *natsPublisher) Publish(ctx context.Context, event models.SubEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("outbox.nats: failed to marshal event: %w", err)
+	}
+
+	msg := nats.NewMsg(p.prefix + "." + event.Type)
+	msg.Header.Set(nats.MsgIdHdr, event.ID)
+	msg.Data = data
+	if err := p.conn.PublishMsg(msg); err != nil {
+		return fmt.Errorf("outbox.nats: failed to publish event %s: %w", event.ID, err)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, flushTimeout)
+		defer cancel()
+	}
+	if err := p.conn.FlushWithContext(ctx); err != nil {
+		return fmt.Errorf("outbox.nats: failed to flush event %s: %w", event.ID, err)
+	}
+	return nil
+}
+
+// Close отправляет буферизованные сообщения и закрывает соединение с NATS
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
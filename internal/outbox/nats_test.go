@@ -0,0 +1,73 @@
+package outbox_test
+
+import (
+	"app/internal/models"
+	"app/internal/outbox"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// TestNATSPublisher публикует событие в брокер из TEST_NATS_URL (по умолчанию nats://127.0.0.1:4222)
+// и читает его подписчиком. Без доступного брокера тест пропускается
+func TestNATSPublisher(t *testing.T) {
+	url := os.Getenv("TEST_NATS_URL")
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	conn, err := nats.Connect(url, nats.Timeout(time.Second))
+	if err != nil {
+		t.Skipf("NATS broker is not available at %s: %v", url, err)
+	}
+	defer conn.Close()
+
+	prefix := fmt.Sprintf("test.outbox.%d", time.Now().UnixNano())
+	sub, err := conn.SubscribeSync(prefix + ".>")
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("failed to flush subscription: %v", err)
+	}
+
+	publisher, err := outbox.NewNATSPublisher(url, prefix)
+	if err != nil {
+		t.Fatalf("NewNATSPublisher: %v", err)
+	}
+	defer publisher.(io.Closer).Close()
+
+	event := models.SubEvent{
+		ID:         "0b8f6c1e2d3a4b5c",
+		Seq:        42,
+		Type:       models.EventSubCreated,
+		OccurredAt: time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC),
+		Data:       models.UserSubs{ID: 7, TenantID: "default", ServiceName: "Netflix", Price: 399},
+	}
+	if err := publisher.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	msg, err := sub.NextMsg(2 * time.Second)
+	if err != nil {
+		t.Fatalf("no message received: %v", err)
+	}
+	if want := prefix + "." + models.EventSubCreated; msg.Subject != want {
+		t.Errorf("subject = %q, want %q", msg.Subject, want)
+	}
+	if got := msg.Header.Get(nats.MsgIdHdr); got != event.ID {
+		t.Errorf("%s = %q, want %q", nats.MsgIdHdr, got, event.ID)
+	}
+	var got models.SubEvent
+	if err := json.Unmarshal(msg.Data, &got); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if got.ID != event.ID || got.Seq != event.Seq || got.Data.ServiceName != "Netflix" || !got.OccurredAt.Equal(event.OccurredAt) {
+		t.Errorf("message = %+v, want %+v", got, event)
+	}
+}
@@ -0,0 +1,64 @@
+package outbox
+
+import (
+	"app/internal/models"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Write записывает событие об изменении подписки в outbox в рамках транзакции tx,
+// чтобы событие сохранилось тогда и только тогда, когда зафиксировано само изменение
+func Write(tx *gorm.DB, eventType string, sub models.UserSubs) error {
+	id, err := newEventID()
+	if err != nil {
+		return err
+	}
+	return write(tx, models.SubEvent{
+		ID:         id,
+		Type:       eventType,
+		OccurredAt: time.Now(),
+		Data:       sub,
+	})
+}
+
+// write сохраняет событие; событие с уже записанным ID игнорируется
+func write(tx *gorm.DB, event models.SubEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("outbox.Write: failed to marshal event: %w", err)
+	}
+	return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.OutboxEvent{
+		TenantID:  event.Data.TenantID,
+		EventID:   event.ID,
+		Type:      event.Type,
+		Payload:   string(payload),
+		CreatedAt: event.OccurredAt,
+	}).Error
+}
+
+// Decode восстанавливает событие из записи outbox; Seq события — порядковый номер публикации записи
+func Decode(row models.OutboxEvent) (models.SubEvent, error) {
+	var event models.SubEvent
+	if err := json.Unmarshal([]byte(row.Payload), &event); err != nil {
+		return event, fmt.Errorf("outbox.Decode: failed to unmarshal event %d: %w", row.ID, err)
+	}
+	if row.Seq != nil {
+		event.Seq = *row.Seq
+	}
+	return event, nil
+}
+
+// newEventID возвращает случайный идентификатор события
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("outbox: failed to generate event id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
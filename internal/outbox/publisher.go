@@ -0,0 +1,100 @@
+package outbox
+
+import (
+	"app/internal/models"
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Publisher — контракт получателя событий, опубликованных из outbox.
+// Доставка «хотя бы один раз»: получатель должен быть готов к повтору события с тем же ID.
+type Publisher interface {
+	Publish(ctx context.Context, event models.SubEvent) error
+}
+
+// multiPublisher — публикует событие нескольким получателям
+type multiPublisher struct {
+	publishers []Publisher
+}
+
+// NewMultiPublisher — конструктор multiPublisher
+func NewMultiPublisher(publishers ...Publisher) Publisher {
+	return &multiPublisher{publishers: publishers}
+}
+
+// Publish публикует событие всем получателям и возвращает объединённую ошибку
+func (m *multiPublisher) Publish(ctx context.Context, event models.SubEvent) error {
+	var errs []error
+	for _, publisher := range m.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close закрывает получателей, которым это нужно (например, соединение с NATS)
+func (m *multiPublisher) Close() error {
+	var errs []error
+	for _, publisher := range m.publishers {
+		if closer, ok := publisher.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ChannelPublisher — внутрипроцессный Publisher, раздающий события подписчикам через каналы
+type ChannelPublisher interface {
+	Publisher
+	Subscribe(buffer int) (<-chan models.SubEvent, func())
+}
+
+// channelPublisher — структура, реализующая интерфейс ChannelPublisher
+type channelPublisher struct {
+	mu          sync.Mutex
+	subscribers map[chan models.SubEvent]struct{}
+}
+
+// NewChannelPublisher — конструктор channelPublisher
+func NewChannelPublisher() ChannelPublisher {
+	return &channelPublisher{subscribers: make(map[chan models.SubEvent]struct{})}
+}
+
+// Subscribe регистрирует подписчика и возвращает канал событий и функцию отписки
+func (p *channelPublisher) Subscribe(buffer int) (<-chan models.SubEvent, func()) {
+	ch := make(chan models.SubEvent, buffer)
+	p.mu.Lock()
+	p.subscribers[ch] = struct{}{}
+	p.mu.Unlock()
+
+	return ch, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		// Канал мог быть уже закрыт при отключении медленного подписчика
+		if _, ok := p.subscribers[ch]; ok {
+			delete(p.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Publish передаёт событие каждому подписчику без ожидания. Подписчик, не успевающий
+// разбирать свой буфер, отключается: его канал закрывается, и он должен переподключиться
+func (p *channelPublisher) Publish(_ context.Context, event models.SubEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for ch := range p.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(p.subscribers, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
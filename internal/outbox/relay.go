@@ -0,0 +1,88 @@
+package outbox
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Relay — фоновая публикация событий из outbox
+type Relay interface {
+	Run(ctx context.Context)
+}
+
+// relay — структура, реализующая интерфейс Relay
+type relay struct {
+	repo      Repository
+	publisher Publisher
+	cfg       Config
+	logger    *logrus.Logger
+}
+
+// NewRelay — конструктор relay
+func NewRelay(repo Repository, publisher Publisher, cfg Config, logger *logrus.Logger) Relay {
+	return &relay{
+		repo:      repo,
+		publisher: publisher,
+		cfg:       cfg,
+		logger:    logger,
+	}
+}
+
+// Run каждые PollInterval записывает события об истёкших подписках и публикует
+// неотправленные события, пока не отменён ctx
+func (r *relay) Run(ctx context.Context) {
+	r.logger.Infof("relay.Run: Starting outbox relay (poll interval %s)", r.cfg.PollInterval)
+	ticker := time.NewTicker(r.cfg.PollInterval)
+	defer ticker.Stop()
+
+	lastExpiryScan := time.Now().Add(-r.cfg.ExpiryLookback)
+	for {
+		now := time.Now()
+		if _, err := r.repo.WriteExpired(lastExpiryScan, now); err == nil {
+			lastExpiryScan = now
+		}
+		r.publishPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			r.logger.Info("relay.Run: Outbox relay stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishPending публикует неотправленные события по порядку, назначая каждому порядковый номер;
+// на первой ошибке останавливается, чтобы повторить с того же события и не нарушить порядок
+func (r *relay) publishPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		events, err := r.repo.ListPending(r.cfg.BatchSize)
+		if err != nil || len(events) == 0 {
+			return
+		}
+
+		for _, row := range events {
+			seq, err := r.repo.AssignSeq(row.ID)
+			if err != nil {
+				return
+			}
+			row.Seq = &seq
+			event, err := Decode(row)
+			if err != nil {
+				r.logger.Errorf("relay.publishPending: Skipping malformed event %d: %v", row.ID, err)
+			} else if err := r.publisher.Publish(ctx, event); err != nil {
+				r.logger.Warnf("relay.publishPending: Failed to publish event %d (%s): %v", row.ID, row.Type, err)
+				return
+			}
+			if err := r.repo.MarkSent(row.ID, time.Now()); err != nil {
+				return
+			}
+		}
+
+		if len(events) < r.cfg.BatchSize {
+			return
+		}
+	}
+}
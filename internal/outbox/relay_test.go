@@ -0,0 +1,189 @@
+package outbox_test
+
+import (
+	"app/internal/database"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"app/internal/outbox"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// startRelay запускает relay с опросом раз в 10ms и останавливает его до закрытия бд
+func startRelay(t *testing.T, publisher outbox.Publisher) outbox.Repository {
+	t.Helper()
+	cfg, err := outbox.ConfigFrom(func(key string) string {
+		return map[string]string{"OUTBOX_POLL_INTERVAL": "10ms"}[key]
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := outbox.NewRepository(dbtest.Logger())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		outbox.NewRelay(repo, publisher, cfg, dbtest.Logger()).Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return repo
+}
+
+// writeEvent записывает событие о подписке в транзакции, которая фиксируется или откатывается по commit
+func writeEvent(t *testing.T, eventType, service string, commit bool) {
+	t.Helper()
+	rollback := errors.New("rollback")
+	err := database.Get().Transaction(func(tx *gorm.DB) error {
+		sub := models.UserSubs{ServiceName: service, Price: 100, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: time.Now()}
+		if err := tx.Create(&sub).Error; err != nil {
+			return err
+		}
+		if err := outbox.Write(tx, eventType, sub); err != nil {
+			return err
+		}
+		if !commit {
+			return rollback
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, rollback) {
+		t.Fatalf("failed to write event: %v", err)
+	}
+}
+
+// receive ждёт следующее событие из ch не дольше секунды
+func receive(t *testing.T, ch <-chan models.SubEvent) models.SubEvent {
+	t.Helper()
+	select {
+	case event, ok := <-ch:
+		if !ok {
+			t.Fatal("subscriber channel closed")
+		}
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event published within 1s")
+	}
+	return models.SubEvent{}
+}
+
+// waitSent ждёт, пока relay отметит отправленными want событий
+func waitSent(t *testing.T, repo outbox.Repository, want int) []models.OutboxEvent {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		sent, err := repo.ListSentAfter(context.Background(), 0, 100, "")
+		if err != nil {
+			t.Fatalf("ListSentAfter: %v", err)
+		}
+		if len(sent) >= want || time.Now().After(deadline) {
+			if len(sent) != want {
+				t.Fatalf("%d events marked as sent, want %d", len(sent), want)
+			}
+			return sent
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRelayPublishesCommittedEvents(t *testing.T) {
+	dbtest.SQLite(t)
+	publisher := outbox.NewChannelPublisher()
+	events, unsubscribe := publisher.Subscribe(10)
+	defer unsubscribe()
+
+	writeEvent(t, models.EventSubCreated, "Netflix", true)
+	writeEvent(t, models.EventSubCreated, "Rolled back", false)
+	writeEvent(t, models.EventSubUpdated, "Spotify", true)
+	repo := startRelay(t, publisher)
+
+	want := []struct {
+		seq     uint64
+		typ     string
+		service string
+	}{
+		{1, models.EventSubCreated, "Netflix"},
+		{2, models.EventSubUpdated, "Spotify"},
+	}
+	for _, w := range want {
+		got := receive(t, events)
+		if got.Seq != w.seq || got.Type != w.typ || got.Data.ServiceName != w.service {
+			t.Errorf("event = {seq %d, %s, %s}, want {seq %d, %s, %s}", got.Seq, got.Type, got.Data.ServiceName, w.seq, w.typ, w.service)
+		}
+	}
+
+	sent := waitSent(t, repo, 2)
+	for i, row := range sent {
+		if row.Seq == nil || *row.Seq != want[i].seq || row.SentAt == nil {
+			t.Errorf("row %d: seq %v, sent_at %v, want seq %d and sent_at set", row.ID, row.Seq, row.SentAt, want[i].seq)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event %s for %s: rolled back changes must not be published", event.Type, event.Data.ServiceName)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// flakyPublisher — Publisher, который отклоняет первые failures событий
+type flakyPublisher struct {
+	outbox.Publisher
+	mu       sync.Mutex
+	failures int
+}
+
+func (f *flakyPublisher) Publish(ctx context.Context, event models.SubEvent) error {
+	f.mu.Lock()
+	if f.failures > 0 {
+		f.failures--
+		f.mu.Unlock()
+		return errors.New("broker unavailable")
+	}
+	f.mu.Unlock()
+	return f.Publisher.Publish(ctx, event)
+}
+
+func TestRelayRetriesFailedPublishInOrder(t *testing.T) {
+	dbtest.SQLite(t)
+	channel := outbox.NewChannelPublisher()
+	events, unsubscribe := channel.Subscribe(10)
+	defer unsubscribe()
+
+	writeEvent(t, models.EventSubCreated, "Netflix", true)
+	writeEvent(t, models.EventSubCreated, "Spotify", true)
+	repo := startRelay(t, &flakyPublisher{Publisher: channel, failures: 2})
+
+	for i, service := range []string{"Netflix", "Spotify"} {
+		got := receive(t, events)
+		if got.Data.ServiceName != service || got.Seq != uint64(i+1) {
+			t.Errorf("event #%d = {seq %d, %s}, want {seq %d, %s}", i+1, got.Seq, got.Data.ServiceName, i+1, service)
+		}
+	}
+	waitSent(t, repo, 2)
+}
+
+func TestReplayQueriesHonourContext(t *testing.T) {
+	dbtest.SQLite(t)
+	writeEvent(t, models.EventSubCreated, "Netflix", true)
+	repo := startRelay(t, outbox.NewChannelPublisher())
+	waitSent(t, repo, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := repo.LatestSeq(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("LatestSeq with a canceled context: error %v, want %v", err, context.Canceled)
+	}
+	if _, err := repo.ListSentAfter(ctx, 0, 100, "tenant-a"); !errors.Is(err, context.Canceled) {
+		t.Errorf("ListSentAfter with a canceled context: error %v, want %v", err, context.Canceled)
+	}
+	if seq, err := repo.LatestSeq(context.Background()); err != nil || seq != 1 {
+		t.Errorf("LatestSeq = %d, %v, want 1", seq, err)
+	}
+}
@@ -0,0 +1,147 @@
+package outbox
+
+import (
+	"app/internal/database"
+	"app/internal/models"
+	"app/internal/tenant"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Repository — контракт для работы с outbox в бд
+type Repository interface {
+	ListPending(limit int) ([]models.OutboxEvent, error)
+	ListSentAfter(ctx context.Context, seq uint64, limit int, tenantID string) ([]models.OutboxEvent, error)
+	LatestSeq(ctx context.Context) (uint64, error)
+	AssignSeq(id uint64) (uint64, error)
+	MarkSent(id uint64, at time.Time) error
+	WriteExpired(from, to time.Time) (int, error)
+}
+
+// repository — структура, реализующая интерфейс Repository
+type repository struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+}
+
+// NewRepository — конструктор repository
+func NewRepository(logger *logrus.Logger) Repository {
+	return &repository{
+		db:     database.Get(),
+		logger: logger,
+	}
+}
+
+// ListPending возвращает неопубликованные события в порядке записи
+func (r *repository) ListPending(limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		return tx.Where("sent_at IS NULL").Order("id").Limit(limit).Find(&events).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.ListPending: Failed to fetch pending events: %v", err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// ListSentAfter возвращает опубликованные события с порядковым номером больше seq,
+// по ним подписчик восстанавливает пропущенную часть потока. Непустой tenantID оставляет только события арендатора.
+// Запрос отменяется вместе с ctx, например когда подписчик отключился
+func (r *repository) ListSentAfter(ctx context.Context, seq uint64, limit int, tenantID string) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	list := func(tx *gorm.DB) error {
+		return tx.Where("seq > ? AND sent_at IS NOT NULL", seq).Order("seq").Limit(limit).Find(&events).Error
+	}
+	var err error
+	if tenantID != "" {
+		err = tenant.Transaction(r.db.WithContext(ctx), tenantID, list)
+	} else {
+		err = tenant.AllTenants(r.db.WithContext(ctx), list)
+	}
+	if err != nil {
+		r.logger.Errorf("repository.ListSentAfter: Failed to fetch events after %d: %v", seq, err)
+		return nil, err
+	}
+	return events, nil
+}
+
+// LatestSeq возвращает порядковый номер последнего опубликованного события любого арендатора или 0, если событий нет
+func (r *repository) LatestSeq(ctx context.Context) (uint64, error) {
+	var seq uint64
+	err := tenant.AllTenants(r.db.WithContext(ctx), func(tx *gorm.DB) error {
+		return tx.Model(&models.OutboxEvent{}).Select("COALESCE(MAX(seq), 0)").Scan(&seq).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.LatestSeq: Failed to fetch latest event: %v", err)
+		return 0, err
+	}
+	return seq, nil
+}
+
+// AssignSeq назначает событию следующий порядковый номер, если он ещё не назначен, и возвращает его.
+// Номера выдаются в порядке публикации, поэтому событие из поздно зафиксированной транзакции
+// получает номер больше уже опубликованных и не теряется подписчиками. Если два экземпляра
+// назначают номер одновременно, уникальный индекс отклоняет второй, и тот повторяет публикацию позже
+func (r *repository) AssignSeq(id uint64) (uint64, error) {
+	var seq uint64
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		if err := tx.Exec(
+			"UPDATE outbox_events SET seq = (SELECT COALESCE(MAX(seq), 0) + 1 FROM outbox_events) WHERE id = ? AND seq IS NULL", id,
+		).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.OutboxEvent{}).Select("seq").Where("id = ?", id).Scan(&seq).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.AssignSeq: Failed to assign sequence number to event %d: %v", id, err)
+		return 0, err
+	}
+	return seq, nil
+}
+
+// MarkSent отмечает событие как опубликованное
+func (r *repository) MarkSent(id uint64, at time.Time) error {
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		return tx.Model(&models.OutboxEvent{}).Where("id = ?", id).Update("sent_at", at).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.MarkSent: Failed to mark event %d as sent: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// WriteExpired записывает subscription.expired для подписок, закончившихся в промежутке (from, to].
+// ID события детерминирован, поэтому повторное сканирование не создаёт дублей.
+func (r *repository) WriteExpired(from, to time.Time) (int, error) {
+	var subs []models.UserSubs
+	err := tenant.AllTenants(r.db, func(tx *gorm.DB) error {
+		if err := tx.Where("end_date > ? AND end_date <= ?", from, to).Find(&subs).Error; err != nil {
+			r.logger.Errorf("repository.WriteExpired: Failed to fetch expired subscriptions: %v", err)
+			return err
+		}
+
+		for _, sub := range subs {
+			event := models.SubEvent{
+				ID:         fmt.Sprintf("expired-%d-%d", sub.ID, sub.EndDate.Unix()),
+				Type:       models.EventSubExpired,
+				OccurredAt: *sub.EndDate,
+				Data:       sub,
+			}
+			if err := write(tx, event); err != nil {
+				r.logger.Errorf("repository.WriteExpired: Failed to write event for subscription %d: %v", sub.ID, err)
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(subs), nil
+}
@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
+)
+
+// Хранилища корзин
+const (
+	StoreMemory = "memory"
+	StoreRedis  = "redis"
+)
+
+// Limit — token bucket на Requests запросов за Period: корзина вмещает Requests токенов
+// и равномерно пополняется за Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// String возвращает лимит в формате <requests>/<period>
+func (l Limit) String() string {
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
+
+// ParseLimit разбирает лимит вида "100/1m"; период без числа ("100/m") означает одну единицу
+func ParseLimit(value string) (Limit, error) {
+	requests, period, found := strings.Cut(strings.TrimSpace(value), "/")
+	if !found {
+		return Limit{}, fmt.Errorf("invalid limit %q: expected <requests>/<period>", value)
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: requests must be a positive integer", value)
+	}
+	if period != "" && (period[0] < '0' || period[0] > '9') {
+		period = "1" + period
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q: period must be a positive duration", value)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Config — настройки ограничения частоты запросов
+type Config struct {
+	Enabled bool  `config:"enabled" env:"RATELIMIT_ENABLED"`
+	Default Limit `config:"default" env:"RATELIMIT_DEFAULT"`
+	// Routes — лимиты отдельных маршрутов по ключу "<METHOD> <path>", например "GET /subs"
+	Routes map[string]Limit `config:"routes" env:"RATELIMIT_ROUTES"`
+	// IP — лимит на IP-адрес, проверяемый до аутентификации, в том числе для запросов с неверными учётными данными
+	IP Limit `config:"ip" env:"RATELIMIT_IP"`
+	// Store — хранилище корзин: memory (в памяти экземпляра) или redis (общее для всех экземпляров)
+	Store string `config:"store" env:"RATELIMIT_STORE"`
+	// RedisURL — адрес Redis для хранилища redis, например redis://:password@localhost:6379/0
+	RedisURL string `config:"redis_url" env:"RATELIMIT_REDIS_URL" secret:"true"`
+	// RedisPrefix — префикс ключей корзин в Redis
+	RedisPrefix string `config:"redis_prefix" env:"RATELIMIT_REDIS_PREFIX"`
+}
+
+// ConfigFrom читает настройки из переменных RATELIMIT_*.
+// RATELIMIT_ROUTES — список через запятую вида "GET /subs=60/1m,POST /subs=30/1m"
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Enabled:     true,
+		Default:     Limit{Requests: 600, Period: time.Minute},
+		Routes:      map[string]Limit{},
+		IP:          Limit{Requests: 1200, Period: time.Minute},
+		Store:       getenv("RATELIMIT_STORE"),
+		RedisURL:    getenv("RATELIMIT_REDIS_URL"),
+		RedisPrefix: getenv("RATELIMIT_REDIS_PREFIX"),
+	}
+	if cfg.Store == "" {
+		cfg.Store = StoreMemory
+	}
+	if cfg.RedisPrefix == "" {
+		cfg.RedisPrefix = "ratelimit:"
+	}
+
+	if value := getenv("RATELIMIT_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("ratelimit.ConfigFrom: invalid RATELIMIT_ENABLED %q", value)
+		}
+		cfg.Enabled = enabled
+	}
+	if value := getenv("RATELIMIT_DEFAULT"); value != "" {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return cfg, fmt.Errorf("ratelimit.ConfigFrom: RATELIMIT_DEFAULT: %w", err)
+		}
+		cfg.Default = limit
+	}
+	if value := getenv("RATELIMIT_IP"); value != "" {
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return cfg, fmt.Errorf("ratelimit.ConfigFrom: RATELIMIT_IP: %w", err)
+		}
+		cfg.IP = limit
+	}
+	if value := getenv("RATELIMIT_ROUTES"); value != "" {
+		for _, entry := range strings.Split(value, ",") {
+			route, spec, found := strings.Cut(entry, "=")
+			method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+			if !found || !hasPath {
+				return cfg, fmt.Errorf("ratelimit.ConfigFrom: invalid RATELIMIT_ROUTES entry %q: expected <METHOD> <path>=<limit>", entry)
+			}
+			limit, err := ParseLimit(spec)
+			if err != nil {
+				return cfg, fmt.Errorf("ratelimit.ConfigFrom: RATELIMIT_ROUTES: %w", err)
+			}
+			cfg.Routes[RouteKey(method, path)] = limit
+		}
+	}
+
+	switch cfg.Store {
+	case StoreMemory:
+	case StoreRedis:
+		if cfg.RedisURL == "" {
+			return cfg, fmt.Errorf("ratelimit.ConfigFrom: RATELIMIT_REDIS_URL is required for RATELIMIT_STORE %s", StoreRedis)
+		}
+		if _, err := redis.ParseURL(cfg.RedisURL); err != nil {
+			return cfg, fmt.Errorf("ratelimit.ConfigFrom: invalid RATELIMIT_REDIS_URL: %w", err)
+		}
+	default:
+		return cfg, fmt.Errorf("ratelimit.ConfigFrom: invalid RATELIMIT_STORE %q: expected %s or %s", cfg.Store, StoreMemory, StoreRedis)
+	}
+	return cfg, nil
+}
+
+// NewStore создаёт хранилище корзин, выбранное в cfg.Store. Хранилище redis закрывается через io.Closer
+func NewStore(cfg Config, logger *logrus.Logger) (Store, error) {
+	switch cfg.Store {
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	case StoreRedis:
+		options, err := redis.ParseURL(cfg.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("ratelimit.NewStore: invalid RATELIMIT_REDIS_URL: %w", err)
+		}
+		logger.Infof("ratelimit.NewStore: Keeping rate limit buckets in Redis %s (key prefix %s)", options.Addr, cfg.RedisPrefix)
+		return NewRedisStore(redisClient{redis.NewClient(options)}, cfg.RedisPrefix), nil
+	}
+	return nil, fmt.Errorf("ratelimit.NewStore: unknown store %q", cfg.Store)
+}
+
+// RouteKey возвращает ключ маршрута для Config.Routes
+func RouteKey(method, path string) string {
+	return strings.ToUpper(strings.TrimSpace(method)) + " " + strings.TrimSpace(path)
+}
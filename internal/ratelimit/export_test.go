@@ -0,0 +1,21 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NewMemoryStoreAt — NewMemoryStore с часами now вместо time.Now
+func NewMemoryStoreAt(now func() time.Time) Store {
+	s := NewMemoryStore().(*memoryStore)
+	s.now = now
+	return s
+}
+
+// NewRedisStoreAt — NewRedisStore поверх клиента go-redis с часами now вместо time.Now
+func NewRedisStoreAt(client *redis.Client, prefix string, now func() time.Time) Store {
+	s := NewRedisStore(redisClient{client}, prefix).(*redisStore)
+	s.now = now
+	return s
+}
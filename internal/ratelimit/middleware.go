@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"app/internal/auth"
+	"app/internal/logging"
+	"app/internal/tenant"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Корзины: defaultScope — маршруты без собственного лимита, общая для всех таких маршрутов;
+// ipScope — лимит на IP-адрес до аутентификации
+const (
+	defaultScope = "*"
+	ipScope      = "ip"
+)
+
+// Middleware ограничивает частоту запросов каждого клиента: API-ключа или пользователя из Identity,
+// а для анонимных запросов (без аутентификации) — IP-адреса. Маршрут из cfg.Routes получает отдельную корзину
+// со своим лимитом, остальные маршруты делят корзину с лимитом cfg.Default. Отклонённый запрос получает 429
+// и Retry-After, каждый ответ — заголовки RateLimit-*. При ошибке хранилища запрос пропускается
+func Middleware(store Store, cfg Config, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, scope := cfg.Default, defaultScope
+		route := RouteKey(c.Request.Method, c.FullPath())
+		if routeLimit, ok := cfg.Routes[route]; ok && c.FullPath() != "" {
+			limit, scope = routeLimit, route
+		}
+		take(c, store, scope, clientKey(c), limit, logger)
+	}
+}
+
+// IPMiddleware ограничивает частоту запросов с одного IP-адреса лимитом cfg.IP. Ставится до аутентификации,
+// чтобы перебор токенов и ключей с одного адреса тоже ограничивался
+func IPMiddleware(store Store, cfg Config, logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		take(c, store, ipScope, "ip:"+c.ClientIP(), cfg.IP, logger)
+	}
+}
+
+// take берёт токен из корзины клиента client в scope и отклоняет запрос с 429, если токенов нет
+func take(c *gin.Context, store Store, scope, client string, limit Limit, logger *logrus.Logger) {
+	result, err := store.Take(c.Request.Context(), scope+"|"+client, limit)
+	if err != nil {
+		logging.From(c.Request.Context(), logger).Errorf("ratelimit.Middleware: Failed to check limit for %s: %v", client, err)
+		c.Next()
+		return
+	}
+
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+	c.Header("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+ceilSeconds(limit.Period))
+	if !result.Allowed {
+		logging.From(c.Request.Context(), logger).Warnf("ratelimit.Middleware: Rate limit %s exceeded by %s on %s %s", limit, client, c.Request.Method, c.FullPath())
+		c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+		return
+	}
+	c.Next()
+}
+
+// clientKey возвращает ключ клиента: арендатор и субъект Identity (у API-ключа это apikey:<id>),
+// а при выключенной аутентификации — IP-адрес
+func clientKey(c *gin.Context) string {
+	if identity, ok := auth.IdentityFrom(c); ok && identity.Subject != "" {
+		return tenant.From(c) + "/" + identity.Subject
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// ceilSeconds округляет d вверх до целых секунд, как требуют Retry-After и RateLimit-*
+func ceilSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,231 @@
+package ratelimit_test
+
+import (
+	"app/internal/auth"
+	"app/internal/database/dbtest"
+	"app/internal/ratelimit"
+	"app/internal/server"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// failingStore — хранилище, которое всегда возвращает ошибку
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("redis is down")
+}
+
+// newRouter собирает роутер сервера с лимитом на IP и на клиента, доверяющий X-Forwarded-For только от proxies;
+// заголовок X-Subject задаёт Identity вызывающего
+func newRouter(t *testing.T, store ratelimit.Store, cfg ratelimit.Config, proxies ...string) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router, err := server.NewRouter(server.Config{TrustedProxies: proxies})
+	if err != nil {
+		t.Fatal(err)
+	}
+	router.Use(ratelimit.IPMiddleware(store, cfg, dbtest.Logger()))
+	router.Use(func(c *gin.Context) {
+		if subject := c.GetHeader("X-Subject"); subject != "" {
+			c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), auth.Identity{Subject: subject}))
+		}
+	})
+	router.Use(ratelimit.Middleware(store, cfg, dbtest.Logger()))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/subs", ok)
+	router.POST("/subs", ok)
+	return router
+}
+
+// request описывает запрос и ожидаемый ответ: код и заголовки, пустое значение — заголовка нет
+type request struct {
+	advance time.Duration
+	method  string
+	ip      string
+	// forwarded — адрес клиента в X-Forwarded-For и X-Real-IP
+	forwarded string
+	subject   string
+	status    int
+	headers   map[string]string
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	cfg := ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 2, Period: time.Minute},
+		Routes:  map[string]ratelimit.Limit{ratelimit.RouteKey("POST", "/subs"): {Requests: 1, Period: 10 * time.Second}},
+		IP:      ratelimit.Limit{Requests: 100, Period: time.Minute},
+	}
+	tests := []struct {
+		name     string
+		requests []request
+	}{
+		{
+			name: "default limit",
+			requests: []request{
+				{subject: "alice", status: http.StatusNoContent, headers: map[string]string{
+					"RateLimit-Limit": "2", "RateLimit-Remaining": "1", "RateLimit-Reset": "30", "RateLimit-Policy": "2;w=60", "Retry-After": "",
+				}},
+				{subject: "alice", status: http.StatusNoContent, headers: map[string]string{
+					"RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": "",
+				}},
+				{subject: "alice", status: http.StatusTooManyRequests, headers: map[string]string{
+					"RateLimit-Remaining": "0", "RateLimit-Reset": "60", "Retry-After": "30",
+				}},
+				{advance: 10 * time.Second, subject: "alice", status: http.StatusTooManyRequests, headers: map[string]string{
+					"Retry-After": "20",
+				}},
+				{advance: 20 * time.Second, subject: "alice", status: http.StatusNoContent, headers: map[string]string{
+					"RateLimit-Remaining": "0", "Retry-After": "",
+				}},
+			},
+		},
+		{
+			name: "route limit has its own bucket",
+			requests: []request{
+				{method: http.MethodPost, subject: "alice", status: http.StatusNoContent, headers: map[string]string{
+					"RateLimit-Limit": "1", "RateLimit-Remaining": "0", "RateLimit-Reset": "10", "RateLimit-Policy": "1;w=10",
+				}},
+				{method: http.MethodPost, subject: "alice", status: http.StatusTooManyRequests, headers: map[string]string{
+					"Retry-After": "10",
+				}},
+				{subject: "alice", status: http.StatusNoContent, headers: map[string]string{
+					"RateLimit-Limit": "2", "RateLimit-Remaining": "1",
+				}},
+			},
+		},
+		{
+			name: "clients are limited separately",
+			requests: []request{
+				{subject: "alice", status: http.StatusNoContent},
+				{subject: "alice", status: http.StatusNoContent},
+				{subject: "alice", status: http.StatusTooManyRequests},
+				{subject: "bob", status: http.StatusNoContent, headers: map[string]string{"RateLimit-Remaining": "1"}},
+			},
+		},
+		{
+			name: "anonymous clients are limited by IP",
+			requests: []request{
+				{ip: "192.0.2.1", status: http.StatusNoContent},
+				{ip: "192.0.2.1", status: http.StatusNoContent},
+				{ip: "192.0.2.1", status: http.StatusTooManyRequests},
+				{ip: "192.0.2.2", status: http.StatusNoContent},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClock()
+			router := newRouter(t, ratelimit.NewMemoryStoreAt(c.Now), cfg)
+			for i, r := range tt.requests {
+				c.Advance(r.advance)
+				got := serve(router, r)
+				if got.Code != r.status {
+					t.Fatalf("request #%d: status %d, want %d", i+1, got.Code, r.status)
+				}
+				for name, want := range r.headers {
+					if value := got.Header().Get(name); value != want {
+						t.Errorf("request #%d: %s = %q, want %q", i+1, name, value, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIPMiddlewareLimitsBeforeIdentity(t *testing.T) {
+	cfg := ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 100, Period: time.Minute},
+		IP:      ratelimit.Limit{Requests: 2, Period: time.Minute},
+	}
+	router := newRouter(t, ratelimit.NewMemoryStoreAt(newClock().Now), cfg)
+
+	// Разные учётные данные с одного адреса расходуют одну корзину
+	for i, subject := range []string{"alice", "bob", "carol"} {
+		want := http.StatusNoContent
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		got := serve(router, request{ip: "192.0.2.1", subject: subject})
+		if got.Code != want {
+			t.Fatalf("request as %s: status %d, want %d", subject, got.Code, want)
+		}
+	}
+	if got := serve(router, request{ip: "192.0.2.2", subject: "alice"}); got.Code != http.StatusNoContent {
+		t.Errorf("request from another IP: status %d, want %d", got.Code, http.StatusNoContent)
+	}
+}
+
+func TestIPMiddlewareForwardedFor(t *testing.T) {
+	cfg := ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 100, Period: time.Minute},
+		IP:      ratelimit.Limit{Requests: 2, Period: time.Minute},
+	}
+	tests := []struct {
+		name    string
+		proxies []string
+		want    int
+	}{
+		// Без доверенных прокси подмена X-Forwarded-For не даёт новую корзину
+		{name: "untrusted sender", want: http.StatusTooManyRequests},
+		{name: "untrusted sender outside trusted range", proxies: []string{"10.0.0.0/8"}, want: http.StatusTooManyRequests},
+		// За доверенным прокси клиенты различаются по X-Forwarded-For
+		{name: "trusted proxy", proxies: []string{"192.0.2.1"}, want: http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newRouter(t, ratelimit.NewMemoryStoreAt(newClock().Now), cfg, tt.proxies...)
+			for i, forwarded := range []string{"198.51.100.1", "198.51.100.2", "198.51.100.3"} {
+				want := http.StatusNoContent
+				if i == 2 {
+					want = tt.want
+				}
+				if got := serve(router, request{ip: "192.0.2.1", forwarded: forwarded}); got.Code != want {
+					t.Fatalf("request forwarded for %s: status %d, want %d", forwarded, got.Code, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareFailsOpen(t *testing.T) {
+	cfg := ratelimit.Config{
+		Default: ratelimit.Limit{Requests: 1, Period: time.Minute},
+		IP:      ratelimit.Limit{Requests: 1, Period: time.Minute},
+	}
+	router := newRouter(t, failingStore{}, cfg)
+	for i := 0; i < 3; i++ {
+		got := serve(router, request{subject: "alice"})
+		if got.Code != http.StatusNoContent || got.Header().Get("RateLimit-Limit") != "" {
+			t.Fatalf("request #%d with failing store: status %d, headers %v, want %d without RateLimit-*", i+1, got.Code, got.Header(), http.StatusNoContent)
+		}
+	}
+}
+
+// serve выполняет запрос r и возвращает ответ
+func serve(router http.Handler, r request) *httptest.ResponseRecorder {
+	method := r.method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req := httptest.NewRequest(method, "/subs", nil)
+	if r.ip != "" {
+		req.RemoteAddr = r.ip + ":1234"
+	}
+	if r.forwarded != "" {
+		req.Header.Set("X-Forwarded-For", r.forwarded)
+		req.Header.Set("X-Real-IP", r.forwarded)
+	}
+	if r.subject != "" {
+		req.Header.Set("X-Subject", r.subject)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Scripter — минимальный Redis-клиент, способный выполнить Lua-скрипт (EVAL)
+type Scripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// redisClient — Scripter поверх клиента go-redis
+type redisClient struct {
+	*redis.Client
+}
+
+// Eval выполняет скрипт и возвращает его результат
+func (c redisClient) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	return c.Client.Eval(ctx, script, keys, args...).Result()
+}
+
+// takeScript атомарно пополняет корзину и берёт из неё токен.
+// KEYS[1] — корзина; ARGV: ёмкость, токенов в секунду, текущее время в миллисекундах, TTL ключа в миллисекундах.
+// Возвращает {1|0, остаток токенов строкой}
+const takeScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or capacity
+local updated = tonumber(state[2]) or now
+if now > updated then
+	tokens = math.min(capacity, tokens + (now - updated) / 1000 * rate)
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, tostring(tokens)}
+`
+
+// redisStore — Store в Redis; лимиты общие для всех экземпляров сервиса
+type redisStore struct {
+	client Scripter
+	prefix string
+	now    func() time.Time
+}
+
+// NewRedisStore создаёт Store, хранящий корзины в Redis под ключами <prefix><key>
+func NewRedisStore(client Scripter, prefix string) Store {
+	return &redisStore{
+		client: client,
+		prefix: prefix,
+		now:    time.Now,
+	}
+}
+
+// Close закрывает подключение к Redis, если клиент это поддерживает
+func (s *redisStore) Close() error {
+	if closer, ok := s.client.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Take берёт токен из корзины key одним Lua-скриптом
+func (s *redisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	reply, err := s.client.Eval(ctx, takeScript, []string{s.prefix + key},
+		limit.Requests, rate(limit), s.now().UnixMilli(), limit.Period.Milliseconds())
+	if err != nil {
+		return Result{}, fmt.Errorf("redis eval: %w", err)
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return Result{}, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	allowed, ok := values[0].(int64)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	text, ok := values[1].(string)
+	if !ok {
+		return Result{}, fmt.Errorf("unexpected redis reply %v", reply)
+	}
+	tokens, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("unexpected redis reply %v: %w", reply, err)
+	}
+	return result(allowed == 1, tokens, limit), nil
+}
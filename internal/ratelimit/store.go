@@ -0,0 +1,118 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Result — итог попытки взять токен из корзины
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter — через сколько появится следующий токен, если запрос отклонён
+	RetryAfter time.Duration
+	// Reset — через сколько корзина наполнится полностью
+	Reset time.Duration
+}
+
+// Store — хранилище корзин token bucket, общее для всех запросов одного ключа
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// bucket — состояние корзины: остаток токенов на момент updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+// memoryStore — Store в памяти процесса; лимиты не разделяются между экземплярами сервиса
+type memoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// sweepInterval — как часто из памяти удаляются заполнившиеся корзины
+const sweepInterval = time.Minute
+
+// NewMemoryStore создаёт Store в памяти процесса
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+}
+
+// Take берёт токен из корзины key, предварительно пополнив её за прошедшее время
+func (s *memoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), updated: now}
+		s.buckets[key] = b
+	}
+	b.period = limit.Period
+	b.tokens, b.updated = refill(b.tokens, b.updated, now, limit), now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	return result(allowed, b.tokens, limit), nil
+}
+
+// sweep удаляет корзины, которые не использовались дольше своего периода и, значит, уже полны
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if now.Sub(b.updated) >= b.period {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+// rate возвращает скорость пополнения корзины в токенах за секунду
+func rate(limit Limit) float64 {
+	return float64(limit.Requests) / limit.Period.Seconds()
+}
+
+// refill пополняет корзину с tokens токенами на момент updated до момента now
+func refill(tokens float64, updated, now time.Time, limit Limit) float64 {
+	if elapsed := now.Sub(updated).Seconds(); elapsed > 0 {
+		tokens += elapsed * rate(limit)
+	}
+	return math.Min(tokens, float64(limit.Requests))
+}
+
+// result собирает Result по остатку токенов после попытки
+func result(allowed bool, tokens float64, limit Limit) Result {
+	r := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate(limit)),
+	}
+	if !allowed {
+		r.RetryAfter = seconds((1 - tokens) / rate(limit))
+	}
+	return r
+}
+
+// seconds переводит дробное число секунд в time.Duration
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,147 @@
+package ratelimit_test
+
+import (
+	"app/internal/ratelimit"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// clock — управляемые часы тестов
+type clock struct {
+	now time.Time
+}
+
+func newClock() *clock {
+	return &clock{now: time.Date(2025, time.March, 1, 12, 0, 0, 0, time.UTC)}
+}
+
+func (c *clock) Now() time.Time { return c.now }
+
+func (c *clock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// stores — хранилища корзин, на которых проверяется один и тот же token bucket
+var stores = map[string]func(t *testing.T, c *clock) ratelimit.Store{
+	"memory": func(t *testing.T, c *clock) ratelimit.Store {
+		return ratelimit.NewMemoryStoreAt(c.Now)
+	},
+	"redis": func(t *testing.T, c *clock) ratelimit.Store {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return ratelimit.NewRedisStoreAt(client, "ratelimit:", c.Now)
+	},
+}
+
+// take — попытка взять токен через advance после предыдущей и ожидаемый результат
+type take struct {
+	advance   time.Duration
+	allowed   bool
+	remaining int
+	retry     time.Duration
+	reset     time.Duration
+}
+
+func TestStoreTokenBucket(t *testing.T) {
+	// 3 запроса за 3s: корзина на 3 токена пополняется на один токен в секунду
+	limit := ratelimit.Limit{Requests: 3, Period: 3 * time.Second}
+	tests := []struct {
+		name  string
+		takes []take
+	}{
+		{
+			name: "burst up to capacity",
+			takes: []take{
+				{allowed: true, remaining: 2, reset: time.Second},
+				{allowed: true, remaining: 1, reset: 2 * time.Second},
+				{allowed: true, remaining: 0, reset: 3 * time.Second},
+				{allowed: false, remaining: 0, retry: time.Second, reset: 3 * time.Second},
+			},
+		},
+		{
+			name: "partial refill is not enough",
+			takes: []take{
+				{allowed: true, remaining: 2, reset: time.Second},
+				{allowed: true, remaining: 1, reset: 2 * time.Second},
+				{allowed: true, remaining: 0, reset: 3 * time.Second},
+				{advance: 400 * time.Millisecond, allowed: false, remaining: 0, retry: 600 * time.Millisecond, reset: 2600 * time.Millisecond},
+				{advance: 600 * time.Millisecond, allowed: true, remaining: 0, reset: 3 * time.Second},
+			},
+		},
+		{
+			name: "steady rate is allowed",
+			takes: []take{
+				{allowed: true, remaining: 2, reset: time.Second},
+				{allowed: true, remaining: 1, reset: 2 * time.Second},
+				{allowed: true, remaining: 0, reset: 3 * time.Second},
+				{advance: time.Second, allowed: true, remaining: 0, reset: 3 * time.Second},
+				{advance: time.Second, allowed: true, remaining: 0, reset: 3 * time.Second},
+			},
+		},
+		{
+			name: "refill is capped at capacity",
+			takes: []take{
+				{allowed: true, remaining: 2, reset: time.Second},
+				{advance: time.Hour, allowed: true, remaining: 2, reset: time.Second},
+			},
+		},
+		{
+			name: "rejected request does not consume tokens",
+			takes: []take{
+				{allowed: true, remaining: 2, reset: time.Second},
+				{allowed: true, remaining: 1, reset: 2 * time.Second},
+				{allowed: true, remaining: 0, reset: 3 * time.Second},
+				{allowed: false, remaining: 0, retry: time.Second, reset: 3 * time.Second},
+				{allowed: false, remaining: 0, retry: time.Second, reset: 3 * time.Second},
+				{advance: time.Second, allowed: true, remaining: 0, reset: 3 * time.Second},
+			},
+		},
+	}
+	for storeName, newStore := range stores {
+		for _, tt := range tests {
+			t.Run(storeName+"/"+tt.name, func(t *testing.T) {
+				c := newClock()
+				store := newStore(t, c)
+				for i, want := range tt.takes {
+					c.Advance(want.advance)
+					got, err := store.Take(context.Background(), "client", limit)
+					if err != nil {
+						t.Fatalf("take #%d: %v", i+1, err)
+					}
+					if got.Allowed != want.allowed || got.Limit != limit.Requests || got.Remaining != want.remaining ||
+						!near(got.RetryAfter, want.retry) || !near(got.Reset, want.reset) {
+						t.Errorf("take #%d = %+v, want allowed %t, remaining %d, retry after %s, reset %s",
+							i+1, got, want.allowed, want.remaining, want.retry, want.reset)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestStoreSeparatesKeys(t *testing.T) {
+	limit := ratelimit.Limit{Requests: 1, Period: time.Minute}
+	for storeName, newStore := range stores {
+		t.Run(storeName, func(t *testing.T) {
+			store := newStore(t, newClock())
+			for _, key := range []string{"a", "b"} {
+				got, err := store.Take(context.Background(), key, limit)
+				if err != nil || !got.Allowed {
+					t.Fatalf("first take for %s = %+v, %v, want allowed", key, got, err)
+				}
+			}
+			if got, _ := store.Take(context.Background(), "a", limit); got.Allowed {
+				t.Errorf("second take for a allowed, want rejected")
+			}
+		})
+	}
+}
+
+// near сравнивает длительности с точностью до миллисекунды: Redis хранит время в миллисекундах
+func near(got, want time.Duration) bool {
+	d := got - want
+	return d > -time.Millisecond && d < time.Millisecond
+}
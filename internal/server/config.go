@@ -0,0 +1,70 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Config — настройки HTTP-сервера и его остановки
+type Config struct {
+	Port              string        `config:"port" env:"SERVER_PORT"`
+	ReadTimeout       time.Duration `config:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `config:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `config:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// DrainDelay — сколько сервер продолжает принимать запросы после сигнала остановки,
+	// чтобы балансировщик успел увидеть неготовность и снять с него трафик
+	DrainDelay time.Duration `config:"shutdown_drain_delay" env:"SHUTDOWN_DRAIN_DELAY"`
+	// ShutdownGracePeriod — сколько ждать завершения текущих запросов и фоновых задач при остановке
+	ShutdownGracePeriod time.Duration `config:"shutdown_grace_period" env:"SHUTDOWN_GRACE_PERIOD"`
+	// TrustedProxies — адреса и подсети прокси, которым можно верить в X-Forwarded-For и X-Real-IP.
+	// По умолчанию прокси нет, и адресом клиента считается адрес соединения
+	TrustedProxies []string `config:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+}
+
+// ConfigFrom читает настройки из переменных SERVER_* и SHUTDOWN_*
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Port:                getenv("SERVER_PORT"),
+		ReadTimeout:         15 * time.Second,
+		ReadHeaderTimeout:   5 * time.Second,
+		WriteTimeout:        60 * time.Second,
+		IdleTimeout:         120 * time.Second,
+		ShutdownGracePeriod: 30 * time.Second,
+	}
+	if cfg.Port == "" {
+		cfg.Port = "8080"
+	}
+
+	for _, proxy := range strings.Split(getenv("SERVER_TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return cfg, fmt.Errorf("server.ConfigFrom: invalid SERVER_TRUSTED_PROXIES entry %q, expected an IP address or CIDR", proxy)
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, proxy)
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"SERVER_READ_TIMEOUT":        &cfg.ReadTimeout,
+		"SERVER_READ_HEADER_TIMEOUT": &cfg.ReadHeaderTimeout,
+		"SERVER_WRITE_TIMEOUT":       &cfg.WriteTimeout,
+		"SERVER_IDLE_TIMEOUT":        &cfg.IdleTimeout,
+		"SHUTDOWN_DRAIN_DELAY":       &cfg.DrainDelay,
+		"SHUTDOWN_GRACE_PERIOD":      &cfg.ShutdownGracePeriod,
+	} {
+		value := getenv(key)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("server.ConfigFrom: invalid %s %q", key, value)
+		}
+		*dst = d
+	}
+	return cfg, nil
+}
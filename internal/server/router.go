@@ -0,0 +1,18 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+)
+
+// NewRouter возвращает gin.Engine без middleware, который берёт адрес клиента из X-Forwarded-For и X-Real-IP
+// только от прокси cfg.TrustedProxies. Иначе gin верит этим заголовкам от любого клиента, и лимиты по IP
+// обходятся подменой заголовка
+func NewRouter(cfg Config) (*gin.Engine, error) {
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("server.NewRouter: invalid trusted proxies: %w", err)
+	}
+	return router, nil
+}
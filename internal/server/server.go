@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Run обслуживает handler на порту cfg.Port, пока не отменён ctx; остановка описана в Serve
+func Run(ctx context.Context, handler http.Handler, cfg Config, logger *logrus.Logger, onShutdown ...func()) error {
+	listener, err := net.Listen("tcp", ":"+cfg.Port)
+	if err != nil {
+		return fmt.Errorf("server.Run: %w", err)
+	}
+	logger.Infof("server.Run: Server starting on port %s", cfg.Port)
+	return Serve(ctx, listener, handler, cfg, logger, onShutdown...)
+}
+
+// Serve обслуживает handler на listener, пока не отменён ctx. После отмены и cfg.DrainDelay сервер перестаёт принимать соединения,
+// вызывает onShutdown (например, чтобы закрыть долгие потоки событий) и ждёт завершения текущих запросов
+// не дольше cfg.ShutdownGracePeriod, после чего оставшиеся соединения закрываются принудительно
+func Serve(ctx context.Context, listener net.Listener, handler http.Handler, cfg Config, logger *logrus.Logger, onShutdown ...func()) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	for _, fn := range onShutdown {
+		srv.RegisterOnShutdown(fn)
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return fmt.Errorf("server.Serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	if cfg.DrainDelay > 0 {
+		logger.Infof("server.Serve: Shutdown requested, serving for another %s while traffic drains", cfg.DrainDelay)
+		time.Sleep(cfg.DrainDelay)
+	}
+	logger.Infof("server.Serve: Shutting down, waiting up to %s for in-flight requests", cfg.ShutdownGracePeriod)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownGracePeriod)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warnf("server.Serve: Grace period expired, closing remaining connections: %v", err)
+		return errors.Join(fmt.Errorf("server.Serve: %w", err), srv.Close())
+	}
+	logger.Info("server.Serve: Server stopped")
+	return nil
+}
@@ -0,0 +1,151 @@
+package server_test
+
+import (
+	"app/internal/database/dbtest"
+	"app/internal/server"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// serve запускает server.Serve на свободном порту и возвращает адрес сервера, функцию остановки
+// и канал с результатом Serve
+func serve(t *testing.T, handler http.Handler, cfg server.Config, onShutdown ...func()) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener, handler, cfg, dbtest.Logger(), onShutdown...)
+	}()
+	t.Cleanup(cancel)
+	return "http://" + listener.Addr().String(), cancel, done
+}
+
+// get выполняет GET-запрос и возвращает код ответа и тело
+func get(url string) (int, string, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body), err
+}
+
+// wait ждёт результат Serve не дольше timeout
+func wait(t *testing.T, done <-chan error, timeout time.Duration) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		t.Fatalf("server did not stop within %s", timeout)
+		return nil
+	}
+}
+
+func TestServeDrainsBeforeShutdown(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { _, _ = io.WriteString(w, "ok") })
+	shutdownCalled := make(chan struct{})
+	url, stop, done := serve(t, ok, server.Config{DrainDelay: 300 * time.Millisecond, ShutdownGracePeriod: time.Second},
+		func() { close(shutdownCalled) })
+
+	stop()
+	// Пока идёт DrainDelay, сервер продолжает принимать новые запросы
+	time.Sleep(50 * time.Millisecond)
+	if status, body, err := get(url); err != nil || status != http.StatusOK || body != "ok" {
+		t.Errorf("request during the drain delay: %d %q %v, want 200 ok", status, body, err)
+	}
+	select {
+	case <-shutdownCalled:
+		t.Error("onShutdown called before the drain delay elapsed")
+	default:
+	}
+
+	if err := wait(t, done, 2*time.Second); err != nil {
+		t.Errorf("Serve = %v, want nil after a clean shutdown", err)
+	}
+	// http.Server запускает onShutdown в отдельной горутине, поэтому вызов может немного отстать от Serve
+	select {
+	case <-shutdownCalled:
+	case <-time.After(time.Second):
+		t.Error("onShutdown was not called")
+	}
+	if _, _, err := get(url); err == nil {
+		t.Error("request after shutdown succeeded, want the listener closed")
+	}
+}
+
+func TestServeWaitsForInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		_, _ = io.WriteString(w, "done")
+	})
+	url, stop, done := serve(t, slow, server.Config{ShutdownGracePeriod: 2 * time.Second})
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		status, body, err := get(url)
+		results <- result{status, body, err}
+	}()
+	<-started
+	stop()
+
+	// Запрос, начатый до остановки, завершается, и только после этого Serve возвращается
+	if got := <-results; got.err != nil || got.status != http.StatusOK || got.body != "done" {
+		t.Errorf("in-flight request: %d %q %v, want 200 done", got.status, got.body, got.err)
+	}
+	if err := wait(t, done, 2*time.Second); err != nil {
+		t.Errorf("Serve = %v, want nil after in-flight requests completed", err)
+	}
+}
+
+func TestServeForcesCloseAfterGracePeriod(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	hanging := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	url, stop, done := serve(t, hanging, server.Config{ShutdownGracePeriod: 100 * time.Millisecond})
+
+	results := make(chan error, 1)
+	go func() {
+		_, _, err := get(url)
+		results <- err
+	}()
+	<-started
+	stopped := time.Now()
+	stop()
+
+	if err := wait(t, done, 2*time.Second); err == nil {
+		t.Error("Serve = nil, want an error when the grace period expires")
+	}
+	if elapsed := time.Since(stopped); elapsed > time.Second {
+		t.Errorf("Serve returned %s after stop, want about the 100ms grace period", elapsed)
+	}
+	// Соединение зависшего запроса закрыто принудительно
+	select {
+	case err := <-results:
+		if err == nil {
+			t.Error("hanging request succeeded, want its connection closed")
+		}
+	case <-time.After(time.Second):
+		t.Error("hanging request was not closed after the grace period")
+	}
+}
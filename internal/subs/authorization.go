@@ -0,0 +1,215 @@
+package subs
+
+import (
+	"app/internal/auth"
+	"app/internal/models"
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrForbidden — у вызывающего нет прав на операцию
+var ErrForbidden = errors.New("forbidden")
+
+// authorizedService — Service, ограничивающий операции ролями и владельцем подписки:
+// admin видит и меняет всё, analyst только читает всё, user работает только со своими подписками
+type authorizedService struct {
+	service  Service
+	identity auth.Identity
+}
+
+// Authorize оборачивает service проверками прав вызывающего identity
+func Authorize(service Service, identity auth.Identity) Service {
+	return &authorizedService{
+		service:  service,
+		identity: identity,
+	}
+}
+
+// CreateSub создаёт подписку; user создаёт подписки только на себя
+func (a *authorizedService) CreateSub(ctx context.Context, sub *models.UserSubs) error {
+	if sub.UserID == "" && !a.identity.CanReadAll() {
+		sub.UserID = a.identity.Subject
+	}
+	if !a.identity.CanWrite(sub.UserID) {
+		return ErrForbidden
+	}
+	return a.service.CreateSub(ctx, sub)
+}
+
+// GetSubByID возвращает подписку, если вызывающий может её читать
+func (a *authorizedService) GetSubByID(ctx context.Context, id uint) (*models.UserSubs, error) {
+	sub, err := a.service.GetSubByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !a.identity.CanRead(sub.UserID) {
+		return nil, ErrForbidden
+	}
+	return sub, nil
+}
+
+// UpdateSub обновляет подписку; user не может ни менять чужие подписки, ни передавать свои другому
+func (a *authorizedService) UpdateSub(ctx context.Context, sub *models.UserSubs) error {
+	if err := a.checkWrite(ctx, sub.ID); err != nil {
+		return err
+	}
+	if sub.UserID == "" && !a.identity.CanReadAll() {
+		sub.UserID = a.identity.Subject
+	}
+	if !a.identity.CanWrite(sub.UserID) {
+		return ErrForbidden
+	}
+	return a.service.UpdateSub(ctx, sub)
+}
+
+// DeleteSub удаляет подписку, если вызывающий может её менять
+func (a *authorizedService) DeleteSub(ctx context.Context, id uint) error {
+	if err := a.checkWrite(ctx, id); err != nil {
+		return err
+	}
+	return a.service.DeleteSub(ctx, id)
+}
+
+// ListSubs возвращает все подписки, для user — только его собственные
+func (a *authorizedService) ListSubs(ctx context.Context) ([]models.UserSubs, error) {
+	if !a.identity.CanReadAll() {
+		return a.service.ListSubsByUser(ctx, a.identity.Subject)
+	}
+	return a.service.ListSubs(ctx)
+}
+
+// ListSubsByUser возвращает подписки пользователя, если вызывающий может их читать
+func (a *authorizedService) ListSubsByUser(ctx context.Context, userID string) ([]models.UserSubs, error) {
+	if !a.identity.CanRead(userID) {
+		return nil, ErrForbidden
+	}
+	return a.service.ListSubsByUser(ctx, userID)
+}
+
+// ListSubsWithPagination возвращает страницу подписок, для user — только его собственных
+func (a *authorizedService) ListSubsWithPagination(ctx context.Context, limit, offset int, userID string) ([]models.UserSubs, int64, error) {
+	userID, err := scopeUser(a.identity, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return a.service.ListSubsWithPagination(ctx, limit, offset, userID)
+}
+
+// GetTotalPriceForPeriod считает сумму за период, для user — только по его подпискам
+func (a *authorizedService) GetTotalPriceForPeriod(ctx context.Context, startDate, endDate time.Time, filter ReportFilter) (uint, error) {
+	filter, err := scopeFilter(a.identity, filter)
+	if err != nil {
+		return 0, err
+	}
+	return a.service.GetTotalPriceForPeriod(ctx, startDate, endDate, filter)
+}
+
+// GetTotalPriceBreakdown считает суммы по группам, для user — только по его подпискам
+func (a *authorizedService) GetTotalPriceBreakdown(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, groupBy string) ([]models.TotalByGroup, error) {
+	filter, err := scopeFilter(a.identity, filter)
+	if err != nil {
+		return nil, err
+	}
+	return a.service.GetTotalPriceBreakdown(ctx, startDate, endDate, filter, groupBy)
+}
+
+// GetTimeseries строит временной ряд, для user — только по его подпискам
+func (a *authorizedService) GetTimeseries(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, interval string) ([]models.TimeseriesPoint, error) {
+	filter, err := scopeFilter(a.identity, filter)
+	if err != nil {
+		return nil, err
+	}
+	return a.service.GetTimeseries(ctx, startDate, endDate, filter, interval)
+}
+
+// GetForecast строит прогноз, для user — только по его подпискам
+func (a *authorizedService) GetForecast(ctx context.Context, months int, filter ReportFilter) (*models.Forecast, error) {
+	filter, err := scopeFilter(a.identity, filter)
+	if err != nil {
+		return nil, err
+	}
+	return a.service.GetForecast(ctx, months, filter)
+}
+
+// CreatePriceChange планирует изменение цены, если вызывающий может менять подписку
+func (a *authorizedService) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	if err := a.checkWrite(ctx, change.SubID); err != nil {
+		return err
+	}
+	return a.service.CreatePriceChange(ctx, change)
+}
+
+// ListPriceChanges возвращает изменения цены, если вызывающий может читать подписку
+func (a *authorizedService) ListPriceChanges(ctx context.Context, subID uint) ([]models.PriceChange, error) {
+	if _, err := a.GetSubByID(ctx, subID); err != nil {
+		return nil, err
+	}
+	return a.service.ListPriceChanges(ctx, subID)
+}
+
+// ListPriceChangesByUser возвращает изменения цены подписок пользователя, если вызывающий может их читать
+func (a *authorizedService) ListPriceChangesByUser(ctx context.Context, userID string) (map[uint][]models.PriceChange, error) {
+	if !a.identity.CanRead(userID) {
+		return nil, ErrForbidden
+	}
+	return a.service.ListPriceChangesByUser(ctx, userID)
+}
+
+// DeletePriceChange отменяет изменение цены, если вызывающий может менять подписку
+func (a *authorizedService) DeletePriceChange(ctx context.Context, subID, changeID uint) error {
+	if err := a.checkWrite(ctx, subID); err != nil {
+		return err
+	}
+	return a.service.DeletePriceChange(ctx, subID, changeID)
+}
+
+// StreamEvents передаёт события, для user — только о его подписках
+func (a *authorizedService) StreamEvents(ctx context.Context, filter ReportFilter, afterSeq *uint64, send func(models.SubEvent) error) error {
+	filter, err := scopeFilter(a.identity, filter)
+	if err != nil {
+		return err
+	}
+	return a.service.StreamEvents(ctx, filter, afterSeq, send)
+}
+
+// ForTenant возвращает Service арендатора tenantID с теми же правами вызывающего
+func (a *authorizedService) ForTenant(tenantID string) Service {
+	return Authorize(a.service.ForTenant(tenantID), a.identity)
+}
+
+// checkWrite проверяет, что подписка существует и вызывающий может её менять
+func (a *authorizedService) checkWrite(ctx context.Context, id uint) error {
+	sub, err := a.service.GetSubByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !a.identity.CanWrite(sub.UserID) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// scopeUser ограничивает выборку по user_id: user без user_id получает свои подписки, чужие ему недоступны
+func scopeUser(identity auth.Identity, userID string) (string, error) {
+	if identity.CanReadAll() {
+		return userID, nil
+	}
+	if userID == "" {
+		return identity.Subject, nil
+	}
+	if !identity.CanRead(userID) {
+		return "", ErrForbidden
+	}
+	return userID, nil
+}
+
+// scopeFilter ограничивает фильтр отчёта подписками, доступными вызывающему identity
+func scopeFilter(identity auth.Identity, filter ReportFilter) (ReportFilter, error) {
+	userID, err := scopeUser(identity, filter.UserID)
+	if err != nil {
+		return filter, err
+	}
+	filter.UserID = userID
+	return filter, nil
+}
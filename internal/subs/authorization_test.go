@@ -0,0 +1,382 @@
+package subs_test
+
+import (
+	"app/internal/auth"
+	"app/internal/models"
+	"app/internal/subs"
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Владельцы подписок stubService: подписка 1 принадлежит alice, подписка 2 — bob
+const (
+	alice = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	bob   = "0b7ac2d4-4a5e-4c0e-9a8e-3f1f2b6d9e11"
+)
+
+// subOf — ID подписки пользователя
+var subOf = map[string]uint{alice: 1, bob: 2}
+
+// call — вызов Service, дошедший до stubService: метод и пользователь, чьи данные он затрагивает
+type call struct {
+	method string
+	userID string
+}
+
+// stubService — Service, который не обращается к бд, а запоминает вызовы
+type stubService struct {
+	calls []call
+}
+
+func (s *stubService) record(method, userID string) { s.calls = append(s.calls, call{method, userID}) }
+
+func (s *stubService) owner(id uint) string {
+	for userID, subID := range subOf {
+		if subID == id {
+			return userID
+		}
+	}
+	return ""
+}
+
+func (s *stubService) CreateSub(_ context.Context, sub *models.UserSubs) error {
+	s.record("CreateSub", sub.UserID)
+	return nil
+}
+
+func (s *stubService) GetSubByID(_ context.Context, id uint) (*models.UserSubs, error) {
+	s.record("GetSubByID", s.owner(id))
+	return &models.UserSubs{ID: id, UserID: s.owner(id)}, nil
+}
+
+func (s *stubService) UpdateSub(_ context.Context, sub *models.UserSubs) error {
+	s.record("UpdateSub", sub.UserID)
+	return nil
+}
+
+func (s *stubService) DeleteSub(_ context.Context, id uint) error {
+	s.record("DeleteSub", s.owner(id))
+	return nil
+}
+
+func (s *stubService) ListSubs(context.Context) ([]models.UserSubs, error) {
+	s.record("ListSubs", "")
+	return nil, nil
+}
+
+func (s *stubService) ListSubsByUser(_ context.Context, userID string) ([]models.UserSubs, error) {
+	s.record("ListSubsByUser", userID)
+	return nil, nil
+}
+
+func (s *stubService) ListSubsWithPagination(_ context.Context, _, _ int, userID string) ([]models.UserSubs, int64, error) {
+	s.record("ListSubsWithPagination", userID)
+	return nil, 0, nil
+}
+
+func (s *stubService) GetTotalPriceForPeriod(_ context.Context, _, _ time.Time, filter subs.ReportFilter) (uint, error) {
+	s.record("GetTotalPriceForPeriod", filter.UserID)
+	return 0, nil
+}
+
+func (s *stubService) GetTotalPriceBreakdown(_ context.Context, _, _ time.Time, filter subs.ReportFilter, _ string) ([]models.TotalByGroup, error) {
+	s.record("GetTotalPriceBreakdown", filter.UserID)
+	return nil, nil
+}
+
+func (s *stubService) GetTimeseries(_ context.Context, _, _ time.Time, filter subs.ReportFilter, _ string) ([]models.TimeseriesPoint, error) {
+	s.record("GetTimeseries", filter.UserID)
+	return nil, nil
+}
+
+func (s *stubService) GetForecast(_ context.Context, _ int, filter subs.ReportFilter) (*models.Forecast, error) {
+	s.record("GetForecast", filter.UserID)
+	return nil, nil
+}
+
+func (s *stubService) CreatePriceChange(_ context.Context, change *models.PriceChange) error {
+	s.record("CreatePriceChange", s.owner(change.SubID))
+	return nil
+}
+
+func (s *stubService) ListPriceChanges(_ context.Context, subID uint) ([]models.PriceChange, error) {
+	s.record("ListPriceChanges", s.owner(subID))
+	return nil, nil
+}
+
+func (s *stubService) ListPriceChangesByUser(_ context.Context, userID string) (map[uint][]models.PriceChange, error) {
+	s.record("ListPriceChangesByUser", userID)
+	return nil, nil
+}
+
+func (s *stubService) DeletePriceChange(_ context.Context, subID, _ uint) error {
+	s.record("DeletePriceChange", s.owner(subID))
+	return nil
+}
+
+func (s *stubService) StreamEvents(_ context.Context, filter subs.ReportFilter, _ *uint64, _ func(models.SubEvent) error) error {
+	s.record("StreamEvents", filter.UserID)
+	return nil
+}
+
+func (s *stubService) ForTenant(string) subs.Service { return s }
+
+// access — вид операции: чтение или изменение данных пользователя, либо выборка без user_id
+type access int
+
+const (
+	read access = iota
+	write
+	list
+)
+
+// authorizedMethods — все методы Service. call вызывает метод для данных пользователя userID;
+// для list userID пустой
+var authorizedMethods = []struct {
+	name   string
+	access access
+	call   func(s subs.Service, userID string) error
+}{
+	{"CreateSub", write, func(s subs.Service, userID string) error {
+		return s.CreateSub(context.Background(), &models.UserSubs{UserID: userID})
+	}},
+	{"GetSubByID", read, func(s subs.Service, userID string) error {
+		_, err := s.GetSubByID(context.Background(), subOf[userID])
+		return err
+	}},
+	{"UpdateSub", write, func(s subs.Service, userID string) error {
+		return s.UpdateSub(context.Background(), &models.UserSubs{ID: subOf[userID], UserID: userID})
+	}},
+	{"DeleteSub", write, func(s subs.Service, userID string) error {
+		return s.DeleteSub(context.Background(), subOf[userID])
+	}},
+	{"ListSubs", list, func(s subs.Service, _ string) error {
+		_, err := s.ListSubs(context.Background())
+		return err
+	}},
+	{"ListSubsByUser", read, func(s subs.Service, userID string) error {
+		_, err := s.ListSubsByUser(context.Background(), userID)
+		return err
+	}},
+	{"ListSubsWithPagination", read, func(s subs.Service, userID string) error {
+		_, _, err := s.ListSubsWithPagination(context.Background(), 10, 0, userID)
+		return err
+	}},
+	{"ListSubsWithPagination", list, func(s subs.Service, userID string) error {
+		_, _, err := s.ListSubsWithPagination(context.Background(), 10, 0, userID)
+		return err
+	}},
+	{"GetTotalPriceForPeriod", read, func(s subs.Service, userID string) error {
+		_, err := s.GetTotalPriceForPeriod(context.Background(), time.Time{}, time.Time{}, subs.ReportFilter{UserID: userID})
+		return err
+	}},
+	{"GetTotalPriceForPeriod", list, func(s subs.Service, userID string) error {
+		_, err := s.GetTotalPriceForPeriod(context.Background(), time.Time{}, time.Time{}, subs.ReportFilter{UserID: userID})
+		return err
+	}},
+	{"GetTotalPriceBreakdown", read, func(s subs.Service, userID string) error {
+		_, err := s.GetTotalPriceBreakdown(context.Background(), time.Time{}, time.Time{}, subs.ReportFilter{UserID: userID}, subs.GroupByCategory)
+		return err
+	}},
+	{"GetTotalPriceBreakdown", list, func(s subs.Service, userID string) error {
+		_, err := s.GetTotalPriceBreakdown(context.Background(), time.Time{}, time.Time{}, subs.ReportFilter{UserID: userID}, subs.GroupByCategory)
+		return err
+	}},
+	{"GetTimeseries", read, func(s subs.Service, userID string) error {
+		_, err := s.GetTimeseries(context.Background(), time.Time{}, time.Time{}, subs.ReportFilter{UserID: userID}, string(models.BillingMonth))
+		return err
+	}},
+	{"GetTimeseries", list, func(s subs.Service, userID string) error {
+		_, err := s.GetTimeseries(context.Background(), time.Time{}, time.Time{}, subs.ReportFilter{UserID: userID}, string(models.BillingMonth))
+		return err
+	}},
+	{"GetForecast", read, func(s subs.Service, userID string) error {
+		_, err := s.GetForecast(context.Background(), 12, subs.ReportFilter{UserID: userID})
+		return err
+	}},
+	{"GetForecast", list, func(s subs.Service, userID string) error {
+		_, err := s.GetForecast(context.Background(), 12, subs.ReportFilter{UserID: userID})
+		return err
+	}},
+	{"CreatePriceChange", write, func(s subs.Service, userID string) error {
+		return s.CreatePriceChange(context.Background(), &models.PriceChange{SubID: subOf[userID]})
+	}},
+	{"ListPriceChanges", read, func(s subs.Service, userID string) error {
+		_, err := s.ListPriceChanges(context.Background(), subOf[userID])
+		return err
+	}},
+	{"ListPriceChangesByUser", read, func(s subs.Service, userID string) error {
+		_, err := s.ListPriceChangesByUser(context.Background(), userID)
+		return err
+	}},
+	{"DeletePriceChange", write, func(s subs.Service, userID string) error {
+		return s.DeletePriceChange(context.Background(), subOf[userID], 1)
+	}},
+	{"StreamEvents", read, func(s subs.Service, userID string) error {
+		return s.StreamEvents(context.Background(), subs.ReportFilter{UserID: userID}, nil, nil)
+	}},
+	{"StreamEvents", list, func(s subs.Service, userID string) error {
+		return s.StreamEvents(context.Background(), subs.ReportFilter{UserID: userID}, nil, nil)
+	}},
+}
+
+// forbidden — ожидаемый отказ вместо вызова Service
+const forbidden = "forbidden"
+
+func TestAuthorize(t *testing.T) {
+	// want — для каждого вида операции: пользователь, с которым вызов дойдёт до Service, или forbidden.
+	// Для read и write — сначала по своим данным (alice), затем по чужим (bob); для list — одно значение,
+	// пустое, если выборка не ограничена пользователем
+	roles := []struct {
+		name     string
+		identity auth.Identity
+		want     map[access][]string
+	}{
+		{
+			name:     "admin",
+			identity: auth.Identity{Subject: alice, Roles: []string{auth.RoleAdmin}},
+			want:     map[access][]string{read: {alice, bob}, write: {alice, bob}, list: {""}},
+		},
+		{
+			name:     "analyst",
+			identity: auth.Identity{Subject: alice, Roles: []string{auth.RoleAnalyst}},
+			want:     map[access][]string{read: {alice, bob}, write: {forbidden, forbidden}, list: {""}},
+		},
+		{
+			name:     "writer",
+			identity: auth.Identity{Subject: alice, Roles: []string{auth.RoleWriter}},
+			want:     map[access][]string{read: {alice, bob}, write: {alice, bob}, list: {""}},
+		},
+		{
+			name:     "user",
+			identity: auth.Identity{Subject: alice, Roles: []string{auth.RoleUser}},
+			want:     map[access][]string{read: {alice, forbidden}, write: {alice, forbidden}, list: {alice}},
+		},
+		{
+			name:     "no roles in token",
+			identity: auth.Identity{Subject: alice},
+			want:     map[access][]string{read: {alice, forbidden}, write: {alice, forbidden}, list: {alice}},
+		},
+	}
+	for _, role := range roles {
+		for _, method := range authorizedMethods {
+			targets := []string{alice, bob}
+			if method.access == list {
+				targets = []string{""}
+			}
+			for i, target := range targets {
+				want := role.want[method.access][i]
+				name := role.name + "/" + method.name
+				if target != "" {
+					name += "/" + map[string]string{alice: "own", bob: "other"}[target]
+				}
+				t.Run(name, func(t *testing.T) {
+					stub := &stubService{}
+					// ForTenant сохраняет права вызывающего
+					err := method.call(subs.Authorize(stub, role.identity).ForTenant("tenant-a"), target)
+					if want == forbidden {
+						if !errors.Is(err, subs.ErrForbidden) {
+							t.Errorf("error %v, want ErrForbidden", err)
+						}
+						// GetSubByID читает подписку, чтобы узнать её владельца, но не возвращает её
+						if method.name != "GetSubByID" && slices.ContainsFunc(stub.calls, func(c call) bool { return c.method == method.name }) {
+							t.Errorf("forbidden call reached the service: %v", stub.calls)
+						}
+						return
+					}
+					if err != nil {
+						t.Fatalf("error %v, want the call to be allowed", err)
+					}
+					if got := stub.calls[len(stub.calls)-1]; got.userID != want {
+						t.Errorf("service called with %+v, want user %q", got, want)
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestAuthorizeOwnership(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity auth.Identity
+		call     func(s subs.Service) error
+		want     call
+	}{
+		{
+			name:     "user creates a subscription without user_id for themselves",
+			identity: auth.Identity{Subject: alice},
+			call:     func(s subs.Service) error { return s.CreateSub(context.Background(), &models.UserSubs{}) },
+			want:     call{"CreateSub", alice},
+		},
+		{
+			name:     "admin creates a subscription without user_id as is",
+			identity: auth.Identity{Subject: alice, Roles: []string{auth.RoleAdmin}},
+			call:     func(s subs.Service) error { return s.CreateSub(context.Background(), &models.UserSubs{}) },
+			want:     call{"CreateSub", ""},
+		},
+		{
+			name:     "user updates their subscription without user_id",
+			identity: auth.Identity{Subject: alice},
+			call: func(s subs.Service) error {
+				return s.UpdateSub(context.Background(), &models.UserSubs{ID: subOf[alice]})
+			},
+			want: call{"UpdateSub", alice},
+		},
+		{
+			name:     "user cannot hand their subscription over",
+			identity: auth.Identity{Subject: alice},
+			call: func(s subs.Service) error {
+				return s.UpdateSub(context.Background(), &models.UserSubs{ID: subOf[alice], UserID: bob})
+			},
+		},
+		{
+			name:     "user cannot take over another subscription",
+			identity: auth.Identity{Subject: alice},
+			call: func(s subs.Service) error {
+				return s.UpdateSub(context.Background(), &models.UserSubs{ID: subOf[bob], UserID: alice})
+			},
+		},
+		{
+			name:     "admin hands a subscription over",
+			identity: auth.Identity{Subject: alice, Roles: []string{auth.RoleAdmin}},
+			call: func(s subs.Service) error {
+				return s.UpdateSub(context.Background(), &models.UserSubs{ID: subOf[alice], UserID: bob})
+			},
+			want: call{"UpdateSub", bob},
+		},
+		{
+			name:     "user lists their own subscriptions only",
+			identity: auth.Identity{Subject: alice},
+			call: func(s subs.Service) error {
+				_, err := s.ListSubs(context.Background())
+				return err
+			},
+			want: call{"ListSubsByUser", alice},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubService{}
+			err := tt.call(subs.Authorize(stub, tt.identity))
+			if tt.want == (call{}) {
+				if !errors.Is(err, subs.ErrForbidden) {
+					t.Errorf("error %v, want ErrForbidden", err)
+				}
+				if slices.ContainsFunc(stub.calls, func(c call) bool { return c.method == "UpdateSub" }) {
+					t.Errorf("forbidden call reached the service: %v", stub.calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error %v, want the call to be allowed", err)
+			}
+			if got := stub.calls[len(stub.calls)-1]; got != tt.want {
+				t.Errorf("service called with %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
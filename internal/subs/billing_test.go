@@ -0,0 +1,42 @@
+package subs_test
+
+import (
+	"app/internal/models"
+	"app/internal/subs"
+	"testing"
+	"time"
+)
+
+func TestNextBillingDate(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+	end := date(2023, time.April, 15)
+	tests := []struct {
+		name   string
+		start  time.Time
+		end    *time.Time
+		period models.BillingPeriod
+		after  time.Time
+		want   time.Time
+		ok     bool
+	}{
+		{name: "before start", start: date(2023, time.January, 10), period: models.BillingMonth, after: date(2023, time.January, 1), want: date(2023, time.January, 10), ok: true},
+		{name: "on billing day", start: date(2023, time.January, 10), period: models.BillingMonth, after: date(2023, time.February, 10), want: date(2023, time.March, 10), ok: true},
+		{name: "31st in February", start: date(2023, time.January, 31), period: models.BillingMonth, after: date(2023, time.February, 1), want: date(2023, time.February, 28), ok: true},
+		{name: "31st after February", start: date(2023, time.January, 31), period: models.BillingMonth, after: date(2023, time.February, 28), want: date(2023, time.March, 31), ok: true},
+		{name: "30th in leap February", start: date(2024, time.January, 30), period: models.BillingMonth, after: date(2024, time.February, 1), want: date(2024, time.February, 29), ok: true},
+		{name: "quarterly from the 30th", start: date(2022, time.November, 30), period: models.BillingQuarter, after: date(2022, time.December, 1), want: date(2023, time.February, 28), ok: true},
+		{name: "yearly from leap day", start: date(2024, time.February, 29), period: models.BillingYear, after: date(2024, time.March, 1), want: date(2025, time.February, 28), ok: true},
+		{name: "after end date", start: date(2023, time.January, 31), end: &end, period: models.BillingMonth, after: date(2023, time.April, 1), ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := models.UserSubs{StartDate: tt.start, EndDate: tt.end}
+			got, ok := subs.NextBillingDate(sub, tt.period, tt.after)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("NextBillingDate(%s, %s, %s) = %s, %t, want %s, %t", tt.start, tt.period, tt.after, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -1,13 +1,22 @@
 package subs
 
 import (
+	"app/internal/auth"
+	"app/internal/catalog"
+	"app/internal/logging"
 	"app/internal/models"
+	"app/internal/tenant"
+	"app/internal/timeout"
+	"context"
 	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
@@ -21,8 +30,18 @@ type Handlers interface {
 	DeleteSub(c *gin.Context)
 	ListSubs(c *gin.Context)
 	GetTotalPriceForPeriod(c *gin.Context)
+	GetTotalPriceBreakdown(c *gin.Context)
+	GetTimeseries(c *gin.Context)
+	GetForecast(c *gin.Context)
+	CreatePriceChange(c *gin.Context)
+	ListPriceChanges(c *gin.Context)
+	DeletePriceChange(c *gin.Context)
+	StreamEvents(c *gin.Context)
 }
 
+// eventHeartbeat — интервал комментариев-пингов в потоке событий
+const eventHeartbeat = 15 * time.Second
+
 // handlers  — структура, реализующая интерфейс Handlers
 type handlers struct {
 	service Service
@@ -39,26 +58,45 @@ func NewHandlers(service Service, logger *logrus.Logger) Handlers {
 
 // CreateSub godoc
 // @Summary Создаёт запись подписки
-// @Description Создает новую запись о подписке пользователя (формат времени: RFC3339 [2025-07-31T19:00:00Z])
+// @Description Создает новую запись о подписке пользователя (формат времени: RFC3339 [2025-07-31T19:00:00Z]).
+// @Description Сервис указывается через service_id или service_name (название или алиас из каталога); сервис не из каталога — 422. Без price берётся цена сервиса по умолчанию.
+// @Description Без end_date подписка считается бессрочной
 // @Tags subscriptions
 // @Accept json
 // @Produce json
 // @Param subscription body models.UserSubs true "Данные подписки"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом вернёт первый ответ"
 // @Success 201 {object} models.UserSubs
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subs [post]
 func (h *handlers) CreateSub(c *gin.Context) {
-	h.logger.Info("handlers.CreateSub: Creating subscription")
+	h.log(c).Info("handlers.CreateSub: Creating subscription")
 	var sub models.UserSubs
 	if err := c.ShouldBindJSON(&sub); err != nil {
-		h.logger.Errorf("handlers.CreateSub: Failed to bind JSON: %v", err)
+		h.log(c).Errorf("handlers.CreateSub: Failed to bind JSON: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	logging.Annotate(c, logrus.Fields{"user_id": sub.UserID})
 
-	if err := h.service.CreateSub(&sub); err != nil {
-		h.logger.Errorf("handlers.CreateSub: Failed to create subscription: %v", err)
+	if err := h.scoped(c).CreateSub(c.Request.Context(), &sub); err != nil {
+		h.log(c).Errorf("handlers.CreateSub: Failed to create subscription: %v", err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
+		if errors.Is(err, catalog.ErrUnknownService) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		// Проверяем, является ли ошибка ошибкой БД
 		if errors.Is(err, gorm.ErrRecordNotFound) || strings.Contains(err.Error(), "database") {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -68,7 +106,7 @@ func (h *handlers) CreateSub(c *gin.Context) {
 		return
 	}
 
-	h.logger.Infof("handlers.CreateSub: Subscription created successfully with ID %d", sub.ID)
+	h.log(c).Infof("handlers.CreateSub: Subscription created successfully with ID %d", sub.ID)
 	c.JSON(http.StatusCreated, sub)
 }
 
@@ -81,19 +119,29 @@ func (h *handlers) CreateSub(c *gin.Context) {
 // @Success 200 {object} models.UserSubs
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subs/{id} [get]
 func (h *handlers) GetSubByID(c *gin.Context) {
-	h.logger.Info("handlers.GetSubByID: Fetching subscription by ID")
+	h.log(c).Info("handlers.GetSubByID: Fetching subscription by ID")
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		h.logger.Errorf("handlers.GetSubByID: Invalid ID format: %v", err)
+		h.log(c).Errorf("handlers.GetSubByID: Invalid ID format: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
+	logging.Annotate(c, logrus.Fields{"sub_id": id})
 
-	sub, err := h.service.GetSubByID(uint(id))
+	sub, err := h.scoped(c).GetSubByID(c.Request.Context(), uint(id))
 	if err != nil {
-		h.logger.Warnf("handlers.GetSubByID: Subscription not found with ID %d", id)
+		h.log(c).Warnf("handlers.GetSubByID: Subscription not found with ID %d", id)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
 		// Проверяем, является ли ошибка ошибкой БД
 		if errors.Is(err, gorm.ErrRecordNotFound) || strings.Contains(err.Error(), "database") {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -103,7 +151,7 @@ func (h *handlers) GetSubByID(c *gin.Context) {
 		return
 	}
 
-	h.logger.Infof("handlers.GetSubByID: Subscription with ID %d fetched successfully", id)
+	h.log(c).Infof("handlers.GetSubByID: Subscription with ID %d fetched successfully", id)
 	c.JSON(http.StatusOK, sub)
 }
 
@@ -118,27 +166,43 @@ func (h *handlers) GetSubByID(c *gin.Context) {
 // @Success 200 {object} models.UserSubs
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subs/{id} [put]
 func (h *handlers) UpdateSub(c *gin.Context) {
-	h.logger.Info("handlers.UpdateSub: Updating subscription")
+	h.log(c).Info("handlers.UpdateSub: Updating subscription")
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		h.logger.Errorf("handlers.UpdateSub: Invalid ID format: %v", err)
+		h.log(c).Errorf("handlers.UpdateSub: Invalid ID format: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
+	logging.Annotate(c, logrus.Fields{"sub_id": id})
 
 	var sub models.UserSubs
 	if err := c.ShouldBindJSON(&sub); err != nil {
-		h.logger.Errorf("handlers.UpdateSub: Failed to bind JSON: %v", err)
+		h.log(c).Errorf("handlers.UpdateSub: Failed to bind JSON: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 	// Убеждаемся, что ID из URL используется, а не из тела запроса
 	sub.ID = uint(id)
+	logging.Annotate(c, logrus.Fields{"user_id": sub.UserID})
 
-	if err := h.service.UpdateSub(&sub); err != nil {
-		h.logger.Errorf("handlers.UpdateSub: Failed to update subscription with ID %d: %v", id, err)
+	if err := h.scoped(c).UpdateSub(c.Request.Context(), &sub); err != nil {
+		h.log(c).Errorf("handlers.UpdateSub: Failed to update subscription with ID %d: %v", id, err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
+		if errors.Is(err, catalog.ErrUnknownService) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			return
+		}
 		// Проверяем, является ли ошибка ошибкой БД
 		if errors.Is(err, gorm.ErrRecordNotFound) || strings.Contains(err.Error(), "database") {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -148,7 +212,7 @@ func (h *handlers) UpdateSub(c *gin.Context) {
 		return
 	}
 
-	h.logger.Infof("handlers.UpdateSub: Subscription with ID %d updated successfully", id)
+	h.log(c).Infof("handlers.UpdateSub: Subscription with ID %d updated successfully", id)
 	c.JSON(http.StatusOK, sub)
 }
 
@@ -161,18 +225,28 @@ func (h *handlers) UpdateSub(c *gin.Context) {
 // @Success 204
 // @Failure 400 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subs/{id} [delete]
 func (h *handlers) DeleteSub(c *gin.Context) {
-	h.logger.Info("handlers.DeleteSub: Deleting subscription")
+	h.log(c).Info("handlers.DeleteSub: Deleting subscription")
 	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
 	if err != nil {
-		h.logger.Errorf("handlers.DeleteSub: Invalid ID format: %v", err)
+		h.log(c).Errorf("handlers.DeleteSub: Invalid ID format: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
 		return
 	}
+	logging.Annotate(c, logrus.Fields{"sub_id": id})
 
-	if err := h.service.DeleteSub(uint(id)); err != nil {
-		h.logger.Warnf("handlers.DeleteSub: Subscription not found with ID %d", id)
+	if err := h.scoped(c).DeleteSub(c.Request.Context(), uint(id)); err != nil {
+		h.log(c).Warnf("handlers.DeleteSub: Subscription not found with ID %d", id)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
 		// Проверяем, является ли ошибка ошибкой БД
 		if errors.Is(err, gorm.ErrRecordNotFound) || strings.Contains(err.Error(), "database") {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -182,7 +256,7 @@ func (h *handlers) DeleteSub(c *gin.Context) {
 		return
 	}
 
-	h.logger.Infof("handlers.DeleteSub: Subscription with ID %d deleted successfully", id)
+	h.log(c).Infof("handlers.DeleteSub: Subscription with ID %d deleted successfully", id)
 	c.Status(http.StatusNoContent)
 }
 
@@ -193,14 +267,22 @@ func (h *handlers) DeleteSub(c *gin.Context) {
 // @Produce json
 // @Param page query int false "Номер страницы (по умолчанию 1)"
 // @Param limit query int false "Количество элементов на странице (по умолчанию 10, максимум 100)"
+// @Param user_id query string false "ID пользователя; обычный пользователь видит только свои подписки"
 // @Success 200 {array} models.UserSubs
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subs [get]
 func (h *handlers) ListSubs(c *gin.Context) {
-	h.logger.Info("handlers.ListSubs: Fetching list of all subscriptions")
+	h.log(c).Info("handlers.ListSubs: Fetching list of all subscriptions")
 
 	// Получаем параметры пагинации
 	pageStr := c.Query("page")
 	limitStr := c.Query("limit")
+	userID := c.Query("user_id")
+	if userID != "" {
+		logging.Annotate(c, logrus.Fields{"user_id": userID})
+	}
 
 	page := 1
 	limit := 10
@@ -221,9 +303,15 @@ func (h *handlers) ListSubs(c *gin.Context) {
 	if pageStr != "" || limitStr != "" {
 		offset := (page - 1) * limit
 
-		subs, total, err := h.service.ListSubsWithPagination(limit, offset)
+		subs, total, err := h.scoped(c).ListSubsWithPagination(c.Request.Context(), limit, offset, userID)
 		if err != nil {
-			h.logger.Errorf("handlers.ListSubs: Failed to fetch list of subscriptions: %v", err)
+			h.log(c).Errorf("handlers.ListSubs: Failed to fetch list of subscriptions: %v", err)
+			if forbidden(c, err) {
+				return
+			}
+			if timeout.Expired(c) {
+				return
+			}
 			// Проверяем, является ли ошибка ошибкой БД
 			if strings.Contains(err.Error(), "database") {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -233,7 +321,7 @@ func (h *handlers) ListSubs(c *gin.Context) {
 			return
 		}
 
-		h.logger.Infof("handlers.ListSubs: Fetched %d subscriptions (page %d, limit %d)", len(subs), page, limit)
+		h.log(c).Infof("handlers.ListSubs: Fetched %d subscriptions (page %d, limit %d)", len(subs), page, limit)
 		c.JSON(http.StatusOK, gin.H{
 			"subscriptions": subs,
 			"pagination": gin.H{
@@ -246,9 +334,21 @@ func (h *handlers) ListSubs(c *gin.Context) {
 	}
 
 	// Если параметры пагинации не указаны, возвращаем все подписки (обратная совместимость)
-	subs, err := h.service.ListSubs()
+	var subs []models.UserSubs
+	var err error
+	if userID != "" {
+		subs, err = h.scoped(c).ListSubsByUser(c.Request.Context(), userID)
+	} else {
+		subs, err = h.scoped(c).ListSubs(c.Request.Context())
+	}
 	if err != nil {
-		h.logger.Errorf("handlers.ListSubs: Failed to fetch list of subscriptions: %v", err)
+		h.log(c).Errorf("handlers.ListSubs: Failed to fetch list of subscriptions: %v", err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
 		// Проверяем, является ли ошибка ошибкой БД
 		if strings.Contains(err.Error(), "database") {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -258,61 +358,190 @@ func (h *handlers) ListSubs(c *gin.Context) {
 		return
 	}
 
-	h.logger.Infof("handlers.ListSubs: Fetched %d subscriptions", len(subs))
+	h.log(c).Infof("handlers.ListSubs: Fetched %d subscriptions", len(subs))
 	c.JSON(http.StatusOK, subs)
 }
 
 // GetTotalPriceForPeriod godoc
 // @Summary Подсчитать сумму подписок за период
-// @Description Подсчитывает суммарную стоимость всех подписок за выбранный период с фильтрацией по ID пользователя и названию подписки
+// @Description Подсчитывает суммарную стоимость всех подписок за выбранный период с фильтрацией по ID пользователя, названию подписки и категории сервиса.
+// @Description Сумма считается по подпискам арендатора запроса, он возвращается в tenant_id
 // @Tags subscriptions
 // @Produce json
 // @Param start_date query string true "Дата начала периода (в формате RFC3339) [2025-06-30T19:00:00Z]"
 // @Param end_date query string true "Дата окончания периода (в формате RFC3339) [2025-07-31T19:00:00Z]"
 // @Param user_id query string false "ID пользователя"
-// @Param service_name query string false "Название сервиса"
-// @Success 200 {object} map[string]uint
+// @Param service_name query string false "Название сервиса или его алиас из каталога"
+// @Param category query string false "Категория сервиса"
+// @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]string
 // @Failure 500 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
 // @Router /subs/total [get]
 func (h *handlers) GetTotalPriceForPeriod(c *gin.Context) {
-	h.logger.Info("handlers.GetTotalPriceForPeriod: Calculating total price for period")
-	// Парсим параметрф запроса
-	startDateStr := c.Query("start_date")
-	endDateStr := c.Query("end_date")
-	userID := c.Query("user_id")
-	serviceName := c.Query("service_name")
+	h.log(c).Info("handlers.GetTotalPriceForPeriod: Calculating total price for period")
+	startDate, endDate, ok := h.parsePeriod(c, "GetTotalPriceForPeriod", "start_date", "end_date")
+	if !ok {
+		return
+	}
 
-	// Проверяем необходмые параметры
-	if startDateStr == "" {
-		h.logger.Warn("handlers.GetTotalPriceForPeriod: start_date is required")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date is required"})
+	total, err := h.scoped(c).GetTotalPriceForPeriod(c.Request.Context(), startDate, endDate, reportFilter(c))
+	if err != nil {
+		h.log(c).Errorf("handlers.GetTotalPriceForPeriod: Failed to calculate total price: %v", err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
+		// Проверяем, является ли ошибка ошибкой БД
+		if strings.Contains(err.Error(), "database") {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
-	if endDateStr == "" {
-		h.logger.Warn("handlers.GetTotalPriceForPeriod: end_date is required")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date is required"})
+
+	h.log(c).Infof("handlers.GetTotalPriceForPeriod: Total price calculated: %d", total)
+	c.JSON(http.StatusOK, gin.H{"total": total, "tenant_id": tenant.From(c)})
+}
+
+// GetTotalPriceBreakdown godoc
+// @Summary Сумма подписок за период в разрезе категорий
+// @Description Подсчитывает стоимость подписок за период, сгруппированную по категориям или сервисам (пустая категория — сервисы без категории)
+// @Tags subscriptions
+// @Produce json
+// @Param start_date query string true "Дата начала периода (в формате RFC3339) [2025-06-30T19:00:00Z]"
+// @Param end_date query string true "Дата окончания периода (в формате RFC3339) [2025-07-31T19:00:00Z]"
+// @Param group_by query string false "Группировка: category (по умолчанию) или service"
+// @Param user_id query string false "ID пользователя"
+// @Param service_name query string false "Название сервиса или его алиас из каталога"
+// @Param category query string false "Категория сервиса"
+// @Success 200 {array} models.TotalByGroup
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subs/total/breakdown [get]
+func (h *handlers) GetTotalPriceBreakdown(c *gin.Context) {
+	h.log(c).Info("handlers.GetTotalPriceBreakdown: Calculating totals breakdown for period")
+	startDate, endDate, ok := h.parsePeriod(c, "GetTotalPriceBreakdown", "start_date", "end_date")
+	if !ok {
 		return
 	}
 
-	// Парсим даты
-	startDate, err := time.Parse(time.RFC3339, startDateStr)
+	totals, err := h.scoped(c).GetTotalPriceBreakdown(c.Request.Context(), startDate, endDate, reportFilter(c), c.Query("group_by"))
 	if err != nil {
-		h.logger.Errorf("handlers.GetTotalPriceForPeriod: Invalid start_date format: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid start_date format, expected RFC3339"})
+		h.log(c).Errorf("handlers.GetTotalPriceBreakdown: Failed to calculate totals: %v", err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
+		// Проверяем, является ли ошибка ошибкой БД
+		if strings.Contains(err.Error(), "database") {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	endDate, err := time.Parse(time.RFC3339, endDateStr)
+	h.log(c).Infof("handlers.GetTotalPriceBreakdown: Calculated totals for %d groups", len(totals))
+	c.JSON(http.StatusOK, totals)
+}
+
+// GetTimeseries godoc
+// @Summary Временной ряд расходов на подписки
+// @Description Возвращает по одной точке на месяц, квартал или год: сумму списаний и количество активных подписок (не больше 120 точек)
+// @Tags subscriptions
+// @Produce json
+// @Param start query string true "Дата начала периода (в формате RFC3339) [2025-01-01T00:00:00Z]"
+// @Param end query string true "Дата окончания периода (в формате RFC3339) [2025-12-31T23:59:59Z]"
+// @Param interval query string false "Интервал: month (по умолчанию), quarter или year"
+// @Param user_id query string false "ID пользователя"
+// @Param service_name query string false "Название сервиса или его алиас из каталога"
+// @Param category query string false "Категория сервиса"
+// @Success 200 {array} models.TimeseriesPoint
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subs/timeseries [get]
+func (h *handlers) GetTimeseries(c *gin.Context) {
+	h.log(c).Info("handlers.GetTimeseries: Building spend timeseries")
+	startDate, endDate, ok := h.parsePeriod(c, "GetTimeseries", "start", "end")
+	if !ok {
+		return
+	}
+
+	points, err := h.scoped(c).GetTimeseries(c.Request.Context(), startDate, endDate, reportFilter(c), c.Query("interval"))
 	if err != nil {
-		h.logger.Errorf("handlers.GetTotalPriceForPeriod: Invalid end_date format: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid end_date format, expected RFC3339"})
+		h.log(c).Errorf("handlers.GetTimeseries: Failed to build timeseries: %v", err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
+		// Проверяем, является ли ошибка ошибкой БД
+		if strings.Contains(err.Error(), "database") {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	total, err := h.service.GetTotalPriceForPeriod(startDate, endDate, userID, serviceName)
+	h.log(c).Infof("handlers.GetTimeseries: Built timeseries with %d points", len(points))
+	c.JSON(http.StatusOK, points)
+}
+
+// GetForecast godoc
+// @Summary Прогноз расходов на подписки
+// @Description Продлевает действующие и бессрочные подписки по их периоду списания с учётом запланированных изменений цены
+// @Description и возвращает прогноз по месяцам вместе с фактической суммой за такое же количество прошедших месяцев
+// @Tags subscriptions
+// @Produce json
+// @Param months query int false "Горизонт прогноза в месяцах (по умолчанию 12, максимум 60)"
+// @Param user_id query string false "ID пользователя"
+// @Param service_name query string false "Название сервиса или его алиас из каталога"
+// @Param category query string false "Категория сервиса"
+// @Success 200 {object} models.Forecast
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subs/forecast [get]
+func (h *handlers) GetForecast(c *gin.Context) {
+	h.log(c).Info("handlers.GetForecast: Forecasting spend")
+	months := 12
+	if monthsStr := c.Query("months"); monthsStr != "" {
+		m, err := strconv.Atoi(monthsStr)
+		if err != nil {
+			h.log(c).Errorf("handlers.GetForecast: Invalid months format: %v", err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid months"})
+			return
+		}
+		months = m
+	}
+
+	forecast, err := h.scoped(c).GetForecast(c.Request.Context(), months, reportFilter(c))
 	if err != nil {
-		h.logger.Errorf("handlers.GetTotalPriceForPeriod: Failed to calculate total price: %v", err)
+		h.log(c).Errorf("handlers.GetForecast: Failed to forecast spend: %v", err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
 		// Проверяем, является ли ошибка ошибкой БД
 		if strings.Contains(err.Error(), "database") {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
@@ -322,6 +551,340 @@ func (h *handlers) GetTotalPriceForPeriod(c *gin.Context) {
 		return
 	}
 
-	h.logger.Infof("handlers.GetTotalPriceForPeriod: Total price calculated: %d", total)
-	c.JSON(http.StatusOK, gin.H{"total": total})
+	h.log(c).Infof("handlers.GetForecast: Projected total for %d months: %d", months, forecast.ProjectedTotal)
+	c.JSON(http.StatusOK, forecast)
+}
+
+// CreatePriceChange godoc
+// @Summary Запланировать изменение цены
+// @Description Планирует новую ежемесячную цену подписки с указанной даты; используется в прогнозе расходов
+// @Tags subscriptions
+// @Accept json
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Param change body models.PriceChange true "Новая цена и дата начала её действия"
+// @Param Idempotency-Key header string false "Ключ идемпотентности: повтор с тем же ключом вернёт первый ответ"
+// @Success 201 {object} models.PriceChange
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subs/{id}/price-changes [post]
+func (h *handlers) CreatePriceChange(c *gin.Context) {
+	h.log(c).Info("handlers.CreatePriceChange: Scheduling price change")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.CreatePriceChange: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	logging.Annotate(c, logrus.Fields{"sub_id": id})
+
+	var change models.PriceChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		h.log(c).Errorf("handlers.CreatePriceChange: Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Убеждаемся, что ID из URL используется, а не из тела запроса
+	change.SubID = uint(id)
+
+	if err := h.scoped(c).CreatePriceChange(c.Request.Context(), &change); err != nil {
+		h.log(c).Errorf("handlers.CreatePriceChange: Failed to schedule price change for subscription %d: %v", id, err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		} else if strings.Contains(err.Error(), "database") {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	h.log(c).Infof("handlers.CreatePriceChange: Price change %d scheduled for subscription %d", change.ID, id)
+	c.JSON(http.StatusCreated, change)
+}
+
+// ListPriceChanges godoc
+// @Summary Запланированные изменения цены
+// @Description Возвращает запланированные изменения цены подписки по возрастанию даты
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Success 200 {array} models.PriceChange
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subs/{id}/price-changes [get]
+func (h *handlers) ListPriceChanges(c *gin.Context) {
+	h.log(c).Info("handlers.ListPriceChanges: Fetching price changes")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.ListPriceChanges: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	logging.Annotate(c, logrus.Fields{"sub_id": id})
+
+	changes, err := h.scoped(c).ListPriceChanges(c.Request.Context(), uint(id))
+	if err != nil {
+		h.log(c).Errorf("handlers.ListPriceChanges: Failed to fetch price changes of subscription %d: %v", id, err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "subscription not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, changes)
+}
+
+// DeletePriceChange godoc
+// @Summary Отменить изменение цены
+// @Description Удаляет запланированное изменение цены подписки
+// @Tags subscriptions
+// @Produce json
+// @Param id path int true "ID подписки"
+// @Param change_id path int true "ID изменения цены"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subs/{id}/price-changes/{change_id} [delete]
+func (h *handlers) DeletePriceChange(c *gin.Context) {
+	h.log(c).Info("handlers.DeletePriceChange: Deleting price change")
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.DeletePriceChange: Invalid ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid id"})
+		return
+	}
+	logging.Annotate(c, logrus.Fields{"sub_id": id})
+	changeID, err := strconv.ParseUint(c.Param("change_id"), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.DeletePriceChange: Invalid change ID format: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid change_id"})
+		return
+	}
+	logging.Annotate(c, logrus.Fields{"price_change_id": changeID})
+
+	if err := h.scoped(c).DeletePriceChange(c.Request.Context(), uint(id), uint(changeID)); err != nil {
+		h.log(c).Warnf("handlers.DeletePriceChange: Failed to delete price change %d of subscription %d: %v", changeID, id, err)
+		if forbidden(c, err) {
+			return
+		}
+		if timeout.Expired(c) {
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "price change not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		}
+		return
+	}
+
+	h.log(c).Infof("handlers.DeletePriceChange: Price change %d of subscription %d deleted", changeID, id)
+	c.Status(http.StatusNoContent)
+}
+
+// StreamEvents godoc
+// @Summary Поток событий подписок (Server-Sent Events)
+// @Description Передаёт события subscription.created, subscription.updated, subscription.deleted и subscription.expired в формате text/event-stream.
+// @Description id каждого события — его порядковый номер; при переподключении клиент передаёт последний полученный номер в заголовке Last-Event-ID
+// @Description (или параметре last_event_id) и получает все пропущенные события. Без него поток начинается с новых событий
+// @Tags subscriptions
+// @Produce text/event-stream
+// @Param user_id query string false "ID пользователя"
+// @Param service_name query string false "Название сервиса или его алиас из каталога"
+// @Param last_event_id query int false "Номер последнего полученного события"
+// @Param Last-Event-ID header int false "Номер последнего полученного события"
+// @Success 200 {object} models.SubEvent
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /subs/events [get]
+func (h *handlers) StreamEvents(c *gin.Context) {
+	h.log(c).Info("handlers.StreamEvents: Opening event stream")
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	// Без Last-Event-ID поток начинается с новых событий
+	var afterSeq *uint64
+	if lastEventID != "" {
+		seq, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			h.log(c).Errorf("handlers.StreamEvents: Invalid Last-Event-ID %q: %v", lastEventID, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		afterSeq = &seq
+	}
+
+	// Права проверяются до отправки заголовков: после начала потока ответить 403 уже нельзя
+	filter := reportFilter(c)
+	if identity, ok := auth.IdentityFrom(c); ok {
+		if _, err := scopeFilter(identity, filter); err != nil {
+			h.log(c).Warnf("handlers.StreamEvents: %s is not allowed to stream events of user %q", identity.Subject, filter.UserID)
+			forbidden(c, err)
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	// Поток живёт дольше WriteTimeout сервера, поэтому срок записи для него снимается
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		h.log(c).Warnf("handlers.StreamEvents: Failed to clear write deadline: %v", err)
+	}
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	// Периодический комментарий не даёт прокси закрыть простаивающее соединение
+	heartbeat := time.NewTicker(eventHeartbeat)
+	defer heartbeat.Stop()
+	var mu sync.Mutex
+	write := func(render func() error) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if err := render(); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	// Горутина пинга завершается до выхода из обработчика: после него писать в c.Writer нельзя
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	done := make(chan struct{})
+	defer func() {
+		cancel()
+		<-done
+	}()
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeat.C:
+				if err := write(func() error {
+					_, err := io.WriteString(c.Writer, ": ping\n\n")
+					return err
+				}); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	err := h.scoped(c).StreamEvents(ctx, filter, afterSeq, func(event models.SubEvent) error {
+		return write(func() error {
+			return sse.Encode(c.Writer, sse.Event{
+				Id:    strconv.FormatUint(event.Seq, 10),
+				Event: event.Type,
+				Data:  event,
+			})
+		})
+	})
+	if err != nil && ctx.Err() == nil {
+		h.log(c).Errorf("handlers.StreamEvents: Event stream failed: %v", err)
+		return
+	}
+	h.log(c).Info("handlers.StreamEvents: Event stream closed")
+}
+
+// log возвращает логгер с полями запроса: request_id, tenant_id, sub_id и т.д.
+func (h *handlers) log(c *gin.Context) *logrus.Entry {
+	return logging.From(c.Request.Context(), h.logger)
+}
+
+// scoped возвращает Service арендатора запроса с правами вызывающего; без аутентификации права не ограничиваются
+func (h *handlers) scoped(c *gin.Context) Service {
+	service := h.service.ForTenant(tenant.From(c))
+	if identity, ok := auth.IdentityFrom(c); ok {
+		return Authorize(service, identity)
+	}
+	return service
+}
+
+// forbidden отвечает 403, если у вызывающего нет прав на операцию
+func forbidden(c *gin.Context, err error) bool {
+	if errors.Is(err, ErrForbidden) {
+		c.JSON(http.StatusForbidden, gin.H{"error": ErrForbidden.Error()})
+		return true
+	}
+	return false
+}
+
+// parsePeriod разбирает обязательные параметры начала и конца периода; при ошибке отвечает 400
+func (h *handlers) parsePeriod(c *gin.Context, method, startParam, endParam string) (time.Time, time.Time, bool) {
+	// Парсим параметрф запроса
+	startDateStr := c.Query(startParam)
+	endDateStr := c.Query(endParam)
+
+	// Проверяем необходмые параметры
+	if startDateStr == "" {
+		h.log(c).Warnf("handlers.%s: %s is required", method, startParam)
+		c.JSON(http.StatusBadRequest, gin.H{"error": startParam + " is required"})
+		return time.Time{}, time.Time{}, false
+	}
+	if endDateStr == "" {
+		h.log(c).Warnf("handlers.%s: %s is required", method, endParam)
+		c.JSON(http.StatusBadRequest, gin.H{"error": endParam + " is required"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	// Парсим даты
+	startDate, err := time.Parse(time.RFC3339, startDateStr)
+	if err != nil {
+		h.log(c).Errorf("handlers.%s: Invalid %s format: %v", method, startParam, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + startParam + " format, expected RFC3339"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	endDate, err := time.Parse(time.RFC3339, endDateStr)
+	if err != nil {
+		h.log(c).Errorf("handlers.%s: Invalid %s format: %v", method, endParam, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + endParam + " format, expected RFC3339"})
+		return time.Time{}, time.Time{}, false
+	}
+
+	return startDate, endDate, true
+}
+
+// reportFilter собирает фильтры отчёта из параметров запроса
+func reportFilter(c *gin.Context) ReportFilter {
+	return ReportFilter{
+		UserID:      c.Query("user_id"),
+		ServiceName: c.Query("service_name"),
+		Category:    c.Query("category"),
+	}
 }
@@ -0,0 +1,253 @@
+package subs_test
+
+import (
+	"app/internal/catalog"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"app/internal/outbox"
+	"app/internal/subs"
+	"app/internal/tenant"
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRouter подключается к новой бд SQLite в памяти и собирает роутер подписок, как в main.go, без аутентификации
+func newRouter(t *testing.T) (*gin.Engine, subs.Repository) {
+	t.Helper()
+	dbtest.SQLite(t)
+	logger := dbtest.Logger()
+	repo := subs.NewRepository(logger)
+	catalogService := catalog.NewService(catalog.NewRepository(logger), logger)
+	service := subs.NewService(repo, catalogService, outbox.NewRepository(logger), outbox.NewChannelPublisher(), logger)
+	handlers := subs.NewHandlers(service, logger)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(tenant.Middleware(logger))
+	router.GET("/subs/timeseries", handlers.GetTimeseries)
+	router.GET("/subs/events", handlers.StreamEvents)
+	return router, repo
+}
+
+func TestGetTimeseries(t *testing.T) {
+	tests := []struct {
+		name       string
+		start, end string
+		interval   string
+		status     int
+		periods    []string
+		points     int
+		error      string
+	}{
+		{
+			name:  "bucket boundaries",
+			start: "2024-01-15T00:00:00Z", end: "2024-03-01T00:00:00Z",
+			status:  http.StatusOK,
+			periods: []string{"2024-01-01", "2024-02-01"},
+		},
+		{
+			name:  "bucket boundaries by quarter",
+			start: "2024-02-15T00:00:00Z", end: "2024-07-01T00:00:01Z", interval: "quarter",
+			status:  http.StatusOK,
+			periods: []string{"2024-01-01", "2024-04-01", "2024-07-01"},
+		},
+		{
+			name:  "empty range",
+			start: "2024-02-10T00:00:00Z", end: "2024-02-10T00:00:00Z",
+			status:  http.StatusOK,
+			periods: []string{},
+		},
+		{
+			name:  "ten years by month",
+			start: "2014-01-01T00:00:00Z", end: "2024-01-01T00:00:00Z",
+			status: http.StatusOK,
+			points: 120,
+		},
+		{
+			name:  "one point over the limit",
+			start: "2014-01-01T00:00:00Z", end: "2024-01-01T00:00:01Z",
+			status: http.StatusBadRequest,
+			error:  "limited to 120 points",
+		},
+		{
+			name:  "long range by year",
+			start: "1990-01-01T00:00:00Z", end: "2024-01-01T00:00:00Z", interval: "year",
+			status: http.StatusOK,
+			points: 34,
+		},
+		{
+			name:  "end before start",
+			start: "2024-03-01T00:00:00Z", end: "2024-01-01T00:00:00Z",
+			status: http.StatusBadRequest,
+			error:  "end_date must be after start_date",
+		},
+		{
+			name:  "invalid interval",
+			start: "2024-01-01T00:00:00Z", end: "2024-03-01T00:00:00Z", interval: "week",
+			status: http.StatusBadRequest,
+			error:  "interval must be one of",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, repo := newRouter(t)
+			sub := models.UserSubs{
+				ServiceName: "Netflix", Price: 100, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+				StartDate: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+			}
+			if err := repo.Create(t.Context(), &sub); err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			query := url.Values{"start": {tt.start}, "end": {tt.end}}
+			if tt.interval != "" {
+				query.Set("interval", tt.interval)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/subs/timeseries?"+query.Encode(), nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d (%s)", w.Code, tt.status, w.Body)
+			}
+			if tt.error != "" {
+				if !strings.Contains(w.Body.String(), tt.error) {
+					t.Errorf("body %s, want an error with %q", w.Body, tt.error)
+				}
+				return
+			}
+
+			var points []models.TimeseriesPoint
+			if err := json.Unmarshal(w.Body.Bytes(), &points); err != nil {
+				t.Fatalf("invalid body %s: %v", w.Body, err)
+			}
+			if points == nil {
+				t.Fatalf("body %s, want a JSON array", w.Body)
+			}
+			if tt.periods == nil {
+				if len(points) != tt.points {
+					t.Errorf("got %d points, want %d", len(points), tt.points)
+				}
+				return
+			}
+			periods := make([]string, 0, len(points))
+			for _, point := range points {
+				periods = append(periods, point.Period.UTC().Format(time.DateOnly))
+			}
+			if strings.Join(periods, ",") != strings.Join(tt.periods, ",") {
+				t.Errorf("periods %v, want %v", periods, tt.periods)
+			}
+		})
+	}
+}
+
+func TestStreamEventsReplaysAfterLastEventID(t *testing.T) {
+	router, repo := newRouter(t)
+	// События арендаторов чередуются в outbox
+	var subIDs []uint
+	for _, tenantID := range []string{"tenant-a", "tenant-b", "tenant-a", "tenant-b", "tenant-a"} {
+		sub := models.UserSubs{
+			ServiceName: "Netflix", Price: 100, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba",
+			StartDate: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		}
+		if err := repo.ForTenant(tenantID).Create(t.Context(), &sub); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		subIDs = append(subIDs, sub.ID)
+	}
+	seqs := publishOutbox(t)
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/subs/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Клиент переподключается, получив первое событие tenant-a
+	req.Header.Set(tenant.Header, "tenant-a")
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(seqs[0], 10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// Дочитываются только более поздние события tenant-a по порядку номеров
+	want := []struct {
+		seq   uint64
+		subID uint
+	}{{seqs[2], subIDs[2]}, {seqs[4], subIDs[4]}}
+	events := readEvents(t, resp, len(want))
+	for i, event := range events {
+		if event.Seq != want[i].seq || event.Data.ID != want[i].subID || event.Data.TenantID != "tenant-a" || event.Type != models.EventSubCreated {
+			t.Errorf("event #%d = %d for subscription %d of %q, want %d for subscription %d of tenant-a",
+				i+1, event.Seq, event.Data.ID, event.Data.TenantID, want[i].seq, want[i].subID)
+		}
+	}
+}
+
+// publishOutbox публикует события outbox так же, как relay: назначает номера по порядку записи
+// и отмечает события отправленными. Возвращает номера в порядке записи
+func publishOutbox(t *testing.T) []uint64 {
+	t.Helper()
+	eventLog := outbox.NewRepository(dbtest.Logger())
+	rows, err := eventLog.ListPending(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seqs := make([]uint64, 0, len(rows))
+	for _, row := range rows {
+		seq, err := eventLog.AssignSeq(row.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := eventLog.MarkSent(row.ID, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		seqs = append(seqs, seq)
+	}
+	return seqs
+}
+
+// readEvents читает из потока Server-Sent Events n событий; id каждого должен совпадать с его номером.
+// Если событий меньше, тест падает по истечении срока запроса
+func readEvents(t *testing.T, resp *http.Response, n int) []models.SubEvent {
+	t.Helper()
+	var events []models.SubEvent
+	var id string
+	scanner := bufio.NewScanner(resp.Body)
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id:"):
+			id = strings.TrimPrefix(line, "id:")
+		case strings.HasPrefix(line, "data:"):
+			var event models.SubEvent
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data:")), &event); err != nil {
+				t.Fatalf("invalid event %s: %v", line, err)
+			}
+			if id != strconv.FormatUint(event.Seq, 10) {
+				t.Errorf("event id %q, want its seq %d", id, event.Seq)
+			}
+			events = append(events, event)
+		}
+	}
+	if len(events) < n {
+		t.Fatalf("stream ended after %d events, want %d: %v", len(events), n, scanner.Err())
+	}
+	return events
+}
@@ -2,13 +2,40 @@ package subs
 
 import (
 	"app/internal/database"
+	"app/internal/logging"
 	"app/internal/models"
+	"app/internal/outbox"
+	"app/internal/tenant"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// NextBillingDate возвращает ближайшую дату списания по подписке после момента after.
+// Списания происходят в день начала подписки с периодичностью period (в коротких месяцах — в последний день месяца);
+// после end_date списаний нет, у бессрочной подписки следующая дата списания есть всегда.
+func NextBillingDate(sub models.UserSubs, period models.BillingPeriod, after time.Time) (time.Time, bool) {
+	step := period.Months()
+	months := 0
+	if after.After(sub.StartDate) {
+		months = calculateMonths(sub.StartDate, after) / step * step
+	}
+	next := addMonthsClamped(sub.StartDate, months)
+	for !next.After(after) {
+		months += step
+		next = addMonthsClamped(sub.StartDate, months)
+	}
+	if sub.EndDate != nil && next.After(*sub.EndDate) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
 // calculateMonths вычисляет количество месяцев между двумя датами
 func calculateMonths(start, end time.Time) int {
 	years := end.Year() - start.Year()
@@ -29,21 +56,53 @@ func calculateMonths(start, end time.Time) int {
 	return totalMonths
 }
 
+// ReportFilter — фильтры отбора подписок для отчётов за период
+type ReportFilter struct {
+	UserID      string
+	ServiceName string
+	Category    string
+}
+
+// Группировки для отчёта GetTotalPriceBreakdown
+const (
+	GroupByCategory = "category"
+	GroupByService  = "service"
+)
+
+// subWithService — подписка вместе с категорией и периодом списания сервиса из каталога
+type subWithService struct {
+	models.UserSubs
+	Category      string
+	BillingPeriod models.BillingPeriod
+}
+
 // Repository — контракт для работы с подписками в бд
 type Repository interface {
-	Create(sub *models.UserSubs) error
-	GetByID(id uint) (*models.UserSubs, error)
-	Update(sub *models.UserSubs) error
-	Delete(id uint) error
-	List() ([]models.UserSubs, error)
-	ListWithPagination(limit, offset int) ([]models.UserSubs, int64, error)
-	GetTotalPriceForPeriod(startDate, endDate time.Time, userID, serviceName string) (uint, error)
+	Create(ctx context.Context, sub *models.UserSubs) error
+	GetByID(ctx context.Context, id uint) (*models.UserSubs, error)
+	Update(ctx context.Context, sub *models.UserSubs) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context) ([]models.UserSubs, error)
+	ListByUserID(ctx context.Context, userID string) ([]models.UserSubs, error)
+	ListWithPagination(ctx context.Context, limit, offset int, userID string) ([]models.UserSubs, int64, error)
+	GetTotalPriceForPeriod(ctx context.Context, startDate, endDate time.Time, filter ReportFilter) (uint, error)
+	GetTotalPriceBreakdown(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, groupBy string) ([]models.TotalByGroup, error)
+	GetTimeseries(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, interval models.BillingPeriod) ([]models.TimeseriesPoint, error)
+	GetForecast(ctx context.Context, from time.Time, months int, filter ReportFilter) ([]models.ForecastPoint, error)
+	CreatePriceChange(ctx context.Context, change *models.PriceChange) error
+	ListPriceChanges(ctx context.Context, subID uint) ([]models.PriceChange, error)
+	ListPriceChangesByUser(ctx context.Context, userID string) (map[uint][]models.PriceChange, error)
+	DeletePriceChange(ctx context.Context, subID, changeID uint) error
+	GetServiceStats(ctx context.Context, at time.Time) ([]models.ServiceStats, error)
+	ForTenant(tenantID string) Repository
 }
 
-// repository — структура, реализующая интерфейса Repository
+// repository — структура, реализующая интерфейса Repository.
+// Пустой tenantID — репозиторий без ограничения арендатором (фоновые задачи)
 type repository struct {
-	db     *gorm.DB
-	logger *logrus.Logger
+	db       *gorm.DB
+	tenantID string
+	logger   *logrus.Logger
 }
 
 // NewRepository — конструктор repository
@@ -54,149 +113,761 @@ func NewRepository(logger *logrus.Logger) Repository {
 	}
 }
 
+// ForTenant возвращает репозиторий, работающий только с данными арендатора tenantID
+func (r *repository) ForTenant(tenantID string) Repository {
+	return &repository{
+		db:       r.db,
+		tenantID: tenantID,
+		logger:   r.logger,
+	}
+}
+
+// log возвращает логгер с полями запроса из ctx
+func (r *repository) log(ctx context.Context) *logrus.Entry {
+	return logging.From(ctx, r.logger)
+}
+
+// run выполняет fn с подключением, ограниченным арендатором репозитория; запросы отменяются вместе с ctx
+func (r *repository) run(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return r.runOn(r.db.WithContext(ctx), fn)
+}
+
+// read выполняет fn как run, но на реплике для чтения, если она настроена: для списков и отчётов,
+// которым допустимо небольшое отставание от основной бд
+func (r *repository) read(ctx context.Context, fn func(db *gorm.DB) error) error {
+	return r.runOn(database.Replica(r.db.WithContext(ctx)), fn)
+}
+
+// runOn выполняет fn с подключением db, ограниченным арендатором репозитория;
+// репозиторий без арендатора (метрики, фоновые задачи) работает со всеми арендаторами
+func (r *repository) runOn(db *gorm.DB, fn func(db *gorm.DB) error) error {
+	if r.tenantID == "" {
+		return tenant.AllTenants(db, fn)
+	}
+	return tenant.Transaction(db, r.tenantID, fn)
+}
+
 // Create создает новую запись models.UserSubs в бд
-func (r *repository) Create(sub *models.UserSubs) error {
-	r.logger.Infof("repositor.Create: Creating subscription for user %s, service %s", sub.UserID, sub.ServiceName)
-	err := r.db.Create(sub).Error
+func (r *repository) Create(ctx context.Context, sub *models.UserSubs) error {
+	r.log(ctx).Infof("repository.Create: Creating subscription for user %s, service %s", sub.UserID, sub.ServiceName)
+	if r.tenantID != "" {
+		sub.TenantID = r.tenantID
+	}
+	// Событие пишется в outbox в той же транзакции, что и сама подписка
+	err := r.run(ctx, func(db *gorm.DB) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(sub).Error; err != nil {
+				return err
+			}
+			return outbox.Write(tx, models.EventSubCreated, *sub)
+		})
+	})
 	if err != nil {
-		r.logger.Fatalf("repositor.Create: Failed to create subscription: %v", err)
+		r.log(ctx).Errorf("repository.Create: Failed to create subscription: %v", err)
 		return err
 	}
-	r.logger.Infof("repositor.Create: Subscription created successfully with ID %d", sub.ID)
+	r.log(ctx).Infof("repository.Create: Subscription created successfully with ID %d", sub.ID)
 	return nil
 }
 
 // GetByID возвращает подписку по ID
-func (r *repository) GetByID(id uint) (*models.UserSubs, error) {
-	r.logger.Infof("repository.GetByID: Fetching subscription with ID %d", id)
+func (r *repository) GetByID(ctx context.Context, id uint) (*models.UserSubs, error) {
+	r.log(ctx).Infof("repository.GetByID: Fetching subscription with ID %d", id)
 	var sub models.UserSubs
-	err := r.db.First(&sub, id).Error
+	err := r.run(ctx, func(db *gorm.DB) error {
+		return db.First(&sub, id).Error
+	})
 	if err != nil {
-		r.logger.Warnf("repository.GetByID: Failed to fetch subscription with ID %d: %v", id, err)
+		r.log(ctx).Warnf("repository.GetByID: Failed to fetch subscription with ID %d: %v", id, err)
 		return nil, err // GORM возвращает gorm.ErrRecordNotFound если запись не найдена
 	}
-	r.logger.Infof("repository.GetByID: Subscription with ID %d fetched successfully", id)
+	r.log(ctx).Infof("repository.GetByID: Subscription with ID %d fetched successfully", id)
 	return &sub, nil
 }
 
 // Update обновляет существующую подписку
-func (r *repository) Update(sub *models.UserSubs) error {
-	r.logger.Infof("repository.Update: Updating subscription with ID %d", sub.ID)
+func (r *repository) Update(ctx context.Context, sub *models.UserSubs) error {
+	r.log(ctx).Infof("repository.Update: Updating subscription with ID %d", sub.ID)
 	// Проверяем, существует ли подписка с таким ID
-	var existingSub models.UserSubs
-	err := r.db.First(&existingSub, sub.ID).Error
-	if err != nil {
-		r.logger.Warnf("repository.Update: Subscription with ID %d not found: %v", sub.ID, err)
-		return gorm.ErrRecordNotFound
-	}
+	err := r.run(ctx, func(db *gorm.DB) error {
+		var existingSub models.UserSubs
+		if err := db.First(&existingSub, sub.ID).Error; err != nil {
+			r.log(ctx).Warnf("repository.Update: Subscription with ID %d not found: %v", sub.ID, err)
+			return gorm.ErrRecordNotFound
+		}
+		// Подписка остаётся у своего арендатора
+		sub.TenantID = existingSub.TenantID
 
-	// Если подписка существует, обновляем её
-	err = r.db.Save(sub).Error
+		// Если подписка существует, обновляем её и пишем событие в outbox в той же транзакции
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(sub).Error; err != nil {
+				return err
+			}
+			return outbox.Write(tx, models.EventSubUpdated, *sub)
+		})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
 	if err != nil {
-		r.logger.Warnf("repository.Update: Failed to update subscription with ID %d: %v", sub.ID, err)
+		r.log(ctx).Warnf("repository.Update: Failed to update subscription with ID %d: %v", sub.ID, err)
 		return err
 	}
-	r.logger.Infof("repository.Update: Subscription with ID %d updated successfully", sub.ID)
+	r.log(ctx).Infof("repository.Update: Subscription with ID %d updated successfully", sub.ID)
 	return nil
 }
 
 // Delete удаляет подписку по ID
-func (r *repository) Delete(id uint) error {
-	r.logger.Infof("repository.Delete: Deleting subscription with ID %d", id)
+func (r *repository) Delete(ctx context.Context, id uint) error {
+	r.log(ctx).Infof("repository.Delete: Deleting subscription with ID %d", id)
 	// Проверяем, существует ли подписка с таким ID
-	var existingSub models.UserSubs
-	err := r.db.First(&existingSub, id).Error
-	if err != nil {
-		r.logger.Warnf("repository.Delete: Subscription with ID %d not found: %v", id, err)
-		return gorm.ErrRecordNotFound
-	}
+	err := r.run(ctx, func(db *gorm.DB) error {
+		var existingSub models.UserSubs
+		if err := db.First(&existingSub, id).Error; err != nil {
+			r.log(ctx).Warnf("repository.Delete: Subscription with ID %d not found: %v", id, err)
+			return gorm.ErrRecordNotFound
+		}
 
-	// Если подписка существует, удаляем её
-	err = r.db.Delete(&models.UserSubs{}, id).Error
+		// Если подписка существует, удаляем её вместе с запланированными изменениями цены
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("sub_id = ?", id).Delete(&models.PriceChange{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.UserSubs{}, id).Error; err != nil {
+				return err
+			}
+			return outbox.Write(tx, models.EventSubDeleted, existingSub)
+		})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
 	if err != nil {
-		r.logger.Errorf("repository.Delete: Failed to delete subscription with ID %d: %v", id, err)
+		r.log(ctx).Errorf("repository.Delete: Failed to delete subscription with ID %d: %v", id, err)
 		return err
 	}
-	r.logger.Infof("repository.Delete: Subscription with ID %d deleted successfully", id)
+	r.log(ctx).Infof("repository.Delete: Subscription with ID %d deleted successfully", id)
 	return nil
 }
 
 // List возвращает список всех подписк
-func (r *repository) List() ([]models.UserSubs, error) {
-	r.logger.Info("repository.List: Fetching list of all subscriptions")
+func (r *repository) List(ctx context.Context) ([]models.UserSubs, error) {
+	r.log(ctx).Info("repository.List: Fetching list of all subscriptions")
+	var subs []models.UserSubs
+	err := r.read(ctx, func(db *gorm.DB) error {
+		return db.Find(&subs).Error
+	})
+	if err != nil {
+		r.log(ctx).Errorf("repository.List: Failed to fetch list of subscriptions: %v", err)
+		return nil, err
+	}
+	r.log(ctx).Infof("repository.List: Fetched %d subscriptions", len(subs))
+	return subs, nil
+}
+
+// ListByUserID возвращает все подписки пользователя
+func (r *repository) ListByUserID(ctx context.Context, userID string) ([]models.UserSubs, error) {
+	r.log(ctx).Infof("repository.ListByUserID: Fetching subscriptions of user %s", userID)
 	var subs []models.UserSubs
-	err := r.db.Find(&subs).Error
+	err := r.read(ctx, func(db *gorm.DB) error {
+		return db.Where("user_id = ?", userID).Order("start_date").Find(&subs).Error
+	})
 	if err != nil {
-		r.logger.Errorf("repository.List: Failed to fetch list of subscriptions: %v", err)
+		r.log(ctx).Errorf("repository.ListByUserID: Failed to fetch subscriptions of user %s: %v", userID, err)
 		return nil, err
 	}
-	r.logger.Infof("repository.List: Fetched %d subscriptions", len(subs))
+	r.log(ctx).Infof("repository.ListByUserID: Fetched %d subscriptions of user %s", len(subs), userID)
 	return subs, nil
 }
 
-// ListWithPagination возвращает список подписок с пагинацией
-func (r *repository) ListWithPagination(limit, offset int) ([]models.UserSubs, int64, error) {
-	r.logger.Infof("repository.ListWithPagination: Fetching list of subscriptions with limit %d and offset %d", limit, offset)
+// ListWithPagination возвращает список подписок с пагинацией; непустой userID оставляет только подписки пользователя
+func (r *repository) ListWithPagination(ctx context.Context, limit, offset int, userID string) ([]models.UserSubs, int64, error) {
+	r.log(ctx).Infof("repository.ListWithPagination: Fetching list of subscriptions with limit %d and offset %d, user: %q", limit, offset, userID)
 	var subs []models.UserSubs
 	var total int64
 
-	// Получаем общее количество записей
-	if err := r.db.Model(&models.UserSubs{}).Count(&total).Error; err != nil {
-		r.logger.Errorf("repository.ListWithPagination: Failed to count subscriptions: %v", err)
-		return nil, 0, err
-	}
+	err := r.read(ctx, func(db *gorm.DB) error {
+		query := db.Model(&models.UserSubs{})
+		if userID != "" {
+			query = query.Where("user_id = ?", userID)
+		}
+		// Новая сессия, чтобы запрос можно было использовать и для подсчёта, и для выборки
+		query = query.Session(&gorm.Session{})
 
-	// Получаем записи с пагинацией
-	err := r.db.Limit(limit).Offset(offset).Find(&subs).Error
+		// Получаем общее количество записей
+		if err := query.Count(&total).Error; err != nil {
+			r.log(ctx).Errorf("repository.ListWithPagination: Failed to count subscriptions: %v", err)
+			return err
+		}
+
+		// Получаем записи с пагинацией
+		return query.Order("id").Limit(limit).Offset(offset).Find(&subs).Error
+	})
 	if err != nil {
-		r.logger.Errorf("repository.ListWithPagination: Failed to fetch list of subscriptions: %v", err)
+		r.log(ctx).Errorf("repository.ListWithPagination: Failed to fetch list of subscriptions: %v", err)
 		return nil, 0, err
 	}
 
-	r.logger.Infof("repository.ListWithPagination: Fetched %d subscriptions (limit %d, offset %d)", len(subs), limit, offset)
+	r.log(ctx).Infof("repository.ListWithPagination: Fetched %d subscriptions (limit %d, offset %d)", len(subs), limit, offset)
 	return subs, total, nil
 }
 
 // GetTotalPriceForPeriod подсчитывает суммарную стоимость подписок за период
-func (r *repository) GetTotalPriceForPeriod(startDate, endDate time.Time, userID, serviceName string) (uint, error) {
-	r.logger.Infof("repository.GetTotalPriceForPeriod: Calculating total price for period %s to %s, userID: %s, serviceName: %s", startDate, endDate, userID, serviceName)
-	query := r.db.Model(&models.UserSubs{}).
-		Where("start_date <= ? AND end_date >= ?", endDate, startDate) // колизии дат
+func (r *repository) GetTotalPriceForPeriod(ctx context.Context, startDate, endDate time.Time, filter ReportFilter) (uint, error) {
+	r.log(ctx).Infof("repository.GetTotalPriceForPeriod: Calculating total price for period %s to %s, filter: %+v", startDate, endDate, filter)
+	subs, err := r.findForPeriod(ctx, startDate, endDate, filter)
+	if err != nil {
+		r.log(ctx).Errorf("repository.GetTotalPriceForPeriod: Failed to fetch subscriptions: %v", err)
+		return 0, err
+	}
+	changes, err := r.priceChangesOf(ctx, subs)
+	if err != nil {
+		r.log(ctx).Errorf("repository.GetTotalPriceForPeriod: Failed to fetch price changes: %v", err)
+		return 0, err
+	}
 
-	// Проверка полей
-	if userID != "" {
-		query = query.Where("user_id = ?", userID)
+	// Подсчитываем сумму с учетом количества месяцев и цены на дату списания
+	var total uint
+	for _, sub := range subs {
+		total += priceForPeriod(sub, changes[sub.ID], startDate, endDate)
+	}
+
+	r.log(ctx).Infof("repository.GetTotalPriceForPeriod: Total price calculated: %d", total)
+	return total, nil
+}
+
+// GetTotalPriceBreakdown подсчитывает стоимость подписок за период в разрезе категорий или сервисов
+func (r *repository) GetTotalPriceBreakdown(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, groupBy string) ([]models.TotalByGroup, error) {
+	r.log(ctx).Infof("repository.GetTotalPriceBreakdown: Calculating totals by %s for period %s to %s, filter: %+v", groupBy, startDate, endDate, filter)
+	subs, err := r.findForPeriod(ctx, startDate, endDate, filter)
+	if err != nil {
+		r.log(ctx).Errorf("repository.GetTotalPriceBreakdown: Failed to fetch subscriptions: %v", err)
+		return nil, err
+	}
+	changes, err := r.priceChangesOf(ctx, subs)
+	if err != nil {
+		r.log(ctx).Errorf("repository.GetTotalPriceBreakdown: Failed to fetch price changes: %v", err)
+		return nil, err
 	}
-	if serviceName != "" {
-		query = query.Where("service_name = ?", serviceName)
+
+	totals := make(map[string]uint)
+	for _, sub := range subs {
+		group := sub.Category
+		if groupBy == GroupByService {
+			group = sub.ServiceName
+		}
+		totals[group] += priceForPeriod(sub, changes[sub.ID], startDate, endDate)
 	}
 
-	// Получаем все подписки, которые пересекаются с заданным периодом
-	var subs []models.UserSubs
-	if err := query.Find(&subs).Error; err != nil {
-		r.logger.Errorf("repository.GetTotalPriceForPeriod: Failed to fetch subscriptions: %v", err)
-		return 0, err
+	result := make([]models.TotalByGroup, 0, len(totals))
+	for group, total := range totals {
+		result = append(result, models.TotalByGroup{Group: group, Total: total})
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Total != result[j].Total {
+			return result[i].Total > result[j].Total
+		}
+		return result[i].Group < result[j].Group
+	})
 
-	// Подсчитываем сумму с учетом количества месяцев
-	var total uint
+	r.log(ctx).Infof("repository.GetTotalPriceBreakdown: Calculated totals for %d groups", len(result))
+	return result, nil
+}
+
+// timeseriesQuery строит временной ряд одним запросом: интервалы генерируются через generate_series
+// (интервал, пересекающийся с периодом лишь границей, в ряд не входит),
+// а списания по каждой подписке — ежемесячно с даты начала, пока оплаченный месяц не выходит за end_date,
+// что совпадает с подсчётом месяцев в GetTotalPriceForPeriod. Месяц оплачивается по цене на дату списания
+// периода оплаты сервиса, в который он попадает, как в GetTotalPriceForPeriod и GetForecast
+const timeseriesQuery = `
+WITH buckets AS (
+	SELECT * FROM (
+		SELECT b AS period,
+			GREATEST(b, @start) AS window_start,
+			LEAST(b + CAST(@step AS interval), @end) AS window_end
+		FROM generate_series(date_trunc(@trunc, CAST(@start AS timestamptz)), CAST(@end AS timestamptz), CAST(@step AS interval)) AS b
+	) AS w
+	WHERE w.window_start < w.window_end
+),
+active AS (
+	SELECT s.id, s.price, s.start_date, s.end_date,
+		CASE COALESCE(sv.billing_period, 'month') WHEN 'quarter' THEN 3 WHEN 'year' THEN 12 ELSE 1 END AS cycle
+	FROM user_subs s
+	LEFT JOIN services sv ON sv.id = s.service_id
+	WHERE s.start_date < @end AND (s.end_date IS NULL OR s.end_date >= @start) %s
+),
+charges AS (
+	SELECT s.start_date + n * interval '1 month' AS charged_at,
+		COALESCE((
+			SELECT pc.price FROM price_changes pc
+			WHERE pc.sub_id = s.id AND pc.effective_from <= s.start_date + (n / s.cycle * s.cycle) * interval '1 month'
+			ORDER BY pc.effective_from DESC LIMIT 1
+		), s.price) AS price
+	FROM active s
+	CROSS JOIN LATERAL generate_series(0, CAST(
+		EXTRACT(YEAR FROM age(COALESCE(s.end_date, @end), s.start_date)) * 12 +
+		EXTRACT(MONTH FROM age(COALESCE(s.end_date, @end), s.start_date)) AS integer)) AS n
+	WHERE s.start_date + n * interval '1 month' >= @start
+		AND s.start_date + n * interval '1 month' <= COALESCE(s.end_date, @end) - interval '1 month'
+)
+SELECT b.period,
+	COALESCE((
+		SELECT SUM(c.price) FROM charges c
+		WHERE c.charged_at >= b.window_start AND c.charged_at < b.window_end
+	), 0) AS total,
+	(
+		SELECT COUNT(*) FROM active s
+		WHERE s.start_date < b.window_end AND (s.end_date IS NULL OR s.end_date >= b.window_start)
+	) AS active_count
+FROM buckets b
+ORDER BY b.period`
+
+// GetTimeseries возвращает стоимость и количество активных подписок по интервалам периода
+func (r *repository) GetTimeseries(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, interval models.BillingPeriod) ([]models.TimeseriesPoint, error) {
+	r.log(ctx).Infof("repository.GetTimeseries: Building %s timeseries for period %s to %s, filter: %+v", interval, startDate, endDate, filter)
+	// В SQLite нет generate_series и date_trunc, поэтому ряд строится в Go по тем же правилам, что и timeseriesQuery
+	if r.db.Dialector.Name() == database.DriverSQLite {
+		subs, err := r.findForPeriod(ctx, startDate, endDate, filter)
+		if err != nil {
+			r.log(ctx).Errorf("repository.GetTimeseries: Failed to fetch subscriptions: %v", err)
+			return nil, err
+		}
+		changes, err := r.priceChangesOf(ctx, subs)
+		if err != nil {
+			r.log(ctx).Errorf("repository.GetTimeseries: Failed to fetch price changes: %v", err)
+			return nil, err
+		}
+		points, err := timeseriesOf(ctx, subs, changes, startDate, endDate, interval)
+		if err != nil {
+			r.log(ctx).Errorf("repository.GetTimeseries: Failed to build timeseries: %v", err)
+			return nil, err
+		}
+		r.log(ctx).Infof("repository.GetTimeseries: Built timeseries with %d points", len(points))
+		return points, nil
+	}
+
+	args := map[string]any{
+		"start": startDate,
+		"end":   endDate,
+		"trunc": string(interval),
+		"step":  fmt.Sprintf("%d months", interval.Months()),
+	}
+
+	// Фильтры сужают подписки, а не интервалы, чтобы интервалы без подписок тоже попали в ряд
+	var conditions string
+	if filter.UserID != "" {
+		conditions += " AND s.user_id = @user_id"
+		args["user_id"] = filter.UserID
+	}
+	if filter.ServiceName != "" {
+		conditions += " AND s.service_name = @service_name"
+		args["service_name"] = filter.ServiceName
+	}
+	if filter.Category != "" {
+		conditions += " AND s.service_id IN (SELECT id FROM services WHERE category = @category)"
+		args["category"] = filter.Category
+	}
+	// Scope арендатора к сырому SQL не применяется, поэтому условие добавляется явно
+	if r.tenantID != "" {
+		conditions += " AND s.tenant_id = @tenant_id"
+		args["tenant_id"] = r.tenantID
+	}
+
+	points := []models.TimeseriesPoint{}
+	err := r.read(ctx, func(db *gorm.DB) error {
+		return db.Raw(fmt.Sprintf(timeseriesQuery, conditions), args).Scan(&points).Error
+	})
+	if err != nil {
+		r.log(ctx).Errorf("repository.GetTimeseries: Failed to build timeseries: %v", err)
+		return nil, err
+	}
+
+	r.log(ctx).Infof("repository.GetTimeseries: Built timeseries with %d points", len(points))
+	return points, nil
+}
+
+// timeseriesOf строит ряд по подпискам, пересекающимся с периодом, так же, как timeseriesQuery в Postgres:
+// интервалы отсчитываются от начала месяца, квартала или года в UTC и входят в ряд, только если
+// пересекаются с периодом не одной лишь границей, а каждое ежемесячное списание
+// до end_date (для бессрочной — до конца периода) учитывается в интервале, в который попадает,
+// по цене на дату списания периода оплаты. Каждая подписка обходится один раз: её списания
+// раскладываются по интервалам двоичным поиском, а активность отмечается на отрезке интервалов
+func timeseriesOf(ctx context.Context, subs []subWithService, changes map[uint][]models.PriceChange, startDate, endDate time.Time, interval models.BillingPeriod) ([]models.TimeseriesPoint, error) {
+	step := interval.Months()
+	points := []models.TimeseriesPoint{}
+	var windowStarts, windowEnds []time.Time
+	for period := timeseriesStart(startDate, interval); period.Before(endDate); period = period.AddDate(0, step, 0) {
+		windowStart := period
+		if startDate.After(windowStart) {
+			windowStart = startDate
+		}
+		windowEnd := period.AddDate(0, step, 0)
+		if endDate.Before(windowEnd) {
+			windowEnd = endDate
+		}
+		if !windowStart.Before(windowEnd) {
+			continue
+		}
+		points = append(points, models.TimeseriesPoint{Period: period})
+		windowStarts = append(windowStarts, windowStart)
+		windowEnds = append(windowEnds, windowEnd)
+	}
+
+	// active[i] — изменение количества активных подписок на интервале i относительно интервала i-1
+	active := make([]int, len(points)+1)
 	for _, sub := range subs {
-		// Определяем период пересечения
-		actualStart := sub.StartDate
-		if startDate.After(actualStart) {
-			actualStart = startDate
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// Подписка активна в интервалах, окно которых заканчивается после её начала и начинается не позже её окончания
+		from := sort.Search(len(points), func(i int) bool { return windowEnds[i].After(sub.StartDate) })
+		to := len(points)
+		if sub.EndDate != nil {
+			to = sort.Search(len(points), func(i int) bool { return windowStarts[i].After(*sub.EndDate) })
+		}
+		if from < to {
+			active[from]++
+			active[to]--
+		}
+
+		last := endDate
+		if sub.EndDate != nil {
+			last = *sub.EndDate
+		}
+		last = addMonthsClamped(last, -1)
+		cycle := sub.BillingPeriod.Months()
+		// Списания до начала периода пропускаются; calculateMonths может насчитать на месяц больше из-за коротких месяцев
+		i := max(calculateMonths(sub.StartDate, startDate)-1, 0)
+		for ; ; i++ {
+			charge := addMonthsClamped(sub.StartDate, i)
+			if charge.After(last) || !charge.Before(endDate) {
+				break
+			}
+			if charge.Before(startDate) {
+				continue
+			}
+			idx := sort.Search(len(points), func(j int) bool { return windowEnds[j].After(charge) })
+			if idx < len(points) && !charge.Before(windowStarts[idx]) {
+				points[idx].Total += priceAt(sub.UserSubs, changes[sub.ID], addMonthsClamped(sub.StartDate, i/cycle*cycle))
+			}
 		}
+	}
+
+	var count int
+	for i := range points {
+		count += active[i]
+		points[i].ActiveCount = count
+	}
+	return points, nil
+}
+
+// timeseriesStart возвращает начало интервала временного ряда, в который попадает startDate:
+// начало месяца, квартала или года в UTC
+func timeseriesStart(startDate time.Time, interval models.BillingPeriod) time.Time {
+	start := startDate.UTC()
+	return time.Date(start.Year(), start.Month()-time.Month((int(start.Month())-1)%interval.Months()), 1, 0, 0, 0, 0, time.UTC)
+}
 
-		actualEnd := sub.EndDate
-		if endDate.Before(actualEnd) {
-			actualEnd = endDate
+// timeseriesLen возвращает количество точек временного ряда GetTimeseries за период
+func timeseriesLen(startDate, endDate time.Time, interval models.BillingPeriod) int {
+	if !startDate.Before(endDate) {
+		return 0
+	}
+	first, end := timeseriesStart(startDate, interval), endDate.UTC()
+	months := (end.Year()-first.Year())*12 + int(end.Month()) - int(first.Month())
+	n := months/interval.Months() + 1
+	// Интервал, который начинается ровно в конце периода, в ряд не входит
+	if !first.AddDate(0, (n-1)*interval.Months(), 0).Before(end) {
+		n--
+	}
+	return n
+}
+
+// addMonthsClamped прибавляет месяцы как interval в Postgres и как списания по подписке: день, которого нет
+// в целевом месяце, заменяется последним днём месяца (31 января + 1 месяц = 28 или 29 февраля)
+func addMonthsClamped(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	target := time.Date(year, month+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	if last := time.Date(target.Year(), target.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day(); day > last {
+		day = last
+	}
+	return time.Date(target.Year(), target.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// GetForecast прогнозирует списания по месяцам, начиная с месяца, в который попадает from.
+// Действующие и бессрочные подписки продлеваются по их периоду списания с учётом
+// запланированных изменений цены; учитываются только списания не раньше from.
+func (r *repository) GetForecast(ctx context.Context, from time.Time, months int, filter ReportFilter) ([]models.ForecastPoint, error) {
+	r.log(ctx).Infof("repository.GetForecast: Forecasting %d months from %s, filter: %+v", months, from, filter)
+	firstMonth := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	horizon := firstMonth.AddDate(0, months, 0)
+
+	subs, err := r.findForPeriod(ctx, from, horizon, filter)
+	if err != nil {
+		r.log(ctx).Errorf("repository.GetForecast: Failed to fetch subscriptions: %v", err)
+		return nil, err
+	}
+	changes, err := r.priceChangesOf(ctx, subs)
+	if err != nil {
+		r.log(ctx).Errorf("repository.GetForecast: Failed to fetch price changes: %v", err)
+		return nil, err
+	}
+
+	points := make([]models.ForecastPoint, months)
+	for i := range points {
+		points[i].Period = firstMonth.AddDate(0, i, 0)
+	}
+
+	for _, sub := range subs {
+		step := sub.BillingPeriod.Months()
+		offset := 0
+		if from.After(sub.StartDate) {
+			offset = calculateMonths(sub.StartDate, from) / step * step
 		}
+		for ; ; offset += step {
+			// Смещение отсчитывается от даты начала, чтобы подписка с 31-го после февраля снова списывалась 31-го
+			charge := addMonthsClamped(sub.StartDate, offset)
+			if charge.Before(from) {
+				continue
+			}
+			if !charge.Before(horizon) {
+				break
+			}
+			// Списание оплачивает step месяцев, но не дольше end_date
+			paid := step
+			if sub.EndDate != nil {
+				paid = min(paid, calculateMonths(charge, *sub.EndDate))
+			}
+			if paid <= 0 {
+				break
+			}
+			// Месяц списания определяется в часовом поясе from: даты из бд могут прийти в другом поясе
+			local := charge.In(firstMonth.Location())
+			idx := (local.Year()-firstMonth.Year())*12 + int(local.Month()) - int(firstMonth.Month())
+			if idx < 0 || idx >= months {
+				continue
+			}
+			points[idx].Total += uint(paid) * priceAt(sub.UserSubs, changes[sub.ID], charge)
+		}
+	}
+
+	r.log(ctx).Infof("repository.GetForecast: Forecasted %d months for %d subscriptions", months, len(subs))
+	return points, nil
+}
 
-		// Рассчитываем количество месяцев
-		months := calculateMonths(actualStart, actualEnd)
-		total += uint(months) * uint(sub.Price)
+// CreatePriceChange сохраняет запланированное изменение цены подписки
+func (r *repository) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	r.log(ctx).Infof("repository.CreatePriceChange: Scheduling price %d for subscription %d from %s", change.Price, change.SubID, change.EffectiveFrom)
+	if r.tenantID != "" {
+		change.TenantID = r.tenantID
+	}
+	err := r.run(ctx, func(db *gorm.DB) error {
+		return db.Create(change).Error
+	})
+	if err != nil {
+		r.log(ctx).Errorf("repository.CreatePriceChange: Failed to schedule price change: %v", err)
+		return err
 	}
+	return nil
+}
 
-	r.logger.Infof("repository.GetTotalPriceForPeriod: Total price calculated: %d", total)
-	return total, nil
+// ListPriceChanges возвращает запланированные изменения цены подписки по возрастанию даты
+func (r *repository) ListPriceChanges(ctx context.Context, subID uint) ([]models.PriceChange, error) {
+	var changes []models.PriceChange
+	err := r.run(ctx, func(db *gorm.DB) error {
+		return db.Where("sub_id = ?", subID).Order("effective_from").Find(&changes).Error
+	})
+	if err != nil {
+		r.log(ctx).Errorf("repository.ListPriceChanges: Failed to fetch price changes of subscription %d: %v", subID, err)
+		return nil, err
+	}
+	return changes, nil
+}
+
+// ListPriceChangesByUser возвращает изменения цены всех подписок пользователя одним запросом,
+// сгруппированные по ID подписки и отсортированные по возрастанию даты
+func (r *repository) ListPriceChangesByUser(ctx context.Context, userID string) (map[uint][]models.PriceChange, error) {
+	var changes []models.PriceChange
+	err := r.read(ctx, func(db *gorm.DB) error {
+		return db.Where("sub_id IN (SELECT id FROM user_subs WHERE user_id = ?)", userID).Order("effective_from").Find(&changes).Error
+	})
+	if err != nil {
+		r.log(ctx).Errorf("repository.ListPriceChangesByUser: Failed to fetch price changes of user %s: %v", userID, err)
+		return nil, err
+	}
+	result := make(map[uint][]models.PriceChange)
+	for _, change := range changes {
+		result[change.SubID] = append(result[change.SubID], change)
+	}
+	return result, nil
+}
+
+// DeletePriceChange удаляет запланированное изменение цены подписки
+func (r *repository) DeletePriceChange(ctx context.Context, subID, changeID uint) error {
+	r.log(ctx).Infof("repository.DeletePriceChange: Deleting price change %d of subscription %d", changeID, subID)
+	var deleted int64
+	err := r.run(ctx, func(db *gorm.DB) error {
+		res := db.Where("sub_id = ?", subID).Delete(&models.PriceChange{}, changeID)
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		r.log(ctx).Errorf("repository.DeletePriceChange: Failed to delete price change %d: %v", changeID, err)
+		return err
+	}
+	if deleted == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// serviceStatsQuery считает активные на момент @at подписки и их ежемесячную стоимость
+// по арендаторам и сервисам; цена берётся с учётом последнего вступившего в силу изменения
+const serviceStatsQuery = `
+SELECT s.tenant_id, s.service_name,
+	COUNT(*) AS active_subs,
+	COALESCE(SUM(COALESCE((
+		SELECT pc.price FROM price_changes pc
+		WHERE pc.sub_id = s.id AND pc.effective_from <= @at
+		ORDER BY pc.effective_from DESC LIMIT 1
+	), s.price)), 0) AS monthly_revenue
+FROM user_subs s
+WHERE s.start_date <= @at AND (s.end_date IS NULL OR s.end_date >= @at) %s
+GROUP BY s.tenant_id, s.service_name
+ORDER BY s.tenant_id, s.service_name`
+
+// GetServiceStats возвращает количество активных подписок и их текущую ежемесячную стоимость по сервисам
+func (r *repository) GetServiceStats(ctx context.Context, at time.Time) ([]models.ServiceStats, error) {
+	args := map[string]any{"at": at}
+	var conditions string
+	if r.tenantID != "" {
+		conditions = " AND s.tenant_id = @tenant_id"
+		args["tenant_id"] = r.tenantID
+	}
+
+	var stats []models.ServiceStats
+	err := r.read(ctx, func(db *gorm.DB) error {
+		return db.Raw(fmt.Sprintf(serviceStatsQuery, conditions), args).Scan(&stats).Error
+	})
+	if err != nil {
+		r.log(ctx).Errorf("repository.GetServiceStats: Failed to calculate service stats: %v", err)
+		return nil, err
+	}
+	return stats, nil
+}
+
+// priceChangesOf возвращает изменения цены подписок, сгруппированные по ID подписки
+func (r *repository) priceChangesOf(ctx context.Context, subs []subWithService) (map[uint][]models.PriceChange, error) {
+	ids := make([]uint, 0, len(subs))
+	for _, sub := range subs {
+		ids = append(ids, sub.ID)
+	}
+	result := make(map[uint][]models.PriceChange)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var changes []models.PriceChange
+	err := r.read(ctx, func(db *gorm.DB) error {
+		return db.Where("sub_id IN ?", ids).Order("effective_from").Find(&changes).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		result[change.SubID] = append(result[change.SubID], change)
+	}
+	return result, nil
+}
+
+// priceAt возвращает ежемесячную цену подписки на дату с учётом изменений, отсортированных по дате
+func priceAt(sub models.UserSubs, changes []models.PriceChange, at time.Time) uint {
+	return uint(sub.PricedAt(changes, at).Price)
+}
+
+// findForPeriod возвращает подписки, пересекающиеся с периодом, вместе с категорией сервиса
+func (r *repository) findForPeriod(ctx context.Context, startDate, endDate time.Time, filter ReportFilter) ([]subWithService, error) {
+	var subs []subWithService
+	err := r.read(ctx, func(db *gorm.DB) error {
+		return forPeriodQuery(db, startDate, endDate, filter).Find(&subs).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+// forPeriodQuery строит запрос подписок, пересекающихся с периодом, с категорией и периодом списания сервиса
+func forPeriodQuery(db *gorm.DB, startDate, endDate time.Time, filter ReportFilter) *gorm.DB {
+	query := db.Table("user_subs").
+		Select("user_subs.*, COALESCE(services.category, '') AS category, COALESCE(services.billing_period, 'month') AS billing_period").
+		Joins("LEFT JOIN services ON services.id = user_subs.service_id").
+		Where("user_subs.start_date <= ? AND (user_subs.end_date IS NULL OR user_subs.end_date >= ?)", endDate, startDate) // колизии дат
+
+	// Проверка полей
+	if filter.UserID != "" {
+		query = query.Where("user_subs.user_id = ?", filter.UserID)
+	}
+	if filter.ServiceName != "" {
+		query = query.Where("user_subs.service_name = ?", filter.ServiceName)
+	}
+	if filter.Category != "" {
+		query = query.Where("services.category = ?", filter.Category)
+	}
+	return query
+}
+
+// PriceForPeriod рассчитывает стоимость подписки с периодом списания period за ту часть периода,
+// в которую она действовала, так же, как GetTotalPriceForPeriod; changes отсортированы по дате
+func PriceForPeriod(sub models.UserSubs, period models.BillingPeriod, changes []models.PriceChange, startDate, endDate time.Time) uint {
+	return priceForPeriod(subWithService{UserSubs: sub, BillingPeriod: period}, changes, startDate, endDate)
+}
+
+// MonthlyPrice возвращает стоимость месяца подписки, в который попадает at, — ту же, что этот месяц
+// добавляет в PriceForPeriod: цену на дату списания периода period, которым он оплачен
+func MonthlyPrice(sub models.UserSubs, period models.BillingPeriod, changes []models.PriceChange, at time.Time) uint {
+	return monthlyPrice(subWithService{UserSubs: sub, BillingPeriod: period}, changes, at)
+}
+
+// priceForPeriod рассчитывает стоимость подписки за ту часть периода, в которую она действовала.
+// Каждый полный месяц пересечения стоит столько, сколько стоил месяц в списании, которым он оплачен
+func priceForPeriod(sub subWithService, changes []models.PriceChange, startDate, endDate time.Time) uint {
+	// Определяем период пересечения
+	actualStart := sub.StartDate
+	if startDate.After(actualStart) {
+		actualStart = startDate
+	}
+
+	// Бессрочная подписка действует до конца периода
+	actualEnd := endDate
+	if sub.EndDate != nil && sub.EndDate.Before(actualEnd) {
+		actualEnd = *sub.EndDate
+	}
+
+	// Рассчитываем количество месяцев
+	months := calculateMonths(actualStart, actualEnd)
+	var total uint
+	for i := 0; i < months; i++ {
+		total += monthlyPrice(sub, changes, addMonthsClamped(actualStart, i))
+	}
+	return total
+}
+
+// monthlyPrice возвращает стоимость месяца подписки, в который попадает at: цену в списании, которым он оплачен
+func monthlyPrice(sub subWithService, changes []models.PriceChange, at time.Time) uint {
+	return priceAt(sub.UserSubs, changes, chargeOf(sub, at))
+}
+
+// chargeOf возвращает дату списания, которым оплачен момент at: последнее списание по периоду сервиса не позже at
+func chargeOf(sub subWithService, at time.Time) time.Time {
+	step := sub.BillingPeriod.Months()
+	months := 0
+	if at.After(sub.StartDate) {
+		months = calculateMonths(sub.StartDate, at) / step * step
+	}
+	// calculateMonths не учитывает короткие месяцы: с 31 января до 28 февраля он насчитывает 0 месяцев
+	for !addMonthsClamped(sub.StartDate, months+step).After(at) {
+		months += step
+	}
+	return addMonthsClamped(sub.StartDate, months)
 }
@@ -1,151 +1,501 @@
 package subs
 
 import (
+	"app/internal/catalog"
+	"app/internal/logging"
 	"app/internal/models"
+	"app/internal/outbox"
+	"context"
 	"errors"
-	"log"
+	"fmt"
 	"time"
 
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
 // Service — контракт для работы с service
 type Service interface {
-	CreateSub(sub *models.UserSubs) error
-	GetSubByID(id uint) (*models.UserSubs, error)
-	UpdateSub(sub *models.UserSubs) error
-	DeleteSub(id uint) error
-	ListSubs() ([]models.UserSubs, error)
-	ListSubsWithPagination(limit, offset int) ([]models.UserSubs, int64, error)
-	GetTotalPriceForPeriod(startDate, endDate time.Time, userID, serviceName string) (uint, error)
+	CreateSub(ctx context.Context, sub *models.UserSubs) error
+	GetSubByID(ctx context.Context, id uint) (*models.UserSubs, error)
+	UpdateSub(ctx context.Context, sub *models.UserSubs) error
+	DeleteSub(ctx context.Context, id uint) error
+	ListSubs(ctx context.Context) ([]models.UserSubs, error)
+	ListSubsByUser(ctx context.Context, userID string) ([]models.UserSubs, error)
+	ListSubsWithPagination(ctx context.Context, limit, offset int, userID string) ([]models.UserSubs, int64, error)
+	GetTotalPriceForPeriod(ctx context.Context, startDate, endDate time.Time, filter ReportFilter) (uint, error)
+	GetTotalPriceBreakdown(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, groupBy string) ([]models.TotalByGroup, error)
+	GetTimeseries(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, interval string) ([]models.TimeseriesPoint, error)
+	GetForecast(ctx context.Context, months int, filter ReportFilter) (*models.Forecast, error)
+	CreatePriceChange(ctx context.Context, change *models.PriceChange) error
+	ListPriceChanges(ctx context.Context, subID uint) ([]models.PriceChange, error)
+	ListPriceChangesByUser(ctx context.Context, userID string) (map[uint][]models.PriceChange, error)
+	DeletePriceChange(ctx context.Context, subID, changeID uint) error
+	StreamEvents(ctx context.Context, filter ReportFilter, afterSeq *uint64, send func(models.SubEvent) error) error
+	ForTenant(tenantID string) Service
 }
 
+// maxForecastMonths — максимальный горизонт прогноза расходов
+const maxForecastMonths = 60
+
+// maxTimeseriesPoints — максимальное количество точек временного ряда: десять лет по месяцам
+const maxTimeseriesPoints = 120
+
+// Параметры потока событий: размер буфера подписчика и пачки при дочитывании из outbox
+const (
+	eventBuffer      = 64
+	eventReplayBatch = 500
+)
+
 // service  — структура, реализующая интерфейс Service
 type service struct {
-	repo   Repository
-	logger *logrus.Logger
+	repo     Repository
+	catalog  catalog.Service
+	eventLog outbox.Repository
+	events   outbox.ChannelPublisher
+	tenantID string
+	logger   *logrus.Logger
 }
 
 // NewService — конструктор servoce
-func NewService(repo Repository, logger *logrus.Logger) Service {
+func NewService(repo Repository, catalog catalog.Service, eventLog outbox.Repository, events outbox.ChannelPublisher, logger *logrus.Logger) Service {
+	return &service{
+		repo:     repo,
+		catalog:  catalog,
+		eventLog: eventLog,
+		events:   events,
+		logger:   logger,
+	}
+}
+
+// ForTenant возвращает Service, работающий только с подписками арендатора tenantID
+func (s *service) ForTenant(tenantID string) Service {
 	return &service{
-		repo:   repo,
-		logger: logger,
+		repo:     s.repo.ForTenant(tenantID),
+		catalog:  s.catalog,
+		eventLog: s.eventLog,
+		events:   s.events,
+		tenantID: tenantID,
+		logger:   s.logger,
 	}
 }
 
+// log возвращает логгер с полями запроса из ctx
+func (s *service) log(ctx context.Context) *logrus.Entry {
+	return logging.From(ctx, s.logger)
+}
+
 // CreateSub создает новую подписку с валидацией
-func (s *service) CreateSub(sub *models.UserSubs) error {
-	s.logger.Infof("service.CreateSub: Creating subscription")
+func (s *service) CreateSub(ctx context.Context, sub *models.UserSubs) error {
+	s.log(ctx).Infof("service.CreateSub: Creating subscription")
 	// Валидация обязательных полей
 	if sub.ID != 0 {
-		s.logger.Warnf("service.CreateSub: ID should not be provided when creating a subscription")
+		s.log(ctx).Warnf("service.CreateSub: ID should not be provided when creating a subscription")
 		return errors.New("ID should not be provided when creating a subscription")
 	}
-	if sub.ServiceName == "" {
-		s.logger.Warnf("service.CreateSub: service_name is required")
-		return errors.New("service_name is required")
-	}
-	if sub.Price <= 0 {
-		s.logger.Warnf("service.CreateSub: price must be greater than 0")
-		return errors.New("price must be greater than 0")
+	if sub.ServiceID == nil && sub.ServiceName == "" {
+		s.log(ctx).Warnf("service.CreateSub: service_id or service_name is required")
+		return errors.New("service_id or service_name is required")
 	}
 	if sub.UserID == "" {
-		s.logger.Warnf("service.CreateSub: user_id is required")
+		s.log(ctx).Warnf("service.CreateSub: user_id is required")
 		return errors.New("user_id is required")
 	}
 	if sub.StartDate.IsZero() {
-		s.logger.Warnf("service.CreateSub: start_date is required")
+		s.log(ctx).Warnf("service.CreateSub: start_date is required")
 		return errors.New("start_date is required")
 	}
-	if sub.EndDate.IsZero() {
-		s.logger.Warnf("service.CreateSub: end_date is required")
-		return errors.New("end_date is required")
-	}
-	if sub.EndDate.Before(sub.StartDate) {
-		s.logger.Warnf("service.CreateSub: end_date must be after start_date")
+	// Подписка без end_date считается бессрочной
+	if sub.EndDate != nil && sub.EndDate.Before(sub.StartDate) {
+		s.log(ctx).Warnf("service.CreateSub: end_date must be after start_date")
 		return errors.New("end_date must be after start_date")
 	}
+	if err := s.resolveService(ctx, sub); err != nil {
+		s.log(ctx).Warnf("service.CreateSub: Failed to resolve service: %v", err)
+		return err
+	}
+	if sub.Price <= 0 {
+		s.log(ctx).Warnf("service.CreateSub: price must be greater than 0")
+		return errors.New("price must be greater than 0")
+	}
 
-	return s.repo.Create(sub)
+	return s.repo.Create(ctx, sub)
 }
 
 // GetSubByID возвращает подписк по ID
-func (s *service) GetSubByID(id uint) (*models.UserSubs, error) {
-	log.Printf("service.GetSubByID: Fetching subscription with ID %d", id)
-	return s.repo.GetByID(id)
+func (s *service) GetSubByID(ctx context.Context, id uint) (*models.UserSubs, error) {
+	s.log(ctx).Infof("service.GetSubByID: Fetching subscription with ID %d", id)
+	return s.repo.GetByID(ctx, id)
 }
 
 // UpdateSub обновляет существующую подписку с валидацией
-func (s *service) UpdateSub(sub *models.UserSubs) error {
-	log.Printf("service.UpdateSub: Updating subscription with ID %d", sub.ID)
+func (s *service) UpdateSub(ctx context.Context, sub *models.UserSubs) error {
+	s.log(ctx).Infof("service.UpdateSub: Updating subscription with ID %d", sub.ID)
 	// Валидация обязательных полей
 	if sub.ID == 0 {
-		s.logger.Warnf("service.UpdateSub: id is required for update")
+		s.log(ctx).Warnf("service.UpdateSub: id is required for update")
 		return errors.New("id is required for update")
 	}
-	if sub.ServiceName == "" {
-		s.logger.Warnf("service.UpdateSub: service_name is required")
-		return errors.New("service_name is required")
-	}
-	if sub.Price <= 0 {
-		s.logger.Warnf("service.UpdateSub: price must be greater than 0")
-		return errors.New("price must be greater than 0")
+	if sub.ServiceID == nil && sub.ServiceName == "" {
+		s.log(ctx).Warnf("service.UpdateSub: service_id or service_name is required")
+		return errors.New("service_id or service_name is required")
 	}
 	if sub.UserID == "" {
-		s.logger.Warnf("service.UpdateSub: user_id is required")
+		s.log(ctx).Warnf("service.UpdateSub: user_id is required")
 		return errors.New("user_id is required")
 	}
 	if sub.StartDate.IsZero() {
-		s.logger.Warnf("service.UpdateSub: start_date is required")
+		s.log(ctx).Warnf("service.UpdateSub: start_date is required")
 		return errors.New("start_date is required")
 	}
-	if sub.EndDate.IsZero() {
-		s.logger.Warnf("service.UpdateSub: end_date is required")
-		return errors.New("end_date is required")
-	}
-	if sub.EndDate.Before(sub.StartDate) {
-		s.logger.Warnf("service.UpdateSub: end_date must be after start_date")
+	// Подписка без end_date считается бессрочной
+	if sub.EndDate != nil && sub.EndDate.Before(sub.StartDate) {
+		s.log(ctx).Warnf("service.UpdateSub: end_date must be after start_date")
 		return errors.New("end_date must be after start_date")
 	}
+	if err := s.resolveService(ctx, sub); err != nil {
+		s.log(ctx).Warnf("service.UpdateSub: Failed to resolve service: %v", err)
+		return err
+	}
+	if sub.Price <= 0 {
+		s.log(ctx).Warnf("service.UpdateSub: price must be greater than 0")
+		return errors.New("price must be greater than 0")
+	}
 
-	return s.repo.Update(sub)
+	return s.repo.Update(ctx, sub)
 }
 
 // DeleteSub удаляет подписку по ID
-func (s *service) DeleteSub(id uint) error {
-	s.logger.Infof("service.DeleteSub: Deleting subscription with ID %d", id)
-	return s.repo.Delete(id)
+func (s *service) DeleteSub(ctx context.Context, id uint) error {
+	s.log(ctx).Infof("service.DeleteSub: Deleting subscription with ID %d", id)
+	return s.repo.Delete(ctx, id)
 }
 
 // ListSubs возвращает список всех подписок
-func (s *service) ListSubs() ([]models.UserSubs, error) {
-	s.logger.Infof("service.ListSubs: Fetching list of all subscriptions")
-	return s.repo.List()
+func (s *service) ListSubs(ctx context.Context) ([]models.UserSubs, error) {
+	s.log(ctx).Infof("service.ListSubs: Fetching list of all subscriptions")
+	return s.repo.List(ctx)
+}
+
+// ListSubsByUser возвращает все подписки пользователя
+func (s *service) ListSubsByUser(ctx context.Context, userID string) ([]models.UserSubs, error) {
+	s.log(ctx).Infof("service.ListSubsByUser: Fetching subscriptions of user %s", userID)
+	if userID == "" {
+		s.log(ctx).Warnf("service.ListSubsByUser: user_id is required")
+		return nil, errors.New("user_id is required")
+	}
+	return s.repo.ListByUserID(ctx, userID)
 }
 
-// ListSubsWithPagination возвращает список подписок с пагинацией
-func (s *service) ListSubsWithPagination(limit, offset int) ([]models.UserSubs, int64, error) {
-	s.logger.Infof("service.ListSubsWithPagination: Fetching list of subscriptions with limit %d and offset %d", limit, offset)
-	return s.repo.ListWithPagination(limit, offset)
+// ListSubsWithPagination возвращает список подписок с пагинацией; непустой userID оставляет только подписки пользователя
+func (s *service) ListSubsWithPagination(ctx context.Context, limit, offset int, userID string) ([]models.UserSubs, int64, error) {
+	s.log(ctx).Infof("service.ListSubsWithPagination: Fetching list of subscriptions with limit %d and offset %d, user: %q", limit, offset, userID)
+	return s.repo.ListWithPagination(ctx, limit, offset, userID)
 }
 
 // GetTotalPriceForPeriod подсчитывает суммарную стоимостт подписок за период
-func (s *service) GetTotalPriceForPeriod(startDate, endDate time.Time, userID, serviceName string) (uint, error) {
-	s.logger.Infof("service.GetTotalPriceForPeriod: Calculating total price for period %s to %s, userID: %s, serviceName: %s", startDate, endDate, userID, serviceName)
-	// Валидация обязательных полей
+func (s *service) GetTotalPriceForPeriod(ctx context.Context, startDate, endDate time.Time, filter ReportFilter) (uint, error) {
+	s.log(ctx).Infof("service.GetTotalPriceForPeriod: Calculating total price for period %s to %s, filter: %+v", startDate, endDate, filter)
+	if err := s.validatePeriod(ctx, "GetTotalPriceForPeriod", startDate, endDate); err != nil {
+		return 0, err
+	}
+	filter, err := s.normalizeFilter(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.repo.GetTotalPriceForPeriod(ctx, startDate, endDate, filter)
+}
+
+// GetTotalPriceBreakdown подсчитывает стоимость подписок за период в разрезе категорий или сервисов
+func (s *service) GetTotalPriceBreakdown(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, groupBy string) ([]models.TotalByGroup, error) {
+	s.log(ctx).Infof("service.GetTotalPriceBreakdown: Calculating totals by %s for period %s to %s, filter: %+v", groupBy, startDate, endDate, filter)
+	if groupBy == "" {
+		groupBy = GroupByCategory
+	}
+	if groupBy != GroupByCategory && groupBy != GroupByService {
+		s.log(ctx).Warnf("service.GetTotalPriceBreakdown: invalid group_by %q", groupBy)
+		return nil, errors.New("group_by must be one of: category, service")
+	}
+	if err := s.validatePeriod(ctx, "GetTotalPriceBreakdown", startDate, endDate); err != nil {
+		return nil, err
+	}
+	filter, err := s.normalizeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetTotalPriceBreakdown(ctx, startDate, endDate, filter, groupBy)
+}
+
+// GetTimeseries возвращает стоимость и количество активных подписок по месяцам, кварталам или годам
+func (s *service) GetTimeseries(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, interval string) ([]models.TimeseriesPoint, error) {
+	s.log(ctx).Infof("service.GetTimeseries: Building %s timeseries for period %s to %s, filter: %+v", interval, startDate, endDate, filter)
+	period := models.BillingPeriod(interval)
+	if period == "" {
+		period = models.BillingMonth
+	}
+	if !period.Valid() {
+		s.log(ctx).Warnf("service.GetTimeseries: invalid interval %q", interval)
+		return nil, errors.New("interval must be one of: month, quarter, year")
+	}
+	if err := s.validatePeriod(ctx, "GetTimeseries", startDate, endDate); err != nil {
+		return nil, err
+	}
+	if n := timeseriesLen(startDate, endDate, period); n > maxTimeseriesPoints {
+		s.log(ctx).Warnf("service.GetTimeseries: %d points requested, limit is %d", n, maxTimeseriesPoints)
+		return nil, fmt.Errorf("timeseries is limited to %d points, use a longer interval or a shorter period", maxTimeseriesPoints)
+	}
+	filter, err := s.normalizeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repo.GetTimeseries(ctx, startDate, endDate, filter, period)
+}
+
+// GetForecast прогнозирует расходы на months месяцев вперёд и возвращает рядом
+// фактическую сумму за такое же количество прошедших месяцев
+func (s *service) GetForecast(ctx context.Context, months int, filter ReportFilter) (*models.Forecast, error) {
+	s.log(ctx).Infof("service.GetForecast: Forecasting %d months, filter: %+v", months, filter)
+	if months <= 0 || months > maxForecastMonths {
+		s.log(ctx).Warnf("service.GetForecast: invalid months %d", months)
+		return nil, fmt.Errorf("months must be between 1 and %d", maxForecastMonths)
+	}
+	filter, err := s.normalizeFilter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	points, err := s.repo.GetForecast(ctx, now, months, filter)
+	if err != nil {
+		return nil, err
+	}
+	historical, err := s.repo.GetTotalPriceForPeriod(ctx, now.AddDate(0, -months, 0), now, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	forecast := &models.Forecast{
+		From:            now,
+		To:              points[len(points)-1].Period.AddDate(0, 1, 0),
+		Months:          points,
+		HistoricalTotal: historical,
+	}
+	for _, point := range points {
+		forecast.ProjectedTotal += point.Total
+	}
+	return forecast, nil
+}
+
+// CreatePriceChange планирует изменение цены подписки с указанной даты
+func (s *service) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	s.log(ctx).Infof("service.CreatePriceChange: Scheduling price change for subscription %d", change.SubID)
+	if change.ID != 0 {
+		s.log(ctx).Warnf("service.CreatePriceChange: ID should not be provided when creating a price change")
+		return errors.New("ID should not be provided when creating a price change")
+	}
+	if change.Price <= 0 {
+		s.log(ctx).Warnf("service.CreatePriceChange: price must be greater than 0")
+		return errors.New("price must be greater than 0")
+	}
+	if change.EffectiveFrom.IsZero() {
+		s.log(ctx).Warnf("service.CreatePriceChange: effective_from is required")
+		return errors.New("effective_from is required")
+	}
+
+	sub, err := s.repo.GetByID(ctx, change.SubID)
+	if err != nil {
+		return err
+	}
+	if change.EffectiveFrom.Before(sub.StartDate) {
+		s.log(ctx).Warnf("service.CreatePriceChange: effective_from must be after start_date of the subscription")
+		return errors.New("effective_from must be after start_date of the subscription")
+	}
+	return s.repo.CreatePriceChange(ctx, change)
+}
+
+// ListPriceChanges возвращает запланированные изменения цены подписки
+func (s *service) ListPriceChanges(ctx context.Context, subID uint) ([]models.PriceChange, error) {
+	s.log(ctx).Infof("service.ListPriceChanges: Fetching price changes of subscription %d", subID)
+	if _, err := s.repo.GetByID(ctx, subID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPriceChanges(ctx, subID)
+}
+
+// ListPriceChangesByUser возвращает изменения цены всех подписок пользователя по ID подписки
+func (s *service) ListPriceChangesByUser(ctx context.Context, userID string) (map[uint][]models.PriceChange, error) {
+	s.log(ctx).Infof("service.ListPriceChangesByUser: Fetching price changes of user %s", userID)
+	return s.repo.ListPriceChangesByUser(ctx, userID)
+}
+
+// DeletePriceChange отменяет запланированное изменение цены подписки
+func (s *service) DeletePriceChange(ctx context.Context, subID, changeID uint) error {
+	s.log(ctx).Infof("service.DeletePriceChange: Deleting price change %d of subscription %d", changeID, subID)
+	return s.repo.DeletePriceChange(ctx, subID, changeID)
+}
+
+// StreamEvents передаёт в send события подписок с порядковым номером больше afterSeq
+// (без afterSeq — только новые события), подходящие под фильтр, пока не отменён ctx или send не вернёт ошибку.
+// Сначала дочитываются уже опубликованные события из outbox, затем поток продолжается в реальном времени.
+// Если подписчик не успевает разбирать события и отключается публикатором, пропущенное дочитывается из outbox.
+// События в реальном времени приходят от публикатора этого процесса, поэтому поток рассчитан на один экземпляр сервиса.
+func (s *service) StreamEvents(ctx context.Context, filter ReportFilter, from *uint64, send func(models.SubEvent) error) error {
+	filter, err := s.normalizeFilter(ctx, filter)
+	if err != nil {
+		return err
+	}
+	var afterSeq uint64
+	if from != nil {
+		afterSeq = *from
+	} else if afterSeq, err = s.eventLog.LatestSeq(ctx); err != nil {
+		return fmt.Errorf("database error: %w", err)
+	}
+	s.log(ctx).Infof("service.StreamEvents: Streaming events after %d, filter: %+v", afterSeq, filter)
+
+	deliver := func(event models.SubEvent) error {
+		afterSeq = event.Seq
+		if s.tenantID != "" && event.Data.TenantID != s.tenantID {
+			return nil
+		}
+		if !matchesFilter(event.Data, filter) {
+			return nil
+		}
+		return send(event)
+	}
+
+	for {
+		// Подписываемся до дочитывания, чтобы не потерять события, опубликованные в промежутке
+		live, unsubscribe := s.events.Subscribe(eventBuffer)
+		if err := s.replayEvents(ctx, &afterSeq, deliver); err != nil {
+			unsubscribe()
+			return err
+		}
+
+		dropped, err := s.streamLive(ctx, live, &afterSeq, deliver)
+		unsubscribe()
+		if !dropped {
+			return err
+		}
+		s.log(ctx).Warnf("service.StreamEvents: Subscriber fell behind at event %d, replaying from outbox", afterSeq)
+	}
+}
+
+// replayEvents передаёт в deliver опубликованные события из outbox после *afterSeq
+func (s *service) replayEvents(ctx context.Context, afterSeq *uint64, deliver func(models.SubEvent) error) error {
+	for {
+		rows, err := s.eventLog.ListSentAfter(ctx, *afterSeq, eventReplayBatch, s.tenantID)
+		if err != nil {
+			return fmt.Errorf("database error: %w", err)
+		}
+		for _, row := range rows {
+			event, err := outbox.Decode(row)
+			if err != nil {
+				s.log(ctx).Errorf("service.replayEvents: %v", err)
+				*afterSeq = *row.Seq
+				continue
+			}
+			if err := deliver(event); err != nil {
+				return err
+			}
+		}
+		if len(rows) < eventReplayBatch {
+			return nil
+		}
+	}
+}
+
+// streamLive передаёт в deliver события из канала подписчика; dropped — канал закрыт публикатором
+func (s *service) streamLive(ctx context.Context, live <-chan models.SubEvent, afterSeq *uint64, deliver func(models.SubEvent) error) (dropped bool, err error) {
+	for {
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case event, ok := <-live:
+			if !ok {
+				return true, nil
+			}
+			// Событие уже передано при дочитывании из outbox
+			if event.Seq <= *afterSeq {
+				continue
+			}
+			if err := deliver(event); err != nil {
+				return false, err
+			}
+		}
+	}
+}
+
+// matchesFilter проверяет, подходит ли подписка под фильтры user_id и service_name
+func matchesFilter(sub models.UserSubs, filter ReportFilter) bool {
+	if filter.UserID != "" && sub.UserID != filter.UserID {
+		return false
+	}
+	if filter.ServiceName != "" && catalog.Normalize(sub.ServiceName) != catalog.Normalize(filter.ServiceName) {
+		return false
+	}
+	return true
+}
+
+// validatePeriod проверяет границы периода отчёта
+func (s *service) validatePeriod(ctx context.Context, method string, startDate, endDate time.Time) error {
 	if startDate.IsZero() {
-		s.logger.Warnf("service.GetTotalPriceForPeriod: start_date is required")
-		return 0, errors.New("start_date is required")
+		s.log(ctx).Warnf("service.%s: start_date is required", method)
+		return errors.New("start_date is required")
 	}
 	if endDate.IsZero() {
-		s.logger.Warnf("service.GetTotalPriceForPeriod: end_date is required")
-		return 0, errors.New("end_date is required")
+		s.log(ctx).Warnf("service.%s: end_date is required", method)
+		return errors.New("end_date is required")
 	}
 	if endDate.Before(startDate) {
-		s.logger.Warnf("service.GetTotalPriceForPeriod: end_date must be after start_date")
-		return 0, errors.New("end_date must be after start_date")
+		s.log(ctx).Warnf("service.%s: end_date must be after start_date", method)
+		return errors.New("end_date must be after start_date")
 	}
+	return nil
+}
 
-	return s.repo.GetTotalPriceForPeriod(startDate, endDate, userID, serviceName)
+// normalizeFilter приводит название сервиса к каноническому, чтобы алиасы и другое
+// написание учитывались в фильтре, а категорию — к виду из справочника
+func (s *service) normalizeFilter(ctx context.Context, filter ReportFilter) (ReportFilter, error) {
+	if filter.ServiceName != "" {
+		svc, err := s.catalog.FindService(ctx, filter.ServiceName)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return filter, err
+		}
+		if svc != nil {
+			filter.ServiceName = svc.Name
+		}
+	}
+	filter.Category = catalog.Normalize(filter.Category)
+	return filter, nil
+}
+
+// resolveService привязывает подписку к сервису каталога по service_id или по названию.
+// Сервиса не в каталоге — catalog.ErrUnknownService, цена по умолчанию берётся из каталога.
+func (s *service) resolveService(ctx context.Context, sub *models.UserSubs) error {
+	var svc *models.Service
+	var err error
+	if sub.ServiceID != nil {
+		svc, err = s.catalog.GetServiceByID(ctx, *sub.ServiceID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: service_id %d is not in the catalogue", catalog.ErrUnknownService, *sub.ServiceID)
+		}
+	} else {
+		svc, err = s.catalog.FindService(ctx, sub.ServiceName)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %q is not in the catalogue", catalog.ErrUnknownService, sub.ServiceName)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	sub.ServiceID = &svc.ID
+	sub.ServiceName = svc.Name
+	if sub.Price == 0 {
+		sub.Price = svc.DefaultPrice
+	}
+	return nil
 }
@@ -0,0 +1,105 @@
+package substest
+
+import (
+	"app/internal/models"
+	"app/internal/subs"
+	"context"
+	"testing"
+	"time"
+)
+
+// forecastCase — сценарий прогноза списаний GetForecast по месяцам
+type forecastCase struct {
+	name    string
+	subs    []models.UserSubs
+	pricing pricing
+	from    time.Time
+	months  int
+	want    []uint
+}
+
+// forecastCases — сценарии GetForecast. Подписка стоит 100 в месяц и списывается ежемесячно;
+// дни в конце месяца проверяются на невисокосном 2023 годе. Сценарии с изменением цены и периодом списания
+// повторены в totalCases: сумма прогноза на 2024 год должна совпадать со стоимостью подписки за 2024 год
+func forecastCases() []forecastCase {
+	one := func(start time.Time, end *time.Time) []models.UserSubs {
+		return []models.UserSubs{sub("Netflix", start, end)}
+	}
+	// Часовой пояс западнее UTC: начало месяца в нём наступает позже, чем в UTC
+	west := time.FixedZone("UTC-5", -5*3600)
+
+	return []forecastCase{
+		{name: "open-ended", subs: one(month(2023, time.January), nil), from: month(2023, time.January), months: 4, want: []uint{100, 100, 100, 100}},
+		{name: "starts on the 29th", subs: one(day(2023, time.January, 29), nil), from: month(2023, time.January), months: 4, want: []uint{100, 100, 100, 100}},
+		{name: "starts on the 30th", subs: one(day(2023, time.January, 30), nil), from: month(2023, time.January), months: 4, want: []uint{100, 100, 100, 100}},
+		{name: "starts on the 31st", subs: one(day(2023, time.January, 31), nil), from: month(2023, time.January), months: 6, want: []uint{100, 100, 100, 100, 100, 100}},
+		{name: "starts on the 31st, ends on the 31st", subs: one(day(2023, time.January, 31), ptr(day(2023, time.March, 31))), from: month(2023, time.January), months: 4, want: []uint{100, 100, 0, 0}},
+		{name: "charges before from are skipped", subs: one(month(2023, time.January), nil), from: day(2023, time.January, 15), months: 4, want: []uint{0, 100, 100, 100}},
+		{name: "starts after horizon", subs: one(month(2023, time.June), nil), from: month(2023, time.January), months: 3, want: []uint{0, 0, 0}},
+		{
+			name:   "charge on horizon in UTC is inside horizon west of UTC",
+			subs:   one(month(2023, time.April), nil),
+			from:   time.Date(2023, time.January, 1, 0, 0, 0, 0, west),
+			months: 3,
+			want:   []uint{0, 0, 100},
+		},
+		{
+			name:    "price change mid-range applies from the next charge",
+			subs:    one(month(2024, time.January), nil),
+			pricing: pricing{changes: []models.PriceChange{{Price: 200, EffectiveFrom: day(2024, time.July, 10)}}},
+			from:    month(2024, time.January), months: 12,
+			want: []uint{100, 100, 100, 100, 100, 100, 100, 200, 200, 200, 200, 200},
+		},
+		{
+			name:    "quarterly",
+			subs:    one(month(2024, time.January), nil),
+			pricing: pricing{period: models.BillingQuarter},
+			from:    month(2024, time.January), months: 12,
+			want: []uint{300, 0, 0, 300, 0, 0, 300, 0, 0, 300, 0, 0},
+		},
+		{
+			name: "quarterly, price change inside a quarter",
+			subs: one(month(2024, time.January), nil),
+			pricing: pricing{
+				period:  models.BillingQuarter,
+				changes: []models.PriceChange{{Price: 200, EffectiveFrom: month(2024, time.May)}},
+			},
+			from: month(2024, time.January), months: 12,
+			want: []uint{300, 0, 0, 300, 0, 0, 600, 0, 0, 600, 0, 0},
+		},
+		{
+			name: "yearly, price changes between charges",
+			subs: one(month(2023, time.January), nil),
+			pricing: pricing{
+				period: models.BillingYear,
+				changes: []models.PriceChange{
+					{Price: 200, EffectiveFrom: month(2023, time.June)},
+					{Price: 300, EffectiveFrom: month(2024, time.June)},
+				},
+			},
+			from: month(2024, time.January), months: 12,
+			want: []uint{2400, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		},
+	}
+}
+
+func testForecast(t *testing.T, newRepo NewRepository) {
+	for _, tt := range forecastCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			createPriced(t, repo, tt.pricing, tt.subs...)
+			points, err := repo.GetForecast(context.Background(), tt.from, tt.months, subs.ReportFilter{})
+			if err != nil {
+				t.Fatalf("GetForecast(%s, %d): %v", tt.from, tt.months, err)
+			}
+			if len(points) != len(tt.want) {
+				t.Fatalf("GetForecast(%s, %d) returned %d months, want %d", tt.from, tt.months, len(points), len(tt.want))
+			}
+			for i, point := range points {
+				if point.Total != tt.want[i] {
+					t.Errorf("GetForecast(%s, %d): month %s total %d, want %d", tt.from, tt.months, point.Period.Format("2006-01"), point.Total, tt.want[i])
+				}
+			}
+		})
+	}
+}
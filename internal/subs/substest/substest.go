@@ -0,0 +1,440 @@
+package substest
+
+import (
+	"app/internal/database"
+	"app/internal/models"
+	"app/internal/subs"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// NewRepository возвращает репозиторий над пустым хранилищем; вызывается перед каждой проверкой
+type NewRepository func(t *testing.T) subs.Repository
+
+// Пользователи тестовых подписок
+const (
+	userA = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+	userB = "0b7ac2d4-4a5e-4c0e-9a8e-3f1f2b6d9e11"
+)
+
+// Run проверяет, что реализация subs.Repository ведёт себя так же, как репозиторий на Postgres:
+// CRUD, ошибки для отсутствующих записей, пагинацию, изоляцию арендаторов, стоимость подписок за период, временной ряд и прогноз
+func Run(t *testing.T, newRepo NewRepository) {
+	t.Run("CRUD", func(t *testing.T) { testCRUD(t, newRepo(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, newRepo(t)) })
+	t.Run("ListByUserID", func(t *testing.T) { testListByUserID(t, newRepo(t)) })
+	t.Run("Pagination", func(t *testing.T) { testPagination(t, newRepo(t)) })
+	t.Run("PriceChanges", func(t *testing.T) { testPriceChanges(t, newRepo(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, newRepo(t)) })
+	t.Run("TotalPriceForPeriod", func(t *testing.T) { testTotalPriceForPeriod(t, newRepo) })
+	t.Run("Forecast", func(t *testing.T) { testForecast(t, newRepo) })
+	t.Run("Timeseries", func(t *testing.T) { testTimeseries(t, newRepo) })
+}
+
+// month возвращает начало месяца в UTC
+func month(year int, m time.Month) time.Time {
+	return time.Date(year, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// day возвращает начало дня в UTC
+func day(year int, m time.Month, d int) time.Time {
+	return time.Date(year, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// ptr возвращает указатель на t
+func ptr(t time.Time) *time.Time {
+	return &t
+}
+
+// sub возвращает подписку пользователя userA на сервис name стоимостью 100 в месяц
+func sub(name string, start time.Time, end *time.Time) models.UserSubs {
+	return models.UserSubs{ServiceName: name, Price: 100, UserID: userA, StartDate: start, EndDate: end}
+}
+
+// create сохраняет подписки и возвращает их с присвоенными ID
+func create(t *testing.T, repo subs.Repository, items ...models.UserSubs) []models.UserSubs {
+	t.Helper()
+	for i := range items {
+		if err := repo.Create(context.Background(), &items[i]); err != nil {
+			t.Fatalf("Create(%s): %v", items[i].ServiceName, err)
+		}
+	}
+	return items
+}
+
+// pricing — период списания сервиса подписок сценария и изменения цены первой из них
+type pricing struct {
+	period  models.BillingPeriod
+	changes []models.PriceChange
+}
+
+// createPriced сохраняет подписки как create; с заданным периодом списания они оформляются на сервис каталога
+// с этим периодом, а изменения цены планируются для первой подписки
+func createPriced(t *testing.T, repo subs.Repository, p pricing, items ...models.UserSubs) []models.UserSubs {
+	t.Helper()
+	if p.period != "" {
+		service := models.Service{Name: "Billed", NormalizedName: "billed", BillingPeriod: p.period}
+		if err := database.Get().Create(&service).Error; err != nil {
+			t.Fatalf("failed to create a service billed every %s: %v", p.period, err)
+		}
+		for i := range items {
+			items[i].ServiceID = &service.ID
+		}
+	}
+	items = create(t, repo, items...)
+	for _, change := range p.changes {
+		change.SubID = items[0].ID
+		if err := repo.CreatePriceChange(context.Background(), &change); err != nil {
+			t.Fatalf("CreatePriceChange: %v", err)
+		}
+	}
+	return items
+}
+
+// assertSub сравнивает сохранённую подписку с ожидаемой; время сравнивается как момент, без учёта часового пояса
+func assertSub(t *testing.T, got *models.UserSubs, want models.UserSubs) {
+	t.Helper()
+	if got.ID != want.ID || got.ServiceName != want.ServiceName || got.Price != want.Price || got.UserID != want.UserID {
+		t.Errorf("got subscription %d %q %d %s, want %d %q %d %s",
+			got.ID, got.ServiceName, got.Price, got.UserID, want.ID, want.ServiceName, want.Price, want.UserID)
+	}
+	if !got.StartDate.Equal(want.StartDate) {
+		t.Errorf("subscription %d: start_date %s, want %s", want.ID, got.StartDate, want.StartDate)
+	}
+	switch {
+	case (got.EndDate == nil) != (want.EndDate == nil):
+		t.Errorf("subscription %d: end_date %v, want %v", want.ID, got.EndDate, want.EndDate)
+	case got.EndDate != nil && !got.EndDate.Equal(*want.EndDate):
+		t.Errorf("subscription %d: end_date %s, want %s", want.ID, *got.EndDate, *want.EndDate)
+	}
+}
+
+// ids возвращает ID подписок по порядку
+func ids(items []models.UserSubs) []uint {
+	result := make([]uint, 0, len(items))
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+	return result
+}
+
+// equalIDs сравнивает списки ID с учётом порядка
+func equalIDs(got, want []uint) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func testCRUD(t *testing.T, repo subs.Repository) {
+	ctx := context.Background()
+	created := create(t, repo,
+		sub("Netflix", month(2024, time.January), ptr(month(2024, time.June))),
+		sub("Spotify", month(2024, time.March), nil),
+	)
+	for _, item := range created {
+		if item.ID == 0 {
+			t.Fatalf("Create(%s) did not assign an ID", item.ServiceName)
+		}
+		if item.TenantID != "default" {
+			t.Errorf("Create(%s): tenant %q, want default", item.ServiceName, item.TenantID)
+		}
+	}
+	if created[0].ID == created[1].ID {
+		t.Fatalf("Create assigned the same ID %d twice", created[0].ID)
+	}
+
+	for _, want := range created {
+		got, err := repo.GetByID(ctx, want.ID)
+		if err != nil {
+			t.Fatalf("GetByID(%d): %v", want.ID, err)
+		}
+		assertSub(t, got, want)
+	}
+
+	updated := created[1]
+	updated.Price = 300
+	updated.EndDate = ptr(month(2025, time.March))
+	if err := repo.Update(ctx, &updated); err != nil {
+		t.Fatalf("Update(%d): %v", updated.ID, err)
+	}
+	got, err := repo.GetByID(ctx, updated.ID)
+	if err != nil {
+		t.Fatalf("GetByID(%d) after Update: %v", updated.ID, err)
+	}
+	assertSub(t, got, updated)
+
+	// Обновление может снять дату окончания: подписка становится бессрочной
+	updated.EndDate = nil
+	if err := repo.Update(ctx, &updated); err != nil {
+		t.Fatalf("Update(%d) to open-ended: %v", updated.ID, err)
+	}
+	if got, err = repo.GetByID(ctx, updated.ID); err != nil {
+		t.Fatalf("GetByID(%d) after Update: %v", updated.ID, err)
+	}
+	assertSub(t, got, updated)
+
+	list, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("List returned %d subscriptions, want 2", len(list))
+	}
+
+	if err := repo.Delete(ctx, created[0].ID); err != nil {
+		t.Fatalf("Delete(%d): %v", created[0].ID, err)
+	}
+	if _, err := repo.GetByID(ctx, created[0].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetByID(%d) after Delete: got %v, want gorm.ErrRecordNotFound", created[0].ID, err)
+	}
+	if list, err = repo.List(ctx); err != nil {
+		t.Fatalf("List after Delete: %v", err)
+	}
+	if !equalIDs(ids(list), []uint{created[1].ID}) {
+		t.Errorf("List after Delete returned %v, want [%d]", ids(list), created[1].ID)
+	}
+}
+
+func testNotFound(t *testing.T, repo subs.Repository) {
+	ctx := context.Background()
+	existing := create(t, repo, sub("Netflix", month(2024, time.January), nil))[0]
+	missing := existing.ID + 1000
+
+	if _, err := repo.GetByID(ctx, missing); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetByID(%d): got %v, want gorm.ErrRecordNotFound", missing, err)
+	}
+
+	update := sub("Spotify", month(2024, time.January), nil)
+	update.ID = missing
+	if err := repo.Update(ctx, &update); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update(%d): got %v, want gorm.ErrRecordNotFound", missing, err)
+	}
+	if _, err := repo.GetByID(ctx, missing); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update(%d) of a missing subscription created it: GetByID returned %v", missing, err)
+	}
+
+	if err := repo.Delete(ctx, missing); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Delete(%d): got %v, want gorm.ErrRecordNotFound", missing, err)
+	}
+	if err := repo.DeletePriceChange(ctx, existing.ID, 1000); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("DeletePriceChange(%d, 1000): got %v, want gorm.ErrRecordNotFound", existing.ID, err)
+	}
+
+	// Повторное удаление уже удалённой подписки — тоже not found
+	if err := repo.Delete(ctx, existing.ID); err != nil {
+		t.Fatalf("Delete(%d): %v", existing.ID, err)
+	}
+	if err := repo.Delete(ctx, existing.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("second Delete(%d): got %v, want gorm.ErrRecordNotFound", existing.ID, err)
+	}
+
+	list, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List of an empty store: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("List of an empty store returned %d subscriptions", len(list))
+	}
+	page, total, err := repo.ListWithPagination(ctx, 10, 0, "")
+	if err != nil {
+		t.Fatalf("ListWithPagination of an empty store: %v", err)
+	}
+	if len(page) != 0 || total != 0 {
+		t.Errorf("ListWithPagination of an empty store returned %d subscriptions, total %d", len(page), total)
+	}
+}
+
+func testListByUserID(t *testing.T, repo subs.Repository) {
+	ctx := context.Background()
+	items := []models.UserSubs{
+		sub("Netflix", month(2024, time.May), nil),
+		sub("Spotify", month(2023, time.February), nil),
+		sub("YouTube", month(2024, time.January), nil),
+	}
+	items[2].UserID = userB
+	created := create(t, repo, items...)
+
+	list, err := repo.ListByUserID(ctx, userA)
+	if err != nil {
+		t.Fatalf("ListByUserID: %v", err)
+	}
+	// Подписки пользователя упорядочены по дате начала
+	if want := []uint{created[1].ID, created[0].ID}; !equalIDs(ids(list), want) {
+		t.Errorf("ListByUserID(%s) returned %v, want %v", userA, ids(list), want)
+	}
+
+	list, err = repo.ListByUserID(ctx, "3f2504e0-4f89-11d3-9a0c-0305e82c3301")
+	if err != nil {
+		t.Fatalf("ListByUserID of an unknown user: %v", err)
+	}
+	if len(list) != 0 {
+		t.Errorf("ListByUserID of an unknown user returned %d subscriptions", len(list))
+	}
+}
+
+func testPagination(t *testing.T, repo subs.Repository) {
+	ctx := context.Background()
+	var items []models.UserSubs
+	for i := 0; i < 7; i++ {
+		item := sub("Service", month(2024, time.Month(i+1)), nil)
+		if i >= 5 {
+			item.UserID = userB
+		}
+		items = append(items, item)
+	}
+	all := ids(create(t, repo, items...))
+
+	tests := []struct {
+		name          string
+		limit, offset int
+		userID        string
+		want          []uint
+		total         int64
+	}{
+		{name: "first page", limit: 3, offset: 0, want: all[0:3], total: 7},
+		{name: "middle page", limit: 3, offset: 3, want: all[3:6], total: 7},
+		{name: "last partial page", limit: 3, offset: 6, want: all[6:7], total: 7},
+		{name: "past the end", limit: 3, offset: 10, want: nil, total: 7},
+		{name: "page larger than total", limit: 100, offset: 0, want: all, total: 7},
+		{name: "user first page", limit: 2, offset: 0, userID: userA, want: all[0:2], total: 5},
+		{name: "user last page", limit: 2, offset: 4, userID: userA, want: all[4:5], total: 5},
+		{name: "other user", limit: 10, offset: 0, userID: userB, want: all[5:7], total: 2},
+		{name: "unknown user", limit: 10, offset: 0, userID: "3f2504e0-4f89-11d3-9a0c-0305e82c3301", want: nil, total: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, total, err := repo.ListWithPagination(ctx, tt.limit, tt.offset, tt.userID)
+			if err != nil {
+				t.Fatalf("ListWithPagination(%d, %d, %q): %v", tt.limit, tt.offset, tt.userID, err)
+			}
+			if total != tt.total {
+				t.Errorf("total = %d, want %d", total, tt.total)
+			}
+			if !equalIDs(ids(page), tt.want) {
+				t.Errorf("page = %v, want %v", ids(page), tt.want)
+			}
+		})
+	}
+}
+
+func testPriceChanges(t *testing.T, repo subs.Repository) {
+	ctx := context.Background()
+	created := create(t, repo,
+		sub("Netflix", month(2024, time.January), nil),
+		sub("Spotify", month(2024, time.January), nil),
+	)
+	netflix, spotify := created[0].ID, created[1].ID
+
+	changes := []models.PriceChange{
+		{SubID: netflix, Price: 500, EffectiveFrom: month(2024, time.September)},
+		{SubID: netflix, Price: 450, EffectiveFrom: month(2024, time.April)},
+		{SubID: spotify, Price: 300, EffectiveFrom: month(2024, time.June)},
+	}
+	for i := range changes {
+		if err := repo.CreatePriceChange(ctx, &changes[i]); err != nil {
+			t.Fatalf("CreatePriceChange: %v", err)
+		}
+		if changes[i].ID == 0 {
+			t.Fatal("CreatePriceChange did not assign an ID")
+		}
+	}
+
+	list, err := repo.ListPriceChanges(ctx, netflix)
+	if err != nil {
+		t.Fatalf("ListPriceChanges(%d): %v", netflix, err)
+	}
+	// Изменения упорядочены по дате вступления в силу
+	if len(list) != 2 || list[0].ID != changes[1].ID || list[1].ID != changes[0].ID {
+		t.Fatalf("ListPriceChanges(%d) returned %+v, want changes %d and %d", netflix, list, changes[1].ID, changes[0].ID)
+	}
+	if list[0].Price != 450 || !list[0].EffectiveFrom.Equal(month(2024, time.April)) {
+		t.Errorf("ListPriceChanges(%d)[0] = %+v", netflix, list[0])
+	}
+
+	// Изменение удаляется только у своей подписки
+	if err := repo.DeletePriceChange(ctx, spotify, changes[0].ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("DeletePriceChange of another subscription's change: got %v, want gorm.ErrRecordNotFound", err)
+	}
+	if err := repo.DeletePriceChange(ctx, netflix, changes[0].ID); err != nil {
+		t.Fatalf("DeletePriceChange(%d, %d): %v", netflix, changes[0].ID, err)
+	}
+	if list, err = repo.ListPriceChanges(ctx, netflix); err != nil {
+		t.Fatalf("ListPriceChanges(%d): %v", netflix, err)
+	}
+	if len(list) != 1 || list[0].ID != changes[1].ID {
+		t.Errorf("ListPriceChanges(%d) after delete returned %+v", netflix, list)
+	}
+
+	// Удаление подписки удаляет и её изменения цены
+	if err := repo.Delete(ctx, spotify); err != nil {
+		t.Fatalf("Delete(%d): %v", spotify, err)
+	}
+	if list, err = repo.ListPriceChanges(ctx, spotify); err != nil {
+		t.Fatalf("ListPriceChanges(%d): %v", spotify, err)
+	}
+	if len(list) != 0 {
+		t.Errorf("ListPriceChanges(%d) of a deleted subscription returned %d changes", spotify, len(list))
+	}
+}
+
+func testTenantIsolation(t *testing.T, repo subs.Repository) {
+	ctx := context.Background()
+	acme := repo.ForTenant("acme")
+	own := create(t, acme, sub("Netflix", month(2024, time.January), nil))[0]
+	foreign := create(t, repo, sub("Spotify", month(2024, time.January), nil))[0]
+
+	if own.TenantID != "acme" {
+		t.Errorf("Create through ForTenant(acme): tenant %q, want acme", own.TenantID)
+	}
+	if _, err := acme.GetByID(ctx, foreign.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("GetByID of another tenant's subscription: got %v, want gorm.ErrRecordNotFound", err)
+	}
+	update := foreign
+	update.Price = 1
+	if err := acme.Update(ctx, &update); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Update of another tenant's subscription: got %v, want gorm.ErrRecordNotFound", err)
+	}
+	if err := acme.Delete(ctx, foreign.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Errorf("Delete of another tenant's subscription: got %v, want gorm.ErrRecordNotFound", err)
+	}
+
+	list, err := acme.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if !equalIDs(ids(list), []uint{own.ID}) {
+		t.Errorf("List of tenant acme returned %v, want [%d]", ids(list), own.ID)
+	}
+	_, total, err := acme.ListWithPagination(ctx, 10, 0, "")
+	if err != nil {
+		t.Fatalf("ListWithPagination: %v", err)
+	}
+	if total != 1 {
+		t.Errorf("ListWithPagination of tenant acme: total %d, want 1", total)
+	}
+	sum, err := acme.GetTotalPriceForPeriod(ctx, month(2024, time.January), month(2024, time.April), subs.ReportFilter{})
+	if err != nil {
+		t.Fatalf("GetTotalPriceForPeriod: %v", err)
+	}
+	if sum != 300 {
+		t.Errorf("GetTotalPriceForPeriod of tenant acme = %d, want 300", sum)
+	}
+
+	// Репозиторий без арендатора (фоновые задачи) видит подписки всех арендаторов
+	if list, err = repo.List(ctx); err != nil {
+		t.Fatalf("List without tenant: %v", err)
+	}
+	if len(list) != 2 {
+		t.Errorf("List without tenant returned %d subscriptions, want 2", len(list))
+	}
+}
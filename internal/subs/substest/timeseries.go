@@ -0,0 +1,173 @@
+package substest
+
+import (
+	"app/internal/models"
+	"app/internal/subs"
+	"context"
+	"testing"
+	"time"
+)
+
+// point — ожидаемая точка временного ряда
+type point struct {
+	period time.Time
+	total  uint
+	active int
+}
+
+// timeseriesCase — сценарий временного ряда GetTimeseries
+type timeseriesCase struct {
+	name       string
+	subs       []models.UserSubs
+	pricing    pricing
+	start, end time.Time
+	interval   models.BillingPeriod
+	filter     subs.ReportFilter
+	want       []point
+}
+
+// timeseriesCases — сценарии GetTimeseries. Подписка стоит 100 в месяц; если не указано иное,
+// период — первый квартал 2024 года по месяцам. Интервал, который начинается в конце периода, в ряд не входит
+func timeseriesCases() []timeseriesCase {
+	one := func(start time.Time, end *time.Time) []models.UserSubs {
+		return []models.UserSubs{sub("Netflix", start, end)}
+	}
+	jan, feb, mar, apr := month(2024, time.January), month(2024, time.February), month(2024, time.March), month(2024, time.April)
+	nextYear := month(2025, time.January)
+
+	cases := []timeseriesCase{
+		{
+			name: "monthly",
+			subs: one(jan, nil),
+			want: []point{{jan, 100, 1}, {feb, 100, 1}, {mar, 100, 1}},
+		},
+		{
+			// Списание 15 марта оплачивает месяц, который заканчивается после периода, и в ряд не входит
+			name: "starts inside the range",
+			subs: one(day(2024, time.February, 15), nil),
+			want: []point{{jan, 0, 0}, {feb, 100, 1}, {mar, 0, 1}},
+		},
+		{
+			// Подписка активна в марте до 1-го числа, но март уже не оплачивается
+			name: "ends inside the range",
+			subs: one(jan, ptr(mar)),
+			want: []point{{jan, 100, 1}, {feb, 100, 1}, {mar, 0, 1}},
+		},
+		{
+			name: "started long before the range",
+			subs: one(month(2015, time.January), nil),
+			want: []point{{jan, 100, 1}, {feb, 100, 1}, {mar, 100, 1}},
+		},
+		{
+			name:     "quarters",
+			subs:     one(jan, nil),
+			end:      nextYear,
+			interval: models.BillingQuarter,
+			want:     []point{{jan, 300, 1}, {apr, 300, 1}, {month(2024, time.July), 300, 1}, {month(2024, time.October), 300, 1}},
+		},
+		{
+			name:     "years from mid-year",
+			subs:     one(month(2023, time.January), nil),
+			start:    month(2023, time.July),
+			end:      nextYear,
+			interval: models.BillingYear,
+			want:     []point{{month(2023, time.January), 600, 1}, {jan, 1200, 1}},
+		},
+		{
+			// Те же подписка и изменение цены, что в totalCases: сумма по кварталам равна стоимости за год
+			name: "quarterly billing, price change inside a quarter",
+			subs: one(jan, nil),
+			pricing: pricing{
+				period:  models.BillingQuarter,
+				changes: []models.PriceChange{{Price: 200, EffectiveFrom: month(2024, time.May)}},
+			},
+			end:      nextYear,
+			interval: models.BillingQuarter,
+			want:     []point{{jan, 300, 1}, {apr, 300, 1}, {month(2024, time.July), 600, 1}, {month(2024, time.October), 600, 1}},
+		},
+		{
+			name: "filter by user",
+			subs: []models.UserSubs{
+				{ServiceName: "Netflix", UserID: userA, Price: 100, StartDate: jan},
+				{ServiceName: "Netflix", UserID: userB, Price: 250, StartDate: feb},
+			},
+			filter: subs.ReportFilter{UserID: userB},
+			want:   []point{{jan, 0, 0}, {feb, 250, 1}, {mar, 250, 1}},
+		},
+		{
+			name: "charge on a bucket boundary",
+			subs: one(feb, ptr(apr)),
+			want: []point{{jan, 0, 0}, {feb, 100, 1}, {mar, 100, 1}},
+		},
+		{
+			// Последний интервал обрезается по концу периода: мартовское списание оплачивает месяц за его пределами
+			name: "range ends inside a bucket",
+			subs: one(jan, nil),
+			end:  day(2024, time.March, 15),
+			want: []point{{jan, 100, 1}, {feb, 100, 1}, {mar, 0, 1}},
+		},
+		{
+			name:  "range starts inside a bucket",
+			subs:  one(jan, nil),
+			start: day(2024, time.January, 10),
+			want:  []point{{jan, 0, 1}, {feb, 100, 1}, {mar, 100, 1}},
+		},
+		{
+			name: "range ends a second after a bucket starts",
+			subs: one(jan, nil),
+			end:  mar.Add(time.Second),
+			want: []point{{jan, 100, 1}, {feb, 100, 1}, {mar, 0, 1}},
+		},
+		{
+			name:  "empty range on a bucket start",
+			subs:  one(month(2023, time.January), nil),
+			start: feb, end: feb,
+			want: []point{},
+		},
+		{
+			name:  "empty range inside a bucket",
+			subs:  one(month(2023, time.January), nil),
+			start: day(2024, time.February, 10), end: day(2024, time.February, 10),
+			want: []point{},
+		},
+		{
+			name: "no subscriptions",
+			want: []point{{jan, 0, 0}, {feb, 0, 0}, {mar, 0, 0}},
+		},
+	}
+	for i := range cases {
+		if cases[i].start.IsZero() {
+			cases[i].start = jan
+		}
+		if cases[i].end.IsZero() {
+			cases[i].end = apr
+		}
+		if cases[i].interval == "" {
+			cases[i].interval = models.BillingMonth
+		}
+	}
+	return cases
+}
+
+func testTimeseries(t *testing.T, newRepo NewRepository) {
+	for _, tt := range timeseriesCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			createPriced(t, repo, tt.pricing, tt.subs...)
+			points, err := repo.GetTimeseries(context.Background(), tt.start, tt.end, tt.filter, tt.interval)
+			if err != nil {
+				t.Fatalf("GetTimeseries(%s, %s, %s): %v", tt.start, tt.end, tt.interval, err)
+			}
+			if len(points) != len(tt.want) {
+				t.Fatalf("GetTimeseries(%s, %s, %s) returned %d points %+v, want %d", tt.start, tt.end, tt.interval, len(points), points, len(tt.want))
+			}
+			for i, got := range points {
+				want := tt.want[i]
+				if !got.Period.Equal(want.period) || got.Total != want.total || got.ActiveCount != want.active {
+					t.Errorf("point #%d = %s total %d active %d, want %s total %d active %d", i+1,
+						got.Period.Format(time.DateOnly), got.Total, got.ActiveCount, want.period.Format(time.DateOnly), want.total, want.active)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package substest
+
+import (
+	"app/internal/models"
+	"app/internal/subs"
+	"context"
+	"testing"
+	"time"
+)
+
+// totalCase — сценарий пересечения подписок с периодом отчёта GetTotalPriceForPeriod
+type totalCase struct {
+	name       string
+	subs       []models.UserSubs
+	pricing    pricing
+	start, end time.Time
+	filter     subs.ReportFilter
+	want       uint
+}
+
+// totalCases — сценарии GetTotalPriceForPeriod. Если не указано иное, период — 2024 год,
+// а подписка стоит 100 в месяц. Оплачиваются только полные месяцы пересечения подписки с периодом,
+// каждый по цене на дату списания, которым он оплачен
+func totalCases() []totalCase {
+	one := func(start time.Time, end *time.Time) []models.UserSubs {
+		return []models.UserSubs{sub("Netflix", start, end)}
+	}
+
+	cases := []totalCase{
+		{name: "inside period", subs: one(month(2024, time.March), ptr(month(2024, time.June))), want: 300},
+		{name: "covers period", subs: one(month(2023, time.June), ptr(month(2025, time.June))), want: 1200},
+		{name: "matches period", subs: one(month(2024, time.January), ptr(month(2025, time.January))), want: 1200},
+		{name: "starts before, ends inside", subs: one(month(2023, time.October), ptr(month(2024, time.April))), want: 300},
+		{name: "starts inside, ends after", subs: one(month(2024, time.October), ptr(month(2025, time.March))), want: 300},
+		{name: "ends before period", subs: one(month(2023, time.January), ptr(month(2023, time.December))), want: 0},
+		{name: "starts after period", subs: one(month(2025, time.February), nil), want: 0},
+		{name: "ends on period start", subs: one(month(2023, time.June), ptr(month(2024, time.January))), want: 0},
+		{name: "ends a day after period start", subs: one(month(2023, time.June), ptr(day(2024, time.January, 2))), want: 0},
+		{name: "ends a month after period start", subs: one(month(2023, time.June), ptr(month(2024, time.February))), want: 100},
+		{name: "starts on period end", subs: one(month(2025, time.January), nil), want: 0},
+		{name: "starts a month before period end", subs: one(month(2024, time.December), nil), want: 100},
+		{name: "open-ended, started before", subs: one(month(2023, time.May), nil), want: 1200},
+		{name: "open-ended, started inside", subs: one(month(2024, time.July), nil), want: 600},
+		{name: "same start and end", subs: one(month(2024, time.May), ptr(month(2024, time.May))), want: 0},
+		{name: "partial month is not charged", subs: one(day(2024, time.January, 15), ptr(day(2024, time.March, 10))), want: 100},
+		{name: "whole months from mid-month start", subs: one(day(2024, time.January, 15), ptr(day(2024, time.March, 15))), want: 200},
+		{name: "leap day end", subs: one(day(2024, time.January, 31), ptr(day(2024, time.February, 29))), want: 0},
+		{name: "month end to month end", subs: one(day(2024, time.January, 31), ptr(day(2024, time.March, 31))), want: 200},
+		{
+			name:  "mid-month period start",
+			subs:  one(month(2024, time.January), nil),
+			start: day(2024, time.February, 10), end: month(2025, time.January),
+			want: 1000,
+		},
+		{
+			name:  "mid-month period end",
+			subs:  one(month(2023, time.January), nil),
+			start: month(2024, time.January), end: day(2024, time.June, 20),
+			want: 500,
+		},
+		{
+			name:  "single-month period",
+			subs:  one(month(2024, time.January), nil),
+			start: month(2024, time.March), end: month(2024, time.April),
+			want: 100,
+		},
+		{
+			name:  "empty period",
+			subs:  one(month(2024, time.January), nil),
+			start: month(2024, time.March), end: month(2024, time.March),
+			want: 0,
+		},
+		{
+			name:  "reversed period",
+			subs:  one(month(2023, time.January), nil),
+			start: month(2024, time.June), end: month(2024, time.January),
+			want: 0,
+		},
+		{
+			name:  "period across years",
+			subs:  one(month(2023, time.January), nil),
+			start: month(2023, time.November), end: month(2024, time.March),
+			want: 400,
+		},
+		{
+			name:  "decade-long period",
+			subs:  one(month(2015, time.January), ptr(month(2035, time.January))),
+			start: month(2020, time.January), end: month(2030, time.January),
+			want: 12000,
+		},
+		// Те же подписки, что в forecastCases: стоимость за год равна сумме прогноза списаний за год
+		{
+			name:    "price change mid-range applies from the next charge",
+			subs:    one(month(2024, time.January), nil),
+			pricing: pricing{changes: []models.PriceChange{{Price: 200, EffectiveFrom: day(2024, time.July, 10)}}},
+			want:    700 + 1000,
+		},
+		{
+			name:    "quarterly",
+			subs:    one(month(2024, time.January), nil),
+			pricing: pricing{period: models.BillingQuarter},
+			want:    1200,
+		},
+		{
+			name: "quarterly, price change inside a quarter",
+			subs: one(month(2024, time.January), nil),
+			pricing: pricing{
+				period:  models.BillingQuarter,
+				changes: []models.PriceChange{{Price: 200, EffectiveFrom: month(2024, time.May)}},
+			},
+			want: 300 + 300 + 600 + 600,
+		},
+		{
+			name: "yearly, price changes between charges",
+			subs: one(month(2023, time.January), nil),
+			pricing: pricing{
+				period: models.BillingYear,
+				changes: []models.PriceChange{
+					{Price: 200, EffectiveFrom: month(2023, time.June)},
+					{Price: 300, EffectiveFrom: month(2024, time.June)},
+				},
+			},
+			want: 2400,
+		},
+		{
+			name: "quarterly, period starts inside a quarter",
+			subs: one(month(2024, time.January), nil),
+			pricing: pricing{
+				period:  models.BillingQuarter,
+				changes: []models.PriceChange{{Price: 200, EffectiveFrom: month(2024, time.May)}},
+			},
+			start: month(2024, time.May), end: month(2025, time.January),
+			want: 200 + 1200,
+		},
+		{name: "no subscriptions", want: 0},
+		{
+			name: "several subscriptions",
+			subs: []models.UserSubs{
+				{ServiceName: "Netflix", UserID: userA, Price: 100, StartDate: month(2024, time.January), EndDate: ptr(month(2024, time.July))},
+				{ServiceName: "Spotify", UserID: userA, Price: 250, StartDate: month(2024, time.June)},
+				{ServiceName: "YouTube", UserID: userA, Price: 300, StartDate: month(2022, time.January), EndDate: ptr(month(2023, time.June))},
+			},
+			want: 600 + 1750,
+		},
+		{
+			name: "different prices",
+			subs: []models.UserSubs{
+				{ServiceName: "Netflix", UserID: userA, Price: 399, StartDate: month(2024, time.January), EndDate: ptr(month(2024, time.April))},
+				{ServiceName: "Spotify", UserID: userA, Price: 1, StartDate: month(2024, time.January)},
+			},
+			want: 1197 + 12,
+		},
+		{
+			name: "filter by user",
+			subs: []models.UserSubs{
+				{ServiceName: "Netflix", UserID: userA, Price: 100, StartDate: month(2024, time.January)},
+				{ServiceName: "Netflix", UserID: userB, Price: 250, StartDate: month(2024, time.January)},
+			},
+			filter: subs.ReportFilter{UserID: userB},
+			want:   3000,
+		},
+		{
+			name: "filter by service",
+			subs: []models.UserSubs{
+				{ServiceName: "Netflix", UserID: userA, Price: 100, StartDate: month(2024, time.January), EndDate: ptr(month(2024, time.July))},
+				{ServiceName: "Spotify", UserID: userA, Price: 100, StartDate: month(2024, time.January)},
+			},
+			filter: subs.ReportFilter{ServiceName: "Spotify"},
+			want:   1200,
+		},
+		{
+			name: "filter by user and service",
+			subs: []models.UserSubs{
+				{ServiceName: "Netflix", UserID: userA, Price: 100, StartDate: month(2024, time.January)},
+				{ServiceName: "Netflix", UserID: userB, Price: 100, StartDate: month(2024, time.June)},
+				{ServiceName: "Spotify", UserID: userB, Price: 100, StartDate: month(2024, time.January)},
+			},
+			filter: subs.ReportFilter{UserID: userB, ServiceName: "Netflix"},
+			want:   700,
+		},
+		{
+			name:   "service filter is case sensitive",
+			subs:   []models.UserSubs{{ServiceName: "Spotify", UserID: userA, Price: 100, StartDate: month(2024, time.January)}},
+			filter: subs.ReportFilter{ServiceName: "spotify"},
+			want:   0,
+		},
+		{
+			name:   "filter matches nothing",
+			subs:   []models.UserSubs{{ServiceName: "Netflix", UserID: userA, Price: 100, StartDate: month(2024, time.January)}},
+			filter: subs.ReportFilter{UserID: "3f2504e0-4f89-11d3-9a0c-0305e82c3301"},
+			want:   0,
+		},
+	}
+	for i := range cases {
+		if cases[i].start.IsZero() {
+			cases[i].start, cases[i].end = month(2024, time.January), month(2025, time.January)
+		}
+	}
+	return cases
+}
+
+func testTotalPriceForPeriod(t *testing.T, newRepo NewRepository) {
+	for _, tt := range totalCases() {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newRepo(t)
+			createPriced(t, repo, tt.pricing, tt.subs...)
+			got, err := repo.GetTotalPriceForPeriod(context.Background(), tt.start, tt.end, tt.filter)
+			if err != nil {
+				t.Fatalf("GetTotalPriceForPeriod(%s, %s, %+v): %v", tt.start, tt.end, tt.filter, err)
+			}
+			if got != tt.want {
+				t.Errorf("GetTotalPriceForPeriod(%s, %s, %+v) = %d, want %d", tt.start, tt.end, tt.filter, got, tt.want)
+			}
+		})
+	}
+}
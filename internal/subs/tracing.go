@@ -0,0 +1,300 @@
+package subs
+
+import (
+	"app/internal/models"
+	"app/internal/tracing"
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// tracer — трассировщик сервиса и репозитория подписок
+var tracer = otel.Tracer("app/internal/subs")
+
+// tracedService — Service, выполняющий каждый метод в отдельном спане OpenTelemetry
+type tracedService struct {
+	service Service
+}
+
+// Trace оборачивает service спанами OpenTelemetry
+func Trace(service Service) Service {
+	return &tracedService{service: service}
+}
+
+// CreateSub выполняет Service.CreateSub в отдельном спане
+func (t *tracedService) CreateSub(ctx context.Context, sub *models.UserSubs) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.CreateSub")
+	err := t.service.CreateSub(ctx, sub)
+	end(err)
+	return err
+}
+
+// GetSubByID выполняет Service.GetSubByID в отдельном спане
+func (t *tracedService) GetSubByID(ctx context.Context, id uint) (*models.UserSubs, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.GetSubByID")
+	result, err := t.service.GetSubByID(ctx, id)
+	end(err)
+	return result, err
+}
+
+// UpdateSub выполняет Service.UpdateSub в отдельном спане
+func (t *tracedService) UpdateSub(ctx context.Context, sub *models.UserSubs) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.UpdateSub")
+	err := t.service.UpdateSub(ctx, sub)
+	end(err)
+	return err
+}
+
+// DeleteSub выполняет Service.DeleteSub в отдельном спане
+func (t *tracedService) DeleteSub(ctx context.Context, id uint) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.DeleteSub")
+	err := t.service.DeleteSub(ctx, id)
+	end(err)
+	return err
+}
+
+// ListSubs выполняет Service.ListSubs в отдельном спане
+func (t *tracedService) ListSubs(ctx context.Context) ([]models.UserSubs, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.ListSubs")
+	result, err := t.service.ListSubs(ctx)
+	end(err)
+	return result, err
+}
+
+// ListSubsByUser выполняет Service.ListSubsByUser в отдельном спане
+func (t *tracedService) ListSubsByUser(ctx context.Context, userID string) ([]models.UserSubs, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.ListSubsByUser")
+	result, err := t.service.ListSubsByUser(ctx, userID)
+	end(err)
+	return result, err
+}
+
+// ListSubsWithPagination выполняет Service.ListSubsWithPagination в отдельном спане
+func (t *tracedService) ListSubsWithPagination(ctx context.Context, limit, offset int, userID string) ([]models.UserSubs, int64, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.ListSubsWithPagination")
+	result, total, err := t.service.ListSubsWithPagination(ctx, limit, offset, userID)
+	end(err)
+	return result, total, err
+}
+
+// GetTotalPriceForPeriod выполняет Service.GetTotalPriceForPeriod в отдельном спане
+func (t *tracedService) GetTotalPriceForPeriod(ctx context.Context, startDate, endDate time.Time, filter ReportFilter) (uint, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.GetTotalPriceForPeriod")
+	result, err := t.service.GetTotalPriceForPeriod(ctx, startDate, endDate, filter)
+	end(err)
+	return result, err
+}
+
+// GetTotalPriceBreakdown выполняет Service.GetTotalPriceBreakdown в отдельном спане
+func (t *tracedService) GetTotalPriceBreakdown(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, groupBy string) ([]models.TotalByGroup, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.GetTotalPriceBreakdown")
+	result, err := t.service.GetTotalPriceBreakdown(ctx, startDate, endDate, filter, groupBy)
+	end(err)
+	return result, err
+}
+
+// GetTimeseries выполняет Service.GetTimeseries в отдельном спане
+func (t *tracedService) GetTimeseries(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, interval string) ([]models.TimeseriesPoint, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.GetTimeseries")
+	result, err := t.service.GetTimeseries(ctx, startDate, endDate, filter, interval)
+	end(err)
+	return result, err
+}
+
+// GetForecast выполняет Service.GetForecast в отдельном спане
+func (t *tracedService) GetForecast(ctx context.Context, months int, filter ReportFilter) (*models.Forecast, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.GetForecast")
+	result, err := t.service.GetForecast(ctx, months, filter)
+	end(err)
+	return result, err
+}
+
+// CreatePriceChange выполняет Service.CreatePriceChange в отдельном спане
+func (t *tracedService) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.CreatePriceChange")
+	err := t.service.CreatePriceChange(ctx, change)
+	end(err)
+	return err
+}
+
+// ListPriceChanges выполняет Service.ListPriceChanges в отдельном спане
+func (t *tracedService) ListPriceChanges(ctx context.Context, subID uint) ([]models.PriceChange, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.ListPriceChanges")
+	result, err := t.service.ListPriceChanges(ctx, subID)
+	end(err)
+	return result, err
+}
+
+// ListPriceChangesByUser выполняет Service.ListPriceChangesByUser в отдельном спане
+func (t *tracedService) ListPriceChangesByUser(ctx context.Context, userID string) (map[uint][]models.PriceChange, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.ListPriceChangesByUser")
+	result, err := t.service.ListPriceChangesByUser(ctx, userID)
+	end(err)
+	return result, err
+}
+
+// DeletePriceChange выполняет Service.DeletePriceChange в отдельном спане
+func (t *tracedService) DeletePriceChange(ctx context.Context, subID, changeID uint) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.DeletePriceChange")
+	err := t.service.DeletePriceChange(ctx, subID, changeID)
+	end(err)
+	return err
+}
+
+// StreamEvents выполняет Service.StreamEvents в отдельном спане
+func (t *tracedService) StreamEvents(ctx context.Context, filter ReportFilter, afterSeq *uint64, send func(models.SubEvent) error) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Service.StreamEvents")
+	err := t.service.StreamEvents(ctx, filter, afterSeq, send)
+	end(err)
+	return err
+}
+
+// ForTenant возвращает Service арендатора tenantID с трассировкой
+func (t *tracedService) ForTenant(tenantID string) Service {
+	return Trace(t.service.ForTenant(tenantID))
+}
+
+// tracedRepository — Repository, выполняющий каждый метод в отдельном спане OpenTelemetry;
+// запросы к бд внутри метода становятся его дочерними спанами
+type tracedRepository struct {
+	repo Repository
+}
+
+// TraceRepository оборачивает repo спанами OpenTelemetry
+func TraceRepository(repo Repository) Repository {
+	return &tracedRepository{repo: repo}
+}
+
+// Create выполняет Repository.Create в отдельном спане
+func (t *tracedRepository) Create(ctx context.Context, sub *models.UserSubs) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.Create")
+	err := t.repo.Create(ctx, sub)
+	end(err)
+	return err
+}
+
+// GetByID выполняет Repository.GetByID в отдельном спане
+func (t *tracedRepository) GetByID(ctx context.Context, id uint) (*models.UserSubs, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.GetByID")
+	result, err := t.repo.GetByID(ctx, id)
+	end(err)
+	return result, err
+}
+
+// Update выполняет Repository.Update в отдельном спане
+func (t *tracedRepository) Update(ctx context.Context, sub *models.UserSubs) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.Update")
+	err := t.repo.Update(ctx, sub)
+	end(err)
+	return err
+}
+
+// Delete выполняет Repository.Delete в отдельном спане
+func (t *tracedRepository) Delete(ctx context.Context, id uint) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.Delete")
+	err := t.repo.Delete(ctx, id)
+	end(err)
+	return err
+}
+
+// List выполняет Repository.List в отдельном спане
+func (t *tracedRepository) List(ctx context.Context) ([]models.UserSubs, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.List")
+	result, err := t.repo.List(ctx)
+	end(err)
+	return result, err
+}
+
+// ListByUserID выполняет Repository.ListByUserID в отдельном спане
+func (t *tracedRepository) ListByUserID(ctx context.Context, userID string) ([]models.UserSubs, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.ListByUserID")
+	result, err := t.repo.ListByUserID(ctx, userID)
+	end(err)
+	return result, err
+}
+
+// ListWithPagination выполняет Repository.ListWithPagination в отдельном спане
+func (t *tracedRepository) ListWithPagination(ctx context.Context, limit, offset int, userID string) ([]models.UserSubs, int64, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.ListWithPagination")
+	result, total, err := t.repo.ListWithPagination(ctx, limit, offset, userID)
+	end(err)
+	return result, total, err
+}
+
+// GetTotalPriceForPeriod выполняет Repository.GetTotalPriceForPeriod в отдельном спане
+func (t *tracedRepository) GetTotalPriceForPeriod(ctx context.Context, startDate, endDate time.Time, filter ReportFilter) (uint, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.GetTotalPriceForPeriod")
+	result, err := t.repo.GetTotalPriceForPeriod(ctx, startDate, endDate, filter)
+	end(err)
+	return result, err
+}
+
+// GetTotalPriceBreakdown выполняет Repository.GetTotalPriceBreakdown в отдельном спане
+func (t *tracedRepository) GetTotalPriceBreakdown(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, groupBy string) ([]models.TotalByGroup, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.GetTotalPriceBreakdown")
+	result, err := t.repo.GetTotalPriceBreakdown(ctx, startDate, endDate, filter, groupBy)
+	end(err)
+	return result, err
+}
+
+// GetTimeseries выполняет Repository.GetTimeseries в отдельном спане
+func (t *tracedRepository) GetTimeseries(ctx context.Context, startDate, endDate time.Time, filter ReportFilter, interval models.BillingPeriod) ([]models.TimeseriesPoint, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.GetTimeseries")
+	result, err := t.repo.GetTimeseries(ctx, startDate, endDate, filter, interval)
+	end(err)
+	return result, err
+}
+
+// GetForecast выполняет Repository.GetForecast в отдельном спане
+func (t *tracedRepository) GetForecast(ctx context.Context, from time.Time, months int, filter ReportFilter) ([]models.ForecastPoint, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.GetForecast")
+	result, err := t.repo.GetForecast(ctx, from, months, filter)
+	end(err)
+	return result, err
+}
+
+// CreatePriceChange выполняет Repository.CreatePriceChange в отдельном спане
+func (t *tracedRepository) CreatePriceChange(ctx context.Context, change *models.PriceChange) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.CreatePriceChange")
+	err := t.repo.CreatePriceChange(ctx, change)
+	end(err)
+	return err
+}
+
+// ListPriceChanges выполняет Repository.ListPriceChanges в отдельном спане
+func (t *tracedRepository) ListPriceChanges(ctx context.Context, subID uint) ([]models.PriceChange, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.ListPriceChanges")
+	result, err := t.repo.ListPriceChanges(ctx, subID)
+	end(err)
+	return result, err
+}
+
+// ListPriceChangesByUser выполняет Repository.ListPriceChangesByUser в отдельном спане
+func (t *tracedRepository) ListPriceChangesByUser(ctx context.Context, userID string) (map[uint][]models.PriceChange, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.ListPriceChangesByUser")
+	result, err := t.repo.ListPriceChangesByUser(ctx, userID)
+	end(err)
+	return result, err
+}
+
+// DeletePriceChange выполняет Repository.DeletePriceChange в отдельном спане
+func (t *tracedRepository) DeletePriceChange(ctx context.Context, subID, changeID uint) error {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.DeletePriceChange")
+	err := t.repo.DeletePriceChange(ctx, subID, changeID)
+	end(err)
+	return err
+}
+
+// GetServiceStats выполняет Repository.GetServiceStats в отдельном спане
+func (t *tracedRepository) GetServiceStats(ctx context.Context, at time.Time) ([]models.ServiceStats, error) {
+	ctx, end := tracing.Start(ctx, tracer, "subs.Repository.GetServiceStats")
+	result, err := t.repo.GetServiceStats(ctx, at)
+	end(err)
+	return result, err
+}
+
+// ForTenant возвращает Repository арендатора tenantID с трассировкой
+func (t *tracedRepository) ForTenant(tenantID string) Repository {
+	return TraceRepository(t.repo.ForTenant(tenantID))
+}
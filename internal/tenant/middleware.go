@@ -0,0 +1,62 @@
+package tenant
+
+import (
+	"app/internal/auth"
+	"app/internal/logging"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Header — заголовок, которым выбирается арендатор
+const Header = "X-Tenant-ID"
+
+// contextKey — ключ арендатора в gin.Context
+const contextKey = "tenant.id"
+
+// Middleware определяет арендатора запроса. Арендатор из токена или API-ключа обязателен для вызывающего,
+// заголовок X-Tenant-ID может его только повторить. Вызывающий без арендатора в учётных данных работает
+// с арендатором Default; выбрать другого заголовком может только admin или любой вызывающий при выключенной аутентификации
+func Middleware(logger *logrus.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(Header)
+		tenantID := Default
+
+		identity, authenticated := auth.IdentityFrom(c)
+		switch {
+		case authenticated && identity.TenantID != "":
+			if header != "" && header != identity.TenantID {
+				logging.From(c.Request.Context(), logger).Warnf("tenant.Middleware: %s of tenant %s requested tenant %s", identity.Subject, identity.TenantID, header)
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+				return
+			}
+			tenantID = identity.TenantID
+		case header != "" && (!authenticated || identity.HasRole(auth.RoleAdmin)):
+			tenantID = header
+		case header != "" && header != Default:
+			logging.From(c.Request.Context(), logger).Warnf("tenant.Middleware: %s is not allowed to select tenant %s", identity.Subject, header)
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+
+		c.Set(contextKey, tenantID)
+		c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), tenantID))
+		fields := logrus.Fields{"tenant_id": tenantID}
+		if authenticated {
+			fields["subject"] = identity.Subject
+		}
+		logging.Annotate(c, fields)
+		c.Next()
+	}
+}
+
+// From возвращает арендатора запроса, определённого Middleware
+func From(c *gin.Context) string {
+	if value, ok := c.Get(contextKey); ok {
+		if tenantID, ok := value.(string); ok && tenantID != "" {
+			return tenantID
+		}
+	}
+	return FromContext(c.Request.Context())
+}
@@ -0,0 +1,104 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Default — арендатор, к которому относятся данные, если арендатор не указан
+const Default = "default"
+
+// settingName — параметр сессии Postgres, по которому политики row-level security отбирают строки арендатора
+const settingName = "app.tenant_id"
+
+// tenantKey — ключ арендатора в контексте запроса
+type tenantKey struct{}
+
+// WithTenant возвращает копию ctx с арендатором tenantID
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// FromContext возвращает арендатора из контекста запроса или Default
+func FromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantKey{}).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return Default
+}
+
+// Scope — GORM scope, оставляющий в запросе только строки арендатора tenantID
+func Scope(tenantID string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(clause.Eq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"},
+			Value:  tenantID,
+		})
+	}
+}
+
+// DB возвращает подключение, все запросы через которое ограничены арендатором tenantID
+func DB(db *gorm.DB, tenantID string) *gorm.DB {
+	return db.Scopes(Scope(tenantID)).Session(&gorm.Session{})
+}
+
+// Transaction выполняет fn в транзакции от имени арендатора tenantID: запросы через tx ограничены Scope,
+// а в Postgres параметр app.tenant_id включает политики row-level security как вторую линию защиты
+func Transaction(db *gorm.DB, tenantID string, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" {
+			if err := tx.Exec("SELECT set_config(?, ?, true)", settingName, tenantID).Error; err != nil {
+				return err
+			}
+		}
+		return fn(DB(tx, tenantID))
+	})
+}
+
+// workerRole — роль Postgres с BYPASSRLS, от имени которой AllTenants работает со строками всех арендаторов
+var workerRole string
+
+// SetWorkerRole задаёт роль для AllTenants; пустая роль — подключение и так не подчиняется политикам
+func SetWorkerRole(role string) {
+	workerRole = role
+}
+
+// AllTenants выполняет fn в транзакции без ограничения арендатором: так работают фоновые задачи и общий каталог.
+// Без app.tenant_id политики row-level security не пропускают ни одной строки, поэтому в Postgres
+// транзакция переключается на роль SetWorkerRole
+func AllTenants(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Dialector.Name() == "postgres" && workerRole != "" {
+			if err := tx.Exec("SET LOCAL ROLE " + tx.Statement.Quote(workerRole)).Error; err != nil {
+				return fmt.Errorf("switch to worker role %s: %w", workerRole, err)
+			}
+		}
+		return fn(tx)
+	})
+}
+
+// EnableRowLevelSecurity включает в Postgres политику tenant_isolation для таблиц tables.
+// Политика пропускает только строки арендатора из app.tenant_id; без параметра строк не видно,
+// и работать со всеми арендаторами можно только через AllTenants. Суперпользователь политику обходит
+func EnableRowLevelSecurity(db *gorm.DB, tables ...string) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+	for _, table := range tables {
+		statements := []string{
+			fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", table),
+			fmt.Sprintf("ALTER TABLE %s FORCE ROW LEVEL SECURITY", table),
+			fmt.Sprintf("DROP POLICY IF EXISTS tenant_isolation ON %s", table),
+			fmt.Sprintf("CREATE POLICY tenant_isolation ON %s USING (tenant_id = current_setting('%s', true))", table, settingName),
+		}
+		for _, statement := range statements {
+			if err := db.Exec(statement).Error; err != nil {
+				return fmt.Errorf("enable row level security on %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package tenant_test
+
+import (
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"app/internal/tenant"
+	"net/url"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Роли, которые тест создаёт так же, как docker/postgres/init-roles.sh
+const (
+	appRole    = "subs_rls_test_app"
+	workerRole = "subs_rls_test_worker"
+)
+
+// TestRowLevelSecurityPostgres проверяет политики row-level security на Postgres из TEST_POSTGRES_DSN
+// (или в контейнере Docker) от имени роли API: без SUPERUSER и BYPASSRLS и не владельца таблиц.
+// Роль подключается через отдельное подключение, потому что database.Init включает политики и требует владельца
+func TestRowLevelSecurityPostgres(t *testing.T) {
+	dsn := dbtest.PostgresDSN(t)
+	owner := dbtest.Open(t, dbtest.PostgresEnv(t, dsn))
+
+	setup := []string{
+		"DO $$ BEGIN IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '" + appRole + "') THEN " +
+			"CREATE ROLE " + appRole + " LOGIN PASSWORD '" + appRole + "' NOSUPERUSER NOBYPASSRLS; END IF; END $$",
+		"DO $$ BEGIN IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = '" + workerRole + "') THEN " +
+			"CREATE ROLE " + workerRole + " NOLOGIN BYPASSRLS; END IF; END $$",
+		"GRANT " + workerRole + " TO " + appRole,
+		"GRANT USAGE ON SCHEMA public TO " + appRole,
+		"GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO " + appRole + ", " + workerRole,
+		"GRANT USAGE, SELECT, UPDATE ON ALL SEQUENCES IN SCHEMA public TO " + appRole + ", " + workerRole,
+		"TRUNCATE user_subs, price_changes RESTART IDENTITY",
+	}
+	for _, statement := range setup {
+		if err := owner.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	start := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rows := []models.UserSubs{
+		{TenantID: "tenant-a", ServiceName: "Netflix", Price: 100, UserID: "60601fee-2bf1-4721-ae6f-7636e79a0cba", StartDate: start},
+		{TenantID: "tenant-b", ServiceName: "Netflix", Price: 200, UserID: "0b7ac2d4-4a5e-4c0e-9a8e-3f1f2b6d9e11", StartDate: start},
+	}
+	if err := owner.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.User = url.UserPassword(appRole, appRole)
+	app, err := gorm.Open(postgres.Open(u.String()), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect as %s: %v", appRole, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := app.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		tenant.SetWorkerRole("")
+	})
+
+	// tenantsOf возвращает арендаторов строк user_subs, видимых в транзакции; условие where не ограничивается арендатором
+	tenantsOf := func(t *testing.T, tx *gorm.DB, where string) []string {
+		t.Helper()
+		var tenants []string
+		query := tx.Session(&gorm.Session{NewDB: true}).Model(&models.UserSubs{}).Order("tenant_id")
+		if where != "" {
+			query = query.Where(where)
+		}
+		if err := query.Pluck("tenant_id", &tenants).Error; err != nil {
+			t.Fatal(err)
+		}
+		return tenants
+	}
+
+	t.Run("tenant transaction sees only its rows", func(t *testing.T) {
+		err := tenant.Transaction(app, "tenant-a", func(tx *gorm.DB) error {
+			if got := tenantsOf(t, tx, ""); len(got) != 1 || got[0] != "tenant-a" {
+				t.Errorf("tenant-a sees rows of %v, want only tenant-a", got)
+			}
+			// Запрос мимо tenant.Scope всё равно не видит чужих строк
+			if got := tenantsOf(t, tx, "tenant_id = 'tenant-b'"); len(got) != 0 {
+				t.Errorf("tenant-a reads rows of %v, want none", got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("no rows without a tenant", func(t *testing.T) {
+		if got := tenantsOf(t, app, ""); len(got) != 0 {
+			t.Errorf("connection without app.tenant_id sees rows of %v, want none", got)
+		}
+		tenant.SetWorkerRole("")
+		err := tenant.AllTenants(app, func(tx *gorm.DB) error {
+			if got := tenantsOf(t, tx, ""); len(got) != 0 {
+				t.Errorf("AllTenants without the worker role sees rows of %v, want none", got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("worker role sees all tenants", func(t *testing.T) {
+		tenant.SetWorkerRole(workerRole)
+		err := tenant.AllTenants(app, func(tx *gorm.DB) error {
+			if got := tenantsOf(t, tx, ""); len(got) != 2 || got[0] != "tenant-a" || got[1] != "tenant-b" {
+				t.Errorf("AllTenants sees rows of %v, want tenant-a and tenant-b", got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// SET LOCAL ROLE действует только до конца транзакции
+		if got := tenantsOf(t, app, ""); len(got) != 0 {
+			t.Errorf("connection after AllTenants sees rows of %v, want none", got)
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package timeout
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Config — сроки обработки запросов. Нулевой срок означает отсутствие дедлайна
+type Config struct {
+	Default time.Duration `config:"default" env:"REQUEST_TIMEOUT"`
+	// Routes — сроки отдельных маршрутов по ключу "<METHOD> <path>", например "GET /subs/total"
+	Routes map[string]time.Duration `config:"routes" env:"REQUEST_TIMEOUT_ROUTES"`
+}
+
+// ConfigFrom читает сроки из переменных REQUEST_TIMEOUT и REQUEST_TIMEOUT_ROUTES.
+// REQUEST_TIMEOUT_ROUTES — список через запятую вида "GET /subs/total=10s,GET /subs=5s".
+// Поток событий GET /subs/events по умолчанию не ограничен
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		Default: 30 * time.Second,
+		Routes: map[string]time.Duration{
+			"GET /subs/events": 0,
+		},
+	}
+
+	if value := getenv("REQUEST_TIMEOUT"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return cfg, fmt.Errorf("timeout.ConfigFrom: invalid REQUEST_TIMEOUT %q", value)
+		}
+		cfg.Default = d
+	}
+	if value := getenv("REQUEST_TIMEOUT_ROUTES"); value != "" {
+		for _, entry := range strings.Split(value, ",") {
+			route, spec, found := strings.Cut(entry, "=")
+			method, path, hasPath := strings.Cut(strings.TrimSpace(route), " ")
+			if !found || !hasPath {
+				return cfg, fmt.Errorf("timeout.ConfigFrom: invalid REQUEST_TIMEOUT_ROUTES entry %q: expected <METHOD> <path>=<duration>", entry)
+			}
+			d, err := time.ParseDuration(strings.TrimSpace(spec))
+			if err != nil || d < 0 {
+				return cfg, fmt.Errorf("timeout.ConfigFrom: invalid REQUEST_TIMEOUT_ROUTES duration %q", spec)
+			}
+			cfg.Routes[routeKey(method, path)] = d
+		}
+	}
+	return cfg, nil
+}
+
+// routeKey возвращает ключ маршрута для Config.Routes
+func routeKey(method, path string) string {
+	return strings.ToUpper(strings.TrimSpace(method)) + " " + strings.TrimSpace(path)
+}
@@ -0,0 +1,59 @@
+package timeout
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StatusClientClosedRequest — статус для журнала, если клиент отключился раньше, чем получил ответ
+const StatusClientClosedRequest = 499
+
+// Middleware ограничивает контекст запроса сроком маршрута из cfg.Routes или cfg.Default.
+// Контекст передаётся до запросов в бд, поэтому по истечении срока или при отключении клиента запрос к бд отменяется
+func Middleware(cfg Config) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d, ok := cfg.Routes[routeKey(c.Request.Method, c.FullPath())]
+		if !ok {
+			d = cfg.Default
+		}
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Expired отвечает 504, если истёк срок обработки запроса, и прекращает обработку без ответа,
+// если клиент отключился; возвращает false, если контекст запроса ещё действует
+func Expired(c *gin.Context) bool {
+	switch err := c.Request.Context().Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "request timed out"})
+		return true
+	case errors.Is(err, context.Canceled):
+		c.AbortWithStatus(StatusClientClosedRequest)
+		return true
+	}
+	return false
+}
+
+// Until прерывает запрос, когда завершается done. Нужен долгим запросам вроде потока событий,
+// которые иначе не дали бы серверу остановиться: клиент переподключится к другому экземпляру
+func Until(done context.Context) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		stop := context.AfterFunc(done, cancel)
+		defer stop()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Config — настройки трассировки. Заголовки, TLS и остальные параметры экспортёр берёт
+// из стандартных переменных OTEL_EXPORTER_OTLP_*
+type Config struct {
+	Enabled     bool    `config:"enabled" env:"TRACING_ENABLED"`
+	ServiceName string  `config:"service_name" env:"OTEL_SERVICE_NAME"`
+	SampleRatio float64 `config:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	// Endpoint — базовый адрес OTLP/HTTP коллектора, например http://localhost:4318
+	Endpoint string `config:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+}
+
+// ConfigFrom читает настройки трассировки из переменных TRACING_*, OTEL_SERVICE_NAME и OTEL_EXPORTER_OTLP_ENDPOINT
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		ServiceName: getenv("OTEL_SERVICE_NAME"),
+		SampleRatio: 1,
+		Endpoint:    getenv("OTEL_EXPORTER_OTLP_ENDPOINT"),
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = "online-subs"
+	}
+
+	if value := getenv("TRACING_ENABLED"); value != "" {
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return cfg, fmt.Errorf("tracing.ConfigFrom: invalid TRACING_ENABLED %q", value)
+		}
+		cfg.Enabled = enabled
+	}
+	if value := getenv("TRACING_SAMPLE_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			return cfg, fmt.Errorf("tracing.ConfigFrom: invalid TRACING_SAMPLE_RATIO %q", value)
+		}
+		cfg.SampleRatio = ratio
+	}
+	return cfg, nil
+}
@@ -0,0 +1,192 @@
+package tracing_test
+
+import (
+	"app/internal/database/dbtest"
+	"app/internal/tracing"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Входящий контекст трассировки: trace id, родительский спан и заголовок traceparent с флагом записи
+const (
+	traceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+	parentID    = "00f067aa0ba902b7"
+	traceparent = "00-" + traceID + "-" + parentID + "-01"
+)
+
+// restoreGlobals возвращает глобальные провайдер и пропагатор после теста
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestConfigFrom(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want tracing.Config
+		err  string
+	}{
+		{name: "defaults", env: map[string]string{}, want: tracing.Config{ServiceName: "online-subs", SampleRatio: 1}},
+		{
+			name: "all set",
+			env: map[string]string{
+				"TRACING_ENABLED": "true", "OTEL_SERVICE_NAME": "subs-api", "TRACING_SAMPLE_RATIO": "0.25",
+				"OTEL_EXPORTER_OTLP_ENDPOINT": "http://collector:4318",
+			},
+			want: tracing.Config{Enabled: true, ServiceName: "subs-api", SampleRatio: 0.25, Endpoint: "http://collector:4318"},
+		},
+		{name: "invalid enabled", env: map[string]string{"TRACING_ENABLED": "yes please"}, err: "invalid TRACING_ENABLED"},
+		{name: "invalid ratio", env: map[string]string{"TRACING_SAMPLE_RATIO": "half"}, err: "invalid TRACING_SAMPLE_RATIO"},
+		{name: "ratio above one", env: map[string]string{"TRACING_SAMPLE_RATIO": "1.5"}, err: "invalid TRACING_SAMPLE_RATIO"},
+		{name: "negative ratio", env: map[string]string{"TRACING_SAMPLE_RATIO": "-0.1"}, err: "invalid TRACING_SAMPLE_RATIO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tracing.ConfigFrom(func(key string) string { return tt.env[key] })
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ConfigFrom() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConfigFrom(): %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ConfigFrom() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetupDisabledKeepsPropagation(t *testing.T) {
+	restoreGlobals(t)
+	shutdown, err := tracing.Setup(context.Background(), tracing.Config{ServiceName: "online-subs", SampleRatio: 1}, dbtest.Logger())
+	if err != nil {
+		t.Fatalf("Setup(): %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("shutdown(): %v", err)
+	}
+
+	header := http.Header{"Traceparent": []string{traceparent}}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(header))
+	if got := trace.SpanContextFromContext(ctx).TraceID().String(); got != traceID {
+		t.Errorf("extracted trace id = %q, want %q", got, traceID)
+	}
+}
+
+func TestSetupExportsSpans(t *testing.T) {
+	restoreGlobals(t)
+	var mu sync.Mutex
+	var paths []string
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.Method+" "+r.URL.Path)
+	}))
+	defer collector.Close()
+
+	// Базовый адрес со слешем на конце дополняется путём /v1/traces
+	cfg := tracing.Config{Enabled: true, ServiceName: "online-subs", SampleRatio: 1, Endpoint: collector.URL + "/"}
+	shutdown, err := tracing.Setup(context.Background(), cfg, dbtest.Logger())
+	if err != nil {
+		t.Fatalf("Setup(): %v", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "operation")
+	span.End()
+	// shutdown отправляет накопленные спаны
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown(): %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "POST /v1/traces" {
+		t.Errorf("collector received %v, want one POST /v1/traces", paths)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	restoreGlobals(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(tracing.Middleware())
+	var handlerSpan trace.SpanContext
+	router.GET("/subs/:id", func(c *gin.Context) {
+		handlerSpan = trace.SpanContextFromContext(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+	router.GET("/fail", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+
+	req := httptest.NewRequest(http.MethodGet, "/subs/42", nil)
+	req.Header.Set("traceparent", traceparent)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("recorded %d spans, want 3", len(spans))
+	}
+
+	// Спан запроса продолжает входящую трассировку, называется по шаблону маршрута и передаётся обработчику
+	sub := spans[0]
+	if sub.Name() != "GET /subs/:id" || sub.SpanKind() != trace.SpanKindServer {
+		t.Errorf("span = %q (%s), want GET /subs/:id (server)", sub.Name(), sub.SpanKind())
+	}
+	if sub.SpanContext().TraceID().String() != traceID || sub.Parent().SpanID().String() != parentID {
+		t.Errorf("span trace %s parent %s, want trace %s parent %s", sub.SpanContext().TraceID(), sub.Parent().SpanID(), traceID, parentID)
+	}
+	if handlerSpan.SpanID() != sub.SpanContext().SpanID() {
+		t.Errorf("handler context span = %s, want the request span %s", handlerSpan.SpanID(), sub.SpanContext().SpanID())
+	}
+	if got := attributeOf(sub, semconv.HTTPResponseStatusCodeKey); got.AsInt64() != http.StatusOK {
+		t.Errorf("status code attribute = %v, want 200", got.Emit())
+	}
+	if sub.Status().Code == codes.Error {
+		t.Error("successful request span has error status")
+	}
+
+	// Ответ 5xx помечает спан ошибкой
+	if fail := spans[1]; fail.Status().Code != codes.Error || fail.Status().Description != "HTTP 500" {
+		t.Errorf("failed request span status = %+v, want error HTTP 500", fail.Status())
+	}
+	// Запрос к несуществующему маршруту называется только методом
+	if missing := spans[2]; missing.Name() != "GET" {
+		t.Errorf("unmatched request span = %q, want GET", missing.Name())
+	}
+}
+
+// attributeOf возвращает значение атрибута key спана span
+func attributeOf(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
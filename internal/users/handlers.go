@@ -0,0 +1,97 @@
+package users
+
+import (
+	"app/internal/logging"
+	"app/internal/tenant"
+	"app/internal/timeout"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Handlers — контракт для HTTP-обработчиков ресурса пользователей
+type Handlers interface {
+	ListUserSubs(c *gin.Context)
+	GetUserSummary(c *gin.Context)
+}
+
+// handlers — структура, реализующая интерфейс Handlers
+type handlers struct {
+	service Service
+	logger  *logrus.Logger
+}
+
+// NewHandlers — конструктор handlers
+func NewHandlers(service Service, logger *logrus.Logger) Handlers {
+	return &handlers{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// log возвращает логгер с полями запроса
+func (h *handlers) log(c *gin.Context) *logrus.Entry {
+	return logging.From(c.Request.Context(), h.logger)
+}
+
+// ListUserSubs godoc
+// @Summary Подписки пользователя
+// @Description Возвращает все подписки пользователя с признаком активности и датой следующего списания
+// @Tags users
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Success 200 {array} models.UserSubStatus
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /users/{id}/subs [get]
+func (h *handlers) ListUserSubs(c *gin.Context) {
+	userID := c.Param("id")
+	logging.Annotate(c, logrus.Fields{"user_id": userID})
+	h.log(c).Infof("handlers.ListUserSubs: Fetching subscriptions of user %s", userID)
+
+	statuses, err := h.service.ForTenant(tenant.From(c)).ListUserSubs(c.Request.Context(), userID)
+	if err != nil {
+		h.log(c).Errorf("handlers.ListUserSubs: Failed to fetch subscriptions of user %s: %v", userID, err)
+		if timeout.Expired(c) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	h.log(c).Infof("handlers.ListUserSubs: Fetched %d subscriptions of user %s", len(statuses), userID)
+	c.JSON(http.StatusOK, statuses)
+}
+
+// GetUserSummary godoc
+// @Summary Сводка расходов пользователя
+// @Description Возвращает текущий ежемесячный платёж, сумму за всё время, количество активных подписок и ближайшие списания
+// @Tags users
+// @Produce json
+// @Param id path string true "ID пользователя"
+// @Success 200 {object} models.UserSummary
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /users/{id}/summary [get]
+func (h *handlers) GetUserSummary(c *gin.Context) {
+	userID := c.Param("id")
+	logging.Annotate(c, logrus.Fields{"user_id": userID})
+	h.log(c).Infof("handlers.GetUserSummary: Building summary for user %s", userID)
+
+	summary, err := h.service.ForTenant(tenant.From(c)).GetUserSummary(c.Request.Context(), userID)
+	if err != nil {
+		h.log(c).Errorf("handlers.GetUserSummary: Failed to build summary for user %s: %v", userID, err)
+		if timeout.Expired(c) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
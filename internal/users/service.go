@@ -0,0 +1,156 @@
+package users
+
+import (
+	"app/internal/catalog"
+	"app/internal/logging"
+	"app/internal/models"
+	"app/internal/subs"
+	"context"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Service — контракт для получения данных о подписках пользователя
+type Service interface {
+	ListUserSubs(ctx context.Context, userID string) ([]models.UserSubStatus, error)
+	GetUserSummary(ctx context.Context, userID string) (*models.UserSummary, error)
+	ForTenant(tenantID string) Service
+}
+
+// service — структура, реализующая интерфейс Service
+type service struct {
+	subs    subs.Service
+	catalog catalog.Service
+	logger  *logrus.Logger
+}
+
+// NewService — конструктор service
+func NewService(subs subs.Service, catalog catalog.Service, logger *logrus.Logger) Service {
+	return &service{
+		subs:    subs,
+		catalog: catalog,
+		logger:  logger,
+	}
+}
+
+// ForTenant возвращает Service, работающий только с подписками арендатора tenantID
+func (s *service) ForTenant(tenantID string) Service {
+	return &service{
+		subs:    s.subs.ForTenant(tenantID),
+		catalog: s.catalog,
+		logger:  s.logger,
+	}
+}
+
+// log возвращает логгер с полями запроса из ctx
+func (s *service) log(ctx context.Context) *logrus.Entry {
+	return logging.From(ctx, s.logger)
+}
+
+// ListUserSubs возвращает подписки пользователя с признаком активности и датой следующего списания
+func (s *service) ListUserSubs(ctx context.Context, userID string) ([]models.UserSubStatus, error) {
+	s.log(ctx).Infof("service.ListUserSubs: Fetching subscriptions of user %s", userID)
+	statuses, _, err := s.statuses(ctx, userID, time.Now())
+	return statuses, err
+}
+
+// GetUserSummary возвращает сводку расходов пользователя: текущий ежемесячный платёж,
+// сумму за всё время, количество активных подписок и ближайшие списания.
+// Изменения цены всех подписок загружаются одним запросом, а ежемесячный платёж и сумма за всё время
+// считаются одной функцией цены месяца, как в отчётах: по цене списания, которым месяц оплачен
+func (s *service) GetUserSummary(ctx context.Context, userID string) (*models.UserSummary, error) {
+	s.log(ctx).Infof("service.GetUserSummary: Building summary for user %s", userID)
+	now := time.Now()
+	statuses, periods, err := s.statuses(ctx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &models.UserSummary{
+		UserID:       userID,
+		NextRenewals: []models.Renewal{},
+	}
+	if len(statuses) == 0 {
+		return summary, nil
+	}
+	changes, err := s.subs.ListPriceChangesByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, st := range statuses {
+		period := periodOf(st.UserSubs, periods)
+		summary.LifetimeSpend += subs.PriceForPeriod(st.UserSubs, period, changes[st.ID], st.StartDate, now)
+		if st.Active {
+			summary.ActiveSubs++
+			summary.MonthlySpend += subs.MonthlyPrice(st.UserSubs, period, changes[st.ID], now)
+		}
+		if st.NextRenewal != nil {
+			// Следующее списание выполняется по цене, действующей на его дату
+			summary.NextRenewals = append(summary.NextRenewals, models.Renewal{
+				SubID:       st.ID,
+				ServiceName: st.ServiceName,
+				Price:       st.PricedAt(changes[st.ID], *st.NextRenewal).Price,
+				Date:        *st.NextRenewal,
+			})
+		}
+	}
+	sort.Slice(summary.NextRenewals, func(i, j int) bool {
+		return summary.NextRenewals[i].Date.Before(summary.NextRenewals[j].Date)
+	})
+
+	s.log(ctx).Infof("service.GetUserSummary: User %s has %d active subscriptions", userID, summary.ActiveSubs)
+	return summary, nil
+}
+
+// statuses возвращает подписки пользователя с признаком активности и датой следующего списания на момент now
+// вместе с периодами списания сервисов каталога
+func (s *service) statuses(ctx context.Context, userID string, now time.Time) ([]models.UserSubStatus, map[uint]models.BillingPeriod, error) {
+	userSubs, err := s.subs.ListSubsByUser(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	periods, err := s.billingPeriods()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result := make([]models.UserSubStatus, 0, len(userSubs))
+	for _, sub := range userSubs {
+		status := models.UserSubStatus{
+			UserSubs: sub,
+			Active:   sub.ActiveAt(now),
+		}
+		if next, ok := subs.NextBillingDate(sub, periodOf(sub, periods), now); ok {
+			status.NextRenewal = &next
+		}
+		result = append(result, status)
+	}
+	return result, periods, nil
+}
+
+// billingPeriods возвращает периоды списания сервисов каталога по их ID
+func (s *service) billingPeriods() (map[uint]models.BillingPeriod, error) {
+	services, err := s.catalog.ListServices()
+	if err != nil {
+		return nil, err
+	}
+	periods := make(map[uint]models.BillingPeriod, len(services))
+	for _, svc := range services {
+		periods[svc.ID] = svc.BillingPeriod
+	}
+	return periods, nil
+}
+
+// periodOf возвращает период списания подписки; без привязки к каталогу списание ежемесячное
+func periodOf(sub models.UserSubs, periods map[uint]models.BillingPeriod) models.BillingPeriod {
+	if sub.ServiceID == nil {
+		return models.BillingMonth
+	}
+	if period, ok := periods[*sub.ServiceID]; ok {
+		return period
+	}
+	return models.BillingMonth
+}
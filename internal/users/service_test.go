@@ -0,0 +1,115 @@
+package users_test
+
+import (
+	"app/internal/catalog"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"app/internal/outbox"
+	"app/internal/subs"
+	"app/internal/tenant"
+	"app/internal/users"
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestGetUserSummary(t *testing.T) {
+	db := dbtest.SQLite(t)
+	logger := dbtest.Logger()
+	catalogService := catalog.NewService(catalog.NewRepository(logger), logger)
+	subsService := subs.NewService(subs.NewRepository(logger), catalogService, outbox.NewRepository(logger), outbox.NewChannelPublisher(), logger)
+	service := users.NewService(subsService, catalogService, logger).ForTenant(tenant.Default)
+
+	// Даты отсчитываются от первого числа текущего месяца, потому что сводка строится на текущий момент
+	now := time.Now().UTC()
+	month := func(offset int) time.Time {
+		return time.Date(now.Year(), now.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+	}
+	const user = "60601fee-2bf1-4721-ae6f-7636e79a0cba"
+
+	quarterly := models.Service{Name: "Okko", BillingPeriod: models.BillingQuarter}
+	if err := catalogService.CreateService(&quarterly); err != nil {
+		t.Fatalf("CreateService: %v", err)
+	}
+	// Квартальная подписка списывается пять и два месяца назад; цена меняется месяц назад, уже после
+	// последнего списания, поэтому текущий месяц ещё оплачен по старой цене
+	okko := models.UserSubs{ServiceName: "Okko", ServiceID: &quarterly.ID, Price: 100, UserID: user, StartDate: month(-5)}
+	// Ежемесячная подписка дешевеет два месяца назад
+	netflix := models.UserSubs{ServiceName: "Netflix", Price: 80, UserID: user, StartDate: month(-3)}
+	// Завершённая подписка учитывается только в сумме за всё время
+	ended := models.UserSubs{ServiceName: "Spotify", Price: 30, UserID: user, StartDate: month(-4), EndDate: ptr(month(-2))}
+	other := models.UserSubs{ServiceName: "Netflix", Price: 1000, UserID: "0b7ac2d4-4a5e-4c0e-9a8e-3f1f2b6d9e11", StartDate: month(-5)}
+	for _, sub := range []*models.UserSubs{&okko, &netflix, &ended, &other} {
+		if err := db.Create(sub).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	changes := []models.PriceChange{
+		{SubID: okko.ID, Price: 200, EffectiveFrom: month(-1)},
+		{SubID: netflix.ID, Price: 50, EffectiveFrom: month(-2)},
+		{SubID: other.ID, Price: 1, EffectiveFrom: month(-5)},
+	}
+	if err := db.Create(&changes).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Изменения цены всех подписок пользователя загружаются одним запросом
+	var priceChangeQueries int
+	err := db.Callback().Query().After("gorm:query").Register("test:count_price_changes", func(tx *gorm.DB) {
+		if tx.Statement.Table == "price_changes" {
+			priceChangeQueries++
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := service.GetUserSummary(context.Background(), user)
+	if err != nil {
+		t.Fatalf("GetUserSummary: %v", err)
+	}
+	if priceChangeQueries != 1 {
+		t.Errorf("GetUserSummary queried price changes %d times, want 1", priceChangeQueries)
+	}
+
+	// Okko: 5 месяцев по 100; Netflix: 80 + 50 + 50; Spotify: 2 месяца по 30
+	if summary.LifetimeSpend != 500+180+60 {
+		t.Errorf("LifetimeSpend = %d, want %d", summary.LifetimeSpend, 500+180+60)
+	}
+	// Текущий месяц Okko оплачен списанием двухмесячной давности по цене 100
+	if summary.MonthlySpend != 100+50 || summary.ActiveSubs != 2 {
+		t.Errorf("MonthlySpend = %d with %d active, want %d with 2 active", summary.MonthlySpend, summary.ActiveSubs, 100+50)
+	}
+
+	// Сумма за всё время совпадает с отчётом за тот же период
+	total, err := subsService.ForTenant(tenant.Default).GetTotalPriceForPeriod(context.Background(), month(-5), now, subs.ReportFilter{UserID: user})
+	if err != nil {
+		t.Fatalf("GetTotalPriceForPeriod: %v", err)
+	}
+	if summary.LifetimeSpend != total {
+		t.Errorf("LifetimeSpend = %d, GetTotalPriceForPeriod = %d, want equal", summary.LifetimeSpend, total)
+	}
+
+	want := []models.Renewal{
+		{SubID: netflix.ID, ServiceName: "Netflix", Price: 50, Date: month(1)},
+		{SubID: okko.ID, ServiceName: "Okko", Price: 200, Date: month(1)},
+	}
+	if len(summary.NextRenewals) != len(want) {
+		t.Fatalf("NextRenewals = %+v, want %+v", summary.NextRenewals, want)
+	}
+	for _, w := range want {
+		found := false
+		for _, got := range summary.NextRenewals {
+			if got.SubID == w.SubID {
+				found = got.Price == w.Price && got.Date.Equal(w.Date)
+			}
+		}
+		if !found {
+			t.Errorf("NextRenewals = %+v, want %+v", summary.NextRenewals, w)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
@@ -0,0 +1,55 @@
+package webhooks
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Config — настройки доставки вебхуков
+type Config struct {
+	PollInterval time.Duration `config:"poll_interval" env:"WEBHOOKS_POLL_INTERVAL"`
+	Timeout      time.Duration `config:"timeout" env:"WEBHOOKS_TIMEOUT"`
+	MaxAttempts  int           `config:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS"`
+	BackoffBase  time.Duration `config:"backoff_base" env:"WEBHOOKS_BACKOFF_BASE"`
+	BackoffMax   time.Duration `config:"backoff_max" env:"WEBHOOKS_BACKOFF_MAX"`
+	BatchSize    int
+}
+
+// ConfigFrom читает настройки доставки вебхуков из переменных WEBHOOKS_*
+func ConfigFrom(getenv func(string) string) (Config, error) {
+	cfg := Config{
+		PollInterval: 5 * time.Second,
+		Timeout:      10 * time.Second,
+		MaxAttempts:  8,
+		BackoffBase:  10 * time.Second,
+		BackoffMax:   time.Hour,
+		BatchSize:    100,
+	}
+
+	for key, dst := range map[string]*time.Duration{
+		"WEBHOOKS_POLL_INTERVAL": &cfg.PollInterval,
+		"WEBHOOKS_TIMEOUT":       &cfg.Timeout,
+		"WEBHOOKS_BACKOFF_BASE":  &cfg.BackoffBase,
+		"WEBHOOKS_BACKOFF_MAX":   &cfg.BackoffMax,
+	} {
+		value := getenv(key)
+		if value == "" {
+			continue
+		}
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("webhooks.ConfigFrom: invalid %s %q", key, value)
+		}
+		*dst = d
+	}
+
+	if value := getenv("WEBHOOKS_MAX_ATTEMPTS"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return cfg, fmt.Errorf("webhooks.ConfigFrom: invalid WEBHOOKS_MAX_ATTEMPTS %q", value)
+		}
+		cfg.MaxAttempts = n
+	}
+	return cfg, nil
+}
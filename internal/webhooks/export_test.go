@@ -0,0 +1,20 @@
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// NewWorkerAt — NewWorker с часами now вместо time.Now
+func NewWorkerAt(repo Repository, cfg Config, logger *logrus.Logger, now func() time.Time) Worker {
+	w := NewWorker(repo, cfg, logger).(*worker)
+	w.now = now
+	return w
+}
+
+// DeliverDue выполняет одну итерацию Worker.Run: попытку доставки всех доставок, время которых наступило
+func DeliverDue(ctx context.Context, w Worker) {
+	w.(*worker).deliverDue(ctx)
+}
@@ -0,0 +1,302 @@
+package webhooks
+
+import (
+	"app/internal/logging"
+	"app/internal/models"
+	"app/internal/tenant"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// Handlers — контракт для HTTP-обработчиков вебхуков
+type Handlers interface {
+	CreateWebhook(c *gin.Context)
+	GetWebhookByID(c *gin.Context)
+	UpdateWebhook(c *gin.Context)
+	DeleteWebhook(c *gin.Context)
+	ListWebhooks(c *gin.Context)
+	ListDeliveries(c *gin.Context)
+	RetryDelivery(c *gin.Context)
+}
+
+// handlers — структура, реализующая интерфейс Handlers
+type handlers struct {
+	service Service
+	logger  *logrus.Logger
+}
+
+// NewHandlers — конструктор handlers
+func NewHandlers(service Service, logger *logrus.Logger) Handlers {
+	return &handlers{
+		service: service,
+		logger:  logger,
+	}
+}
+
+// CreateWebhook godoc
+// @Summary Зарегистрировать вебхук
+// @Description Регистрирует URL для событий subscription.created, subscription.updated, subscription.deleted и subscription.expired
+// @Description (пустой events — все события). Запросы подписываются HMAC-SHA256 от "<X-Webhook-Timestamp>.<body>" в заголовке X-Webhook-Signature.
+// @Description Если secret не передан, он генерируется и возвращается только в этом ответе
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.Webhook true "Данные вебхука"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /webhooks [post]
+func (h *handlers) CreateWebhook(c *gin.Context) {
+	h.log(c).Info("handlers.CreateWebhook: Creating webhook")
+	var hook models.Webhook
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		h.log(c).Errorf("handlers.CreateWebhook: Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.scoped(c).CreateWebhook(&hook); err != nil {
+		h.log(c).Errorf("handlers.CreateWebhook: Failed to create webhook: %v", err)
+		h.writeError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	h.log(c).Infof("handlers.CreateWebhook: Webhook created successfully with ID %d", hook.ID)
+	c.JSON(http.StatusCreated, hook)
+}
+
+// GetWebhookByID godoc
+// @Summary Получить вебхук по ID
+// @Description Возвращает вебхук без секрета
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /webhooks/{id} [get]
+func (h *handlers) GetWebhookByID(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "GetWebhookByID")
+	if !ok {
+		return
+	}
+
+	hook, err := h.scoped(c).GetWebhookByID(id)
+	if err != nil {
+		h.log(c).Warnf("handlers.GetWebhookByID: Failed to fetch webhook with ID %d: %v", id, err)
+		h.writeError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, hook)
+}
+
+// UpdateWebhook godoc
+// @Summary Обновить вебхук
+// @Description Обновляет URL, события и признак active; пустой secret оставляет прежний секрет
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param webhook body models.Webhook true "Обновленные данные вебхука"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /webhooks/{id} [put]
+func (h *handlers) UpdateWebhook(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "UpdateWebhook")
+	if !ok {
+		return
+	}
+
+	var hook models.Webhook
+	if err := c.ShouldBindJSON(&hook); err != nil {
+		h.log(c).Errorf("handlers.UpdateWebhook: Failed to bind JSON: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Убеждаемся, что ID из URL используется, а не из тела запроса
+	hook.ID = id
+
+	if err := h.scoped(c).UpdateWebhook(&hook); err != nil {
+		h.log(c).Errorf("handlers.UpdateWebhook: Failed to update webhook with ID %d: %v", id, err)
+		h.writeError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	h.log(c).Infof("handlers.UpdateWebhook: Webhook with ID %d updated successfully", id)
+	c.JSON(http.StatusOK, hook)
+}
+
+// DeleteWebhook godoc
+// @Summary Удалить вебхук
+// @Description Удаляет вебхук вместе с журналом доставок
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Success 204
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /webhooks/{id} [delete]
+func (h *handlers) DeleteWebhook(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "DeleteWebhook")
+	if !ok {
+		return
+	}
+
+	if err := h.scoped(c).DeleteWebhook(id); err != nil {
+		h.log(c).Warnf("handlers.DeleteWebhook: Failed to delete webhook with ID %d: %v", id, err)
+		h.writeError(c, err, http.StatusInternalServerError)
+		return
+	}
+
+	h.log(c).Infof("handlers.DeleteWebhook: Webhook with ID %d deleted successfully", id)
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhooks godoc
+// @Summary Список вебхуков
+// @Description Возвращает все зарегистрированные вебхуки без секретов
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} models.Webhook
+// @Failure 500 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /webhooks [get]
+func (h *handlers) ListWebhooks(c *gin.Context) {
+	hooks, err := h.scoped(c).ListWebhooks()
+	if err != nil {
+		h.log(c).Errorf("handlers.ListWebhooks: Failed to fetch list of webhooks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+// ListDeliveries godoc
+// @Summary Журнал доставок вебхука
+// @Description Возвращает попытки доставки событий вебхуку, новые первыми; status=dead — dead-letter
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param status query string false "Статус: pending, succeeded или dead"
+// @Param page query int false "Номер страницы (по умолчанию 1)"
+// @Param limit query int false "Количество элементов на странице (по умолчанию 20, максимум 100)"
+// @Success 200 {array} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /webhooks/{id}/deliveries [get]
+func (h *handlers) ListDeliveries(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "ListDeliveries")
+	if !ok {
+		return
+	}
+
+	page := 1
+	limit := 20
+	if p, err := strconv.Atoi(c.Query("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	deliveries, total, err := h.scoped(c).ListDeliveries(id, c.Query("status"), limit, (page-1)*limit)
+	if err != nil {
+		h.log(c).Errorf("handlers.ListDeliveries: Failed to fetch deliveries of webhook %d: %v", id, err)
+		h.writeError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// RetryDelivery godoc
+// @Summary Повторить доставку из dead-letter
+// @Description Возвращает доставку в очередь с обнулённым счётчиком попыток
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "ID вебхука"
+// @Param delivery_id path int true "ID доставки"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Router /webhooks/{id}/deliveries/{delivery_id}/retry [post]
+func (h *handlers) RetryDelivery(c *gin.Context) {
+	id, ok := h.parseID(c, "id", "RetryDelivery")
+	if !ok {
+		return
+	}
+	deliveryID, ok := h.parseID(c, "delivery_id", "RetryDelivery")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.scoped(c).RetryDelivery(id, deliveryID)
+	if err != nil {
+		h.log(c).Errorf("handlers.RetryDelivery: Failed to retry delivery %d of webhook %d: %v", deliveryID, id, err)
+		h.writeError(c, err, http.StatusBadRequest)
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// scoped возвращает Service арендатора запроса
+func (h *handlers) scoped(c *gin.Context) Service {
+	return h.service.ForTenant(tenant.From(c))
+}
+
+// parseID разбирает числовой параметр пути; при ошибке отвечает 400
+func (h *handlers) parseID(c *gin.Context, param, method string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		h.log(c).Errorf("handlers.%s: Invalid %s format: %v", method, param, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid " + param})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeError отвечает 404 для несуществующих записей, иначе fallback
+func (h *handlers) writeError(c *gin.Context, err error, fallback int) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case fallback == http.StatusInternalServerError:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	default:
+		c.JSON(fallback, gin.H{"error": err.Error()})
+	}
+}
+
+// log возвращает логгер с полями запроса
+func (h *handlers) log(c *gin.Context) *logrus.Entry {
+	return logging.From(c.Request.Context(), h.logger)
+}
@@ -0,0 +1,201 @@
+package webhooks
+
+import (
+	"app/internal/database"
+	"app/internal/models"
+	"app/internal/tenant"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository — контракт для работы с вебхуками и журналом доставок в бд
+type Repository interface {
+	Create(hook *models.Webhook) error
+	GetByID(id uint) (*models.Webhook, error)
+	Update(hook *models.Webhook) error
+	Delete(id uint) error
+	List() ([]models.Webhook, error)
+	ListActive(tenantID string) ([]models.Webhook, error)
+	CreateDeliveries(deliveries []models.WebhookDelivery) error
+	ListDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	SaveDelivery(delivery *models.WebhookDelivery) error
+	GetDelivery(webhookID, id uint) (*models.WebhookDelivery, error)
+	ListDeliveries(webhookID uint, status string, limit, offset int) ([]models.WebhookDelivery, int64, error)
+	ForTenant(tenantID string) Repository
+}
+
+// repository — структура, реализующая интерфейс Repository.
+// Пустой tenantID — репозиторий без ограничения арендатором (публикация событий и доставка)
+type repository struct {
+	db       *gorm.DB
+	tenantID string
+	logger   *logrus.Logger
+}
+
+// NewRepository — конструктор repository
+func NewRepository(logger *logrus.Logger) Repository {
+	return &repository{
+		db:     database.Get(),
+		logger: logger,
+	}
+}
+
+// ForTenant возвращает репозиторий, работающий только с вебхуками арендатора tenantID
+func (r *repository) ForTenant(tenantID string) Repository {
+	return &repository{
+		db:       tenant.DB(r.db, tenantID),
+		tenantID: tenantID,
+		logger:   r.logger,
+	}
+}
+
+// Create создает новый вебхук
+func (r *repository) Create(hook *models.Webhook) error {
+	r.logger.Infof("repository.Create: Creating webhook for %s", hook.URL)
+	if r.tenantID != "" {
+		hook.TenantID = r.tenantID
+	}
+	if err := r.db.Create(hook).Error; err != nil {
+		r.logger.Errorf("repository.Create: Failed to create webhook: %v", err)
+		return err
+	}
+	r.logger.Infof("repository.Create: Webhook created successfully with ID %d", hook.ID)
+	return nil
+}
+
+// GetByID возвращает вебхук по ID
+func (r *repository) GetByID(id uint) (*models.Webhook, error) {
+	var hook models.Webhook
+	if err := r.db.First(&hook, id).Error; err != nil {
+		r.logger.Warnf("repository.GetByID: Failed to fetch webhook with ID %d: %v", id, err)
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// Update обновляет существующий вебхук
+func (r *repository) Update(hook *models.Webhook) error {
+	r.logger.Infof("repository.Update: Updating webhook with ID %d", hook.ID)
+	var existing models.Webhook
+	if err := r.db.First(&existing, hook.ID).Error; err != nil {
+		r.logger.Warnf("repository.Update: Webhook with ID %d not found: %v", hook.ID, err)
+		return gorm.ErrRecordNotFound
+	}
+	hook.CreatedAt = existing.CreatedAt
+	hook.TenantID = existing.TenantID
+	if err := r.db.Save(hook).Error; err != nil {
+		r.logger.Errorf("repository.Update: Failed to update webhook with ID %d: %v", hook.ID, err)
+		return err
+	}
+	return nil
+}
+
+// Delete удаляет вебхук вместе с журналом доставок
+func (r *repository) Delete(id uint) error {
+	r.logger.Infof("repository.Delete: Deleting webhook with ID %d", id)
+	var existing models.Webhook
+	if err := r.db.First(&existing, id).Error; err != nil {
+		r.logger.Warnf("repository.Delete: Webhook with ID %d not found: %v", id, err)
+		return gorm.ErrRecordNotFound
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Webhook{}, id).Error
+	})
+	if err != nil {
+		r.logger.Errorf("repository.Delete: Failed to delete webhook with ID %d: %v", id, err)
+		return err
+	}
+	return nil
+}
+
+// List возвращает все вебхуки
+func (r *repository) List() ([]models.Webhook, error) {
+	var hooks []models.Webhook
+	if err := r.db.Order("id").Find(&hooks).Error; err != nil {
+		r.logger.Errorf("repository.List: Failed to fetch list of webhooks: %v", err)
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// ListActive возвращает включённые вебхуки арендатора tenantID
+func (r *repository) ListActive(tenantID string) ([]models.Webhook, error) {
+	var hooks []models.Webhook
+	if err := r.db.Where("active = ? AND tenant_id = ?", true, tenantID).Find(&hooks).Error; err != nil {
+		r.logger.Errorf("repository.ListActive: Failed to fetch active webhooks: %v", err)
+		return nil, err
+	}
+	return hooks, nil
+}
+
+// CreateDeliveries ставит доставки в очередь; повторная постановка того же события игнорируется
+func (r *repository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries).Error; err != nil {
+		r.logger.Errorf("repository.CreateDeliveries: Failed to enqueue deliveries: %v", err)
+		return err
+	}
+	return nil
+}
+
+// ListDueDeliveries возвращает доставки, время очередной попытки которых наступило
+func (r *repository) ListDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		r.logger.Errorf("repository.ListDueDeliveries: Failed to fetch due deliveries: %v", err)
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// SaveDelivery сохраняет результат попытки доставки
+func (r *repository) SaveDelivery(delivery *models.WebhookDelivery) error {
+	if err := r.db.Save(delivery).Error; err != nil {
+		r.logger.Errorf("repository.SaveDelivery: Failed to save delivery %d: %v", delivery.ID, err)
+		return err
+	}
+	return nil
+}
+
+// GetDelivery возвращает доставку вебхука по ID
+func (r *repository) GetDelivery(webhookID, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.Where("webhook_id = ?", webhookID).First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries возвращает журнал доставок вебхука, новые записи первыми
+func (r *repository) ListDeliveries(webhookID uint, status string, limit, offset int) ([]models.WebhookDelivery, int64, error) {
+	query := r.db.Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		r.logger.Errorf("repository.ListDeliveries: Failed to count deliveries of webhook %d: %v", webhookID, err)
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&deliveries).Error; err != nil {
+		r.logger.Errorf("repository.ListDeliveries: Failed to fetch deliveries of webhook %d: %v", webhookID, err)
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
@@ -0,0 +1,236 @@
+package webhooks
+
+import (
+	"app/internal/models"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// supportedEvents — события, на которые можно подписать вебхук
+var supportedEvents = []string{
+	models.EventSubCreated,
+	models.EventSubUpdated,
+	models.EventSubDeleted,
+	models.EventSubExpired,
+}
+
+// Service — контракт для работы с вебхуками
+type Service interface {
+	CreateWebhook(hook *models.Webhook) error
+	GetWebhookByID(id uint) (*models.Webhook, error)
+	UpdateWebhook(hook *models.Webhook) error
+	DeleteWebhook(id uint) error
+	ListWebhooks() ([]models.Webhook, error)
+	ListDeliveries(webhookID uint, status string, limit, offset int) ([]models.WebhookDelivery, int64, error)
+	RetryDelivery(webhookID, id uint) (*models.WebhookDelivery, error)
+	Publish(ctx context.Context, event models.SubEvent) error
+	ForTenant(tenantID string) Service
+}
+
+// service — структура, реализующая интерфейс Service
+type service struct {
+	repo   Repository
+	logger *logrus.Logger
+}
+
+// NewService — конструктор service
+func NewService(repo Repository, logger *logrus.Logger) Service {
+	return &service{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// ForTenant возвращает Service, работающий только с вебхуками арендатора tenantID
+func (s *service) ForTenant(tenantID string) Service {
+	return &service{
+		repo:   s.repo.ForTenant(tenantID),
+		logger: s.logger,
+	}
+}
+
+// CreateWebhook регистрирует вебхук; если секрет не передан, он генерируется и возвращается один раз
+func (s *service) CreateWebhook(hook *models.Webhook) error {
+	s.logger.Infof("service.CreateWebhook: Creating webhook for %s", hook.URL)
+	if hook.ID != 0 {
+		s.logger.Warnf("service.CreateWebhook: ID should not be provided when creating a webhook")
+		return errors.New("ID should not be provided when creating a webhook")
+	}
+	if err := s.validate(hook); err != nil {
+		return err
+	}
+	if hook.Secret == "" {
+		secret, err := randomHex(32)
+		if err != nil {
+			return err
+		}
+		hook.Secret = secret
+	}
+	hook.Active = true
+	return s.repo.Create(hook)
+}
+
+// GetWebhookByID возвращает вебхук по ID без секрета
+func (s *service) GetWebhookByID(id uint) (*models.Webhook, error) {
+	hook, err := s.repo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	hook.Secret = ""
+	return hook, nil
+}
+
+// UpdateWebhook обновляет вебхук; пустой секрет оставляет прежний
+func (s *service) UpdateWebhook(hook *models.Webhook) error {
+	s.logger.Infof("service.UpdateWebhook: Updating webhook with ID %d", hook.ID)
+	if hook.ID == 0 {
+		s.logger.Warnf("service.UpdateWebhook: id is required for update")
+		return errors.New("id is required for update")
+	}
+	if err := s.validate(hook); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.GetByID(hook.ID)
+	if err != nil {
+		return err
+	}
+	rotated := hook.Secret != ""
+	if !rotated {
+		hook.Secret = existing.Secret
+	}
+	if err := s.repo.Update(hook); err != nil {
+		return err
+	}
+	if !rotated {
+		hook.Secret = ""
+	}
+	return nil
+}
+
+// DeleteWebhook удаляет вебхук вместе с журналом доставок
+func (s *service) DeleteWebhook(id uint) error {
+	s.logger.Infof("service.DeleteWebhook: Deleting webhook with ID %d", id)
+	return s.repo.Delete(id)
+}
+
+// ListWebhooks возвращает все вебхуки без секретов
+func (s *service) ListWebhooks() ([]models.Webhook, error) {
+	hooks, err := s.repo.List()
+	if err != nil {
+		return nil, err
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	return hooks, nil
+}
+
+// ListDeliveries возвращает журнал доставок вебхука
+func (s *service) ListDeliveries(webhookID uint, status string, limit, offset int) ([]models.WebhookDelivery, int64, error) {
+	if status != "" {
+		switch models.DeliveryStatus(status) {
+		case models.DeliveryPending, models.DeliverySucceeded, models.DeliveryDead:
+		default:
+			return nil, 0, errors.New("status must be one of: pending, succeeded, dead")
+		}
+	}
+	if _, err := s.repo.GetByID(webhookID); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.ListDeliveries(webhookID, status, limit, offset)
+}
+
+// RetryDelivery возвращает доставку из dead-letter в очередь с обнулённым счётчиком попыток
+func (s *service) RetryDelivery(webhookID, id uint) (*models.WebhookDelivery, error) {
+	s.logger.Infof("service.RetryDelivery: Retrying delivery %d of webhook %d", id, webhookID)
+	delivery, err := s.repo.GetDelivery(webhookID, id)
+	if err != nil {
+		return nil, err
+	}
+	if delivery.Status != models.DeliveryDead {
+		return nil, errors.New("only dead deliveries can be retried")
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := s.repo.SaveDelivery(delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// Publish ставит событие из outbox в очередь доставки всем включённым вебхукам, подписанным
+// на его тип. Повторная публикация того же события не создаёт дублей доставок.
+func (s *service) Publish(_ context.Context, event models.SubEvent) error {
+	// Событие получают только вебхуки арендатора подписки
+	hooks, err := s.repo.ListActive(event.Data.TenantID)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	var deliveries []models.WebhookDelivery
+	for _, hook := range hooks {
+		if !slices.Contains(hook.Events, event.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return fmt.Errorf("webhooks.Publish: failed to marshal event: %w", err)
+			}
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			TenantID:      hook.TenantID,
+			WebhookID:     hook.ID,
+			EventID:       event.ID,
+			Event:         event.Type,
+			Payload:       string(payload),
+			Status:        models.DeliveryPending,
+			NextAttemptAt: event.OccurredAt,
+		})
+	}
+
+	if len(deliveries) > 0 {
+		s.logger.Infof("service.Publish: Enqueuing %s for %d webhooks", event.Type, len(deliveries))
+	}
+	return s.repo.CreateDeliveries(deliveries)
+}
+
+// validate проверяет URL и список событий вебхука; пустой список — подписка на все события
+func (s *service) validate(hook *models.Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		s.logger.Warnf("service.validate: invalid url %q", hook.URL)
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	if len(hook.Events) == 0 {
+		hook.Events = slices.Clone(supportedEvents)
+	}
+	for _, event := range hook.Events {
+		if !slices.Contains(supportedEvents, event) {
+			s.logger.Warnf("service.validate: unsupported event %q", event)
+			return fmt.Errorf("unsupported event %q", event)
+		}
+	}
+	return nil
+}
+
+// randomHex возвращает n случайных байт в шестнадцатеричном виде
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("webhooks: failed to generate random value: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
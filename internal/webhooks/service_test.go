@@ -0,0 +1,106 @@
+package webhooks_test
+
+import (
+	"app/internal/database"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"app/internal/webhooks"
+	"context"
+	"testing"
+	"time"
+)
+
+// newService подключается к новой бд SQLite в памяти и возвращает сервис вебхуков без ограничения арендатором
+func newService(t *testing.T) webhooks.Service {
+	t.Helper()
+	dbtest.SQLite(t)
+	return webhooks.NewService(webhooks.NewRepository(dbtest.Logger()), dbtest.Logger())
+}
+
+// createWebhook регистрирует вебхук арендатора tenantID на события events
+func createWebhook(t *testing.T, service webhooks.Service, tenantID string, events ...string) models.Webhook {
+	t.Helper()
+	hook := models.Webhook{URL: "https://example.com/" + tenantID, Events: events}
+	if err := service.ForTenant(tenantID).CreateWebhook(&hook); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	return hook
+}
+
+func TestPublishOnlyToEventTenant(t *testing.T) {
+	service := newService(t)
+	created := createWebhook(t, service, "tenant-a", models.EventSubCreated)
+	all := createWebhook(t, service, "tenant-a")
+	createWebhook(t, service, "tenant-a", models.EventSubDeleted)
+	createWebhook(t, service, "tenant-b", models.EventSubCreated)
+	disabled := createWebhook(t, service, "tenant-a", models.EventSubCreated)
+	disabled.Active = false
+	if err := service.ForTenant("tenant-a").UpdateWebhook(&disabled); err != nil {
+		t.Fatalf("UpdateWebhook: %v", err)
+	}
+
+	event := models.SubEvent{
+		ID: "evt", Type: models.EventSubCreated, OccurredAt: time.Now(),
+		Data: models.UserSubs{ID: 1, TenantID: "tenant-a", ServiceName: "Netflix"},
+	}
+	// Повторная публикация того же события не ставит доставки второй раз
+	for range 2 {
+		if err := service.Publish(context.Background(), event); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := database.Get().Order("webhook_id").Find(&deliveries).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []uint{created.ID, all.ID}
+	if len(deliveries) != len(want) {
+		t.Fatalf("Publish enqueued %d deliveries %+v, want %d: active webhooks of tenant-a subscribed to %s", len(deliveries), deliveries, len(want), event.Type)
+	}
+	for i, d := range deliveries {
+		if d.WebhookID != want[i] || d.TenantID != "tenant-a" || d.EventID != "evt" || d.Status != models.DeliveryPending {
+			t.Errorf("delivery #%d = %+v, want a pending delivery of evt to webhook %d of tenant-a", i+1, d, want[i])
+		}
+	}
+}
+
+func TestRetryDelivery(t *testing.T) {
+	service := newService(t)
+	hook := createWebhook(t, service, "tenant-a", models.EventSubCreated)
+	dead := models.WebhookDelivery{
+		TenantID: "tenant-a", WebhookID: hook.ID, EventID: "evt-dead", Event: models.EventSubCreated, Payload: "{}",
+		Status: models.DeliveryDead, Attempts: 8, NextAttemptAt: time.Now().Add(-time.Hour), LastError: "unexpected status 500",
+	}
+	succeeded := models.WebhookDelivery{
+		TenantID: "tenant-a", WebhookID: hook.ID, EventID: "evt-ok", Event: models.EventSubCreated, Payload: "{}",
+		Status: models.DeliverySucceeded, Attempts: 1, NextAttemptAt: time.Now().Add(-time.Hour),
+	}
+	for _, d := range []*models.WebhookDelivery{&dead, &succeeded} {
+		if err := database.Get().Create(d).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := service.ForTenant("tenant-b").RetryDelivery(hook.ID, dead.ID); err == nil {
+		t.Error("RetryDelivery of another tenant's delivery succeeded, want not found")
+	}
+	if _, err := service.ForTenant("tenant-a").RetryDelivery(hook.ID, succeeded.ID); err == nil {
+		t.Error("RetryDelivery of a succeeded delivery succeeded, want an error")
+	}
+
+	started := time.Now()
+	retried, err := service.ForTenant("tenant-a").RetryDelivery(hook.ID, dead.ID)
+	if err != nil {
+		t.Fatalf("RetryDelivery: %v", err)
+	}
+	var stored models.WebhookDelivery
+	if err := database.Get().First(&stored, dead.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range []models.WebhookDelivery{*retried, stored} {
+		if d.Status != models.DeliveryPending || d.Attempts != 0 || d.NextAttemptAt.Before(started.Add(-time.Second)) {
+			t.Errorf("retried delivery %+v, want pending with no attempts, due now", d)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package webhooks
+
+import (
+	"app/internal/models"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Заголовки запроса доставки вебхука
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+// Sign вычисляет подпись HMAC-SHA256 от строки "<timestamp>.<body>" в формате "sha256=<hex>"
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Worker — фоновая доставка событий вебхукам с повторными попытками
+type Worker interface {
+	Run(ctx context.Context)
+}
+
+// worker — структура, реализующая интерфейс Worker
+type worker struct {
+	repo   Repository
+	client *http.Client
+	cfg    Config
+	now    func() time.Time
+	logger *logrus.Logger
+}
+
+// NewWorker — конструктор worker
+func NewWorker(repo Repository, cfg Config, logger *logrus.Logger) Worker {
+	return &worker{
+		repo:   repo,
+		client: &http.Client{},
+		cfg:    cfg,
+		now:    time.Now,
+		logger: logger,
+	}
+}
+
+// Run доставляет события из очереди каждые PollInterval, пока не отменён ctx
+func (w *worker) Run(ctx context.Context) {
+	w.logger.Infof("worker.Run: Starting webhooks worker (poll interval %s, max attempts %d)", w.cfg.PollInterval, w.cfg.MaxAttempts)
+	ticker := time.NewTicker(w.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.deliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.logger.Info("worker.Run: Webhooks worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliverDue выполняет очередную попытку доставки для всех доставок, время которых наступило
+func (w *worker) deliverDue(ctx context.Context) {
+	deliveries, err := w.repo.ListDueDeliveries(w.now(), w.cfg.BatchSize)
+	if err != nil {
+		return
+	}
+
+	hooks := make(map[uint]*models.Webhook)
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		delivery := &deliveries[i]
+		hook, ok := hooks[delivery.WebhookID]
+		if !ok {
+			if hook, err = w.repo.GetByID(delivery.WebhookID); err != nil {
+				continue
+			}
+			hooks[delivery.WebhookID] = hook
+		}
+		w.attempt(ctx, hook, delivery)
+	}
+}
+
+// attempt отправляет доставку и сохраняет результат: успех, следующую попытку с
+// экспоненциальной задержкой или перевод в dead после MaxAttempts неудач
+func (w *worker) attempt(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	status, err := w.send(ctx, hook, delivery)
+	delivery.ResponseStatus = status
+
+	now := w.now()
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= w.cfg.MaxAttempts:
+		delivery.Status = models.DeliveryDead
+		delivery.LastError = err.Error()
+		w.logger.Warnf("worker.attempt: Delivery %d to webhook %d moved to dead-letter after %d attempts: %v", delivery.ID, hook.ID, delivery.Attempts, err)
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(w.backoff(delivery.Attempts))
+		w.logger.Warnf("worker.attempt: Delivery %d to webhook %d failed (attempt %d), next attempt at %s: %v", delivery.ID, hook.ID, delivery.Attempts, delivery.NextAttemptAt, err)
+	}
+
+	if err := w.repo.SaveDelivery(delivery); err != nil {
+		w.logger.Errorf("worker.attempt: Failed to save delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send выполняет подписанный POST-запрос; ответ кроме 2xx считается ошибкой. Запрос вместе с чтением ответа
+// ограничен Timeout, чтобы медленный получатель не задерживал доставку остальным вебхукам
+func (w *worker) send(ctx context.Context, hook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(w.now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderSignature, Sign(hook.Secret, timestamp, body))
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.EventID)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff возвращает задержку перед следующей попыткой: BackoffBase * 2^(attempts-1), не больше BackoffMax
+func (w *worker) backoff(attempts int) time.Duration {
+	delay := w.cfg.BackoffBase
+	for i := 1; i < attempts && delay < w.cfg.BackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, w.cfg.BackoffMax)
+}
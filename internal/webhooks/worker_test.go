@@ -0,0 +1,182 @@
+package webhooks_test
+
+import (
+	"app/internal/database"
+	"app/internal/database/dbtest"
+	"app/internal/models"
+	"app/internal/webhooks"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testConfig — настройки доставки в тестах: задержка 1с, 2с, 4с, но не больше 3с, и четыре попытки
+var testConfig = webhooks.Config{Timeout: time.Second, MaxAttempts: 4, BackoffBase: time.Second, BackoffMax: 3 * time.Second, BatchSize: 10}
+
+// clock — часы теста, которые двигаются только вручную
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+// receiver — получатель вебхуков: отвечает status и запоминает запросы
+type receiver struct {
+	*httptest.Server
+	status   atomic.Int64
+	requests atomic.Int64
+	last     atomic.Pointer[http.Request]
+	body     atomic.Pointer[string]
+}
+
+// newReceiver запускает получателя, отвечающего status
+func newReceiver(t *testing.T, status int) *receiver {
+	t.Helper()
+	r := &receiver{}
+	r.status.Store(int64(status))
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		text := string(body)
+		r.body.Store(&text)
+		r.last.Store(req)
+		r.requests.Add(1)
+		w.WriteHeader(int(r.status.Load()))
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// enqueue подключается к новой бд SQLite в памяти, регистрирует вебхук на url и ставит в очередь одну доставку
+func enqueue(t *testing.T, url string, at time.Time) webhooks.Repository {
+	t.Helper()
+	dbtest.SQLite(t)
+	repo := webhooks.NewRepository(dbtest.Logger())
+	hook := models.Webhook{URL: url, Secret: "secret", Events: []string{models.EventSubCreated}, Active: true}
+	if err := repo.Create(&hook); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	delivery := models.WebhookDelivery{
+		WebhookID: hook.ID, EventID: "evt", Event: models.EventSubCreated,
+		Payload: `{"id":"evt"}`, Status: models.DeliveryPending, NextAttemptAt: at,
+	}
+	if err := repo.CreateDeliveries([]models.WebhookDelivery{delivery}); err != nil {
+		t.Fatalf("CreateDeliveries: %v", err)
+	}
+	return repo
+}
+
+// delivery возвращает единственную доставку из очереди
+func delivery(t *testing.T) models.WebhookDelivery {
+	t.Helper()
+	var d models.WebhookDelivery
+	if err := database.Get().First(&d).Error; err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 с ключом "secret" от строки `1700000000.{"id":"evt"}`
+	want := "sha256=7c757099788fba43a4fe1e0c3b767303fdd971ab6183bc900d3de418c62b08b0"
+	if got := webhooks.Sign("secret", "1700000000", []byte(`{"id":"evt"}`)); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+	if got := webhooks.Sign("other", "1700000000", []byte(`{"id":"evt"}`)); got == want {
+		t.Error("Sign does not depend on the secret")
+	}
+	if got := webhooks.Sign("secret", "1700000001", []byte(`{"id":"evt"}`)); got == want {
+		t.Error("Sign does not depend on the timestamp")
+	}
+}
+
+func TestWorkerDeliversSignedRequest(t *testing.T) {
+	c := &clock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	r := newReceiver(t, http.StatusNoContent)
+	repo := enqueue(t, r.URL, c.now)
+
+	webhooks.DeliverDue(context.Background(), webhooks.NewWorkerAt(repo, testConfig, dbtest.Logger(), c.Now))
+
+	req, body := r.last.Load(), r.body.Load()
+	if req == nil {
+		t.Fatal("webhook was not called")
+	}
+	timestamp := req.Header.Get(webhooks.HeaderTimestamp)
+	if timestamp != "1704067200" {
+		t.Errorf("%s = %q, want the delivery time in Unix seconds", webhooks.HeaderTimestamp, timestamp)
+	}
+	if got, want := req.Header.Get(webhooks.HeaderSignature), webhooks.Sign("secret", timestamp, []byte(*body)); got != want {
+		t.Errorf("%s = %q, want %q", webhooks.HeaderSignature, got, want)
+	}
+	if req.Header.Get(webhooks.HeaderEvent) != models.EventSubCreated || req.Header.Get(webhooks.HeaderDelivery) != "evt" {
+		t.Errorf("event headers %q, %q, want %q, %q", req.Header.Get(webhooks.HeaderEvent), req.Header.Get(webhooks.HeaderDelivery), models.EventSubCreated, "evt")
+	}
+	if d := delivery(t); d.Status != models.DeliverySucceeded || d.Attempts != 1 || d.ResponseStatus != http.StatusNoContent || d.DeliveredAt == nil {
+		t.Errorf("delivery %+v, want succeeded after 1 attempt", d)
+	}
+}
+
+func TestWorkerBackoffAndDeadLetter(t *testing.T) {
+	c := &clock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	r := newReceiver(t, http.StatusInternalServerError)
+	repo := enqueue(t, r.URL, c.now)
+	worker := webhooks.NewWorkerAt(repo, testConfig, dbtest.Logger(), c.Now)
+
+	// Задержка удваивается после каждой неудачи, но не превышает BackoffMax
+	for i, backoff := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		webhooks.DeliverDue(context.Background(), worker)
+		d := delivery(t)
+		if d.Status != models.DeliveryPending || d.Attempts != i+1 || d.ResponseStatus != http.StatusInternalServerError {
+			t.Fatalf("after attempt %d: delivery %+v, want pending with status 500", i+1, d)
+		}
+		if got := d.NextAttemptAt.Sub(c.now); got != backoff {
+			t.Fatalf("after attempt %d: next attempt in %s, want %s", i+1, got, backoff)
+		}
+
+		// До наступления времени следующей попытки доставка не повторяется
+		c.now = c.now.Add(backoff - time.Millisecond)
+		webhooks.DeliverDue(context.Background(), worker)
+		if got := r.requests.Load(); got != int64(i+1) {
+			t.Fatalf("webhook called %d times before the backoff elapsed, want %d", got, i+1)
+		}
+		c.now = c.now.Add(time.Millisecond)
+	}
+
+	webhooks.DeliverDue(context.Background(), worker)
+	if d := delivery(t); d.Status != models.DeliveryDead || d.Attempts != testConfig.MaxAttempts || !strings.Contains(d.LastError, "500") {
+		t.Fatalf("after %d attempts: delivery %+v, want dead", testConfig.MaxAttempts, d)
+	}
+	c.now = c.now.Add(time.Hour)
+	webhooks.DeliverDue(context.Background(), worker)
+	if got := r.requests.Load(); got != int64(testConfig.MaxAttempts) {
+		t.Errorf("webhook called %d times, want %d: dead deliveries are not retried", got, testConfig.MaxAttempts)
+	}
+}
+
+func TestWorkerRequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+
+	c := &clock{now: time.Now()}
+	repo := enqueue(t, slow.URL, c.now)
+	cfg := testConfig
+	cfg.Timeout = 50 * time.Millisecond
+
+	started := time.Now()
+	webhooks.DeliverDue(context.Background(), webhooks.NewWorkerAt(repo, cfg, dbtest.Logger(), c.Now))
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("delivery to a hanging webhook took %s, want about %s", elapsed, cfg.Timeout)
+	}
+	if d := delivery(t); d.Status != models.DeliveryPending || d.Attempts != 1 || !strings.Contains(d.LastError, "deadline exceeded") {
+		t.Errorf("delivery %+v, want a pending retry after the timeout", d)
+	}
+}
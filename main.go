@@ -1,10 +1,36 @@
 package main
 
 import (
+	"app/internal/apikeys"
+	"app/internal/auth"
+	"app/internal/catalog"
+	"app/internal/config"
 	"app/internal/database"
+	"app/internal/health"
+	"app/internal/idempotency"
+	"app/internal/logging"
+	"app/internal/metrics"
+	"app/internal/notify"
+	"app/internal/outbox"
+	"app/internal/ratelimit"
+	"app/internal/server"
 	"app/internal/subs"
+	"app/internal/tenant"
+	"app/internal/timeout"
+	"app/internal/tracing"
+	"app/internal/users"
+	"app/internal/webhooks"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	_ "app/docs"
 
@@ -21,45 +47,243 @@ import (
 // @contact.email aamir-tutaev@mail.ru
 // @host localhost:8080
 // @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description JWT в формате "Bearer <token>" (HS256 или RS256)
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description API-ключ сервисного клиента
 func main() {
-	// Настройка логирования
-	logger := logrus.New()
-	logger.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
-
-	// Установка уровня логирования из переменной окружения
-	logLevel := os.Getenv("LOG_LEVEL")
-	if logLevel == "" {
-		logLevel = "info" // значение по умолчанию
+	// Настройки: значения по умолчанию, файл конфигурации, .env, переменные окружения и флаги.
+	// "config print" выводит итоговые настройки со скрытыми секретами
+	args := os.Args[1:]
+	printConfig := len(args) >= 2 && args[0] == "config" && args[1] == "print"
+	if printConfig {
+		args = args[2:]
+	}
+	cfg, err := config.Load(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
 	}
-	level, err := logrus.ParseLevel(logLevel)
+	if printConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			logrus.Fatalf("Failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	// Настройка логирования: уровень и формат (text или json)
+	logger := logging.New(cfg.Log)
+
+	// SIGINT/SIGTERM запускают остановку: сервер дожидается текущих запросов, затем останавливаются
+	// фоновые задачи и закрывается пул соединений с бд
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Трассировка OpenTelemetry: экспорт по OTLP или только пропагация контекста
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, logger)
 	if err != nil {
-		logger.Warnf("Invalid log level '%s', using 'info' as default", logLevel)
-		level = logrus.InfoLevel
+		logger.Fatalf("Failed to set up tracing: %v", err)
 	}
-	logger.SetLevel(level)
+
 	// Инициализация базы данных
-	database.Init()
+	if _, err := database.Init(cfg.Database, logger); err != nil {
+		logger.Fatalf("Failed to initialize database: %v", err)
+	}
 	logger.Infof("Database initialized successfully")
 
+	// Фоновые задачи работают до начала остановки сервиса; реестр workersHealth показывает их в /readyz
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workersHealth := health.NewWorkers()
+	startWorker := func(name string, run func(ctx context.Context)) {
+		workers.Add(1)
+		workersHealth.Started(name)
+		go func() {
+			defer workers.Done()
+			defer workersHealth.Stopped(name)
+			run(workersCtx)
+		}()
+	}
+
 	// Создание экземпляров репозитория, сервиса и обработчиков
-	repo := subs.NewRepository(logger)
-	service := subs.NewService(repo, logger)
+	apiKeysService := apikeys.NewService(apikeys.NewRepository(logger), logger)
+	apiKeysHandlers := apikeys.NewHandlers(apiKeysService, logger)
+
+	catalogRepo := catalog.NewRepository(logger)
+	catalogService := catalog.NewService(catalogRepo, logger)
+	catalogHandlers := catalog.NewHandlers(catalogService, logger)
+
+	webhooksRepo := webhooks.NewRepository(logger)
+	webhooksService := webhooks.NewService(webhooksRepo, logger)
+	webhooksHandlers := webhooks.NewHandlers(webhooksService, logger)
+
+	// Журнал событий и внутрипроцессная раздача событий подписчикам (SSE)
+	outboxRepo := outbox.NewRepository(logger)
+	events := outbox.NewChannelPublisher()
+
+	repo := subs.TraceRepository(subs.NewRepository(logger))
+	service := subs.Trace(subs.NewService(repo, catalogService, outboxRepo, events, logger))
 	handlers := subs.NewHandlers(service, logger)
 
-	// Создание роутера
-	router := gin.Default()
+	usersService := users.NewService(service, catalogService, logger)
+	usersHandlers := users.NewHandlers(usersService, logger)
+
+	// Привязка подписок, созданных до появления каталога сервисов
+	if _, err := catalogService.BackfillSubs(); err != nil {
+		logger.Errorf("Failed to backfill service catalogue: %v", err)
+	}
+	if _, err := catalogService.BackfillCategories(); err != nil {
+		logger.Errorf("Failed to backfill service categories: %v", err)
+	}
+
+	// Запуск планировщика уведомлений о продлении и окончании подписок
+	if cfg.Notify.Enabled {
+		notifier, err := notify.NewNotifier(cfg.Notify, logger)
+		if err != nil {
+			logger.Fatalf("Failed to create notifier: %v", err)
+		}
+		scheduler := notify.NewScheduler(notify.NewRepository(logger), notifier, cfg.Notify.Interval, cfg.Notify.LeadTime, logger)
+		startWorker("notify-scheduler", scheduler.Run)
+	}
+
+	// Запуск доставки событий вебхукам
+	startWorker("webhooks-worker", webhooks.NewWorker(webhooksRepo, cfg.Webhooks, logger).Run)
+
+	// Публикация событий из outbox: вебхуки, внутрипроцессные подписчики и, при настройке, NATS
+	publisher, err := outbox.NewPublisher(cfg.Outbox, logger, webhooksService, events)
+	if err != nil {
+		logger.Fatalf("Failed to create outbox publisher: %v", err)
+	}
+	startWorker("outbox-relay", outbox.NewRelay(outboxRepo, publisher, cfg.Outbox, logger).Run)
+
+	// Создание роутера; спан запроса открывается первым, чтобы охватить все остальные обработчики,
+	// затем запросу присваивается X-Request-ID, который попадает во все записи логов запроса
+	router, err := server.NewRouter(cfg.Server)
+	if err != nil {
+		logger.Fatalf("Failed to create router: %v", err)
+	}
+	router.Use(tracing.Middleware(), logging.RequestID(), logging.AccessLog(logger), gin.Recovery())
+
+	// Метрики Prometheus: HTTP-запросы, запросы к бд и пул соединений, бизнес-показатели
+	if cfg.Metrics.Enabled {
+		router.Use(metrics.Middleware())
+		router.GET("/metrics", gin.WrapH(metrics.Handler()))
+		startWorker("business-metrics", func(ctx context.Context) {
+			metrics.RunBusiness(ctx, repo.GetServiceStats, cfg.Metrics.BusinessInterval, logger)
+		})
+	}
+
+	// Потоки событий прерываются в начале остановки сервера
+	streams, stopStreams := context.WithCancel(context.Background())
+
+	api := router.Group("")
+
+	// Срок обработки запроса; по его истечении запросы к бд отменяются
+	api.Use(timeout.Middleware(cfg.Timeout))
+
+	// Ограничение частоты запросов: сначала по IP, чтобы перебор учётных данных тоже упирался в лимит,
+	// затем после аутентификации — по API-ключу, пользователю или IP
+	var limiterStore ratelimit.Store
+	if cfg.RateLimit.Enabled {
+		var err error
+		if limiterStore, err = ratelimit.NewStore(cfg.RateLimit, logger); err != nil {
+			logger.Fatalf("Failed to create rate limit store: %v", err)
+		}
+		api.Use(ratelimit.IPMiddleware(limiterStore, cfg.RateLimit, logger))
+	}
+
+	// Все эндпоинты API, кроме документации, требуют JWT или API-ключа
+	if cfg.Auth.Enabled {
+		authenticator, err := auth.NewJWTAuthenticator(cfg.Auth)
+		if err != nil {
+			logger.Fatalf("Failed to create authenticator: %v", err)
+		}
+		api.Use(auth.Middleware(authenticator, apiKeysService, logger))
+	} else {
+		logger.Warn("Authentication is disabled (AUTH_ENABLED=false), API is open to everyone")
+	}
+	// Арендатор запроса берётся из токена или API-ключа, а без них — из заголовка X-Tenant-ID
+	api.Use(tenant.Middleware(logger))
+
+	if cfg.RateLimit.Enabled {
+		api.Use(ratelimit.Middleware(limiterStore, cfg.RateLimit, logger))
+	}
+
+	// Повторы запросов с заголовком Idempotency-Key не создают дубликатов
+	idempotencyRepo := idempotency.NewRepository(logger)
+	startWorker("idempotency-cleanup", func(ctx context.Context) {
+		idempotency.RunCleanup(ctx, idempotencyRepo, cfg.Idempotency, logger)
+	})
+	idempotent := idempotency.Middleware(idempotencyRepo, cfg.Idempotency, logger)
 
 	// Регистрация обработчиков
-	subsGroup := router.Group("/subs")
+	subsGroup := api.Group("/subs")
 	{
-		subsGroup.POST("", handlers.CreateSub)
+		subsGroup.POST("", idempotent, handlers.CreateSub)
 		subsGroup.GET("/:id", handlers.GetSubByID)
 		subsGroup.PUT("/:id", handlers.UpdateSub)
 		subsGroup.DELETE("/:id", handlers.DeleteSub)
 		subsGroup.GET("", handlers.ListSubs)
 		subsGroup.GET("/total", handlers.GetTotalPriceForPeriod)
+		subsGroup.GET("/total/breakdown", handlers.GetTotalPriceBreakdown)
+		subsGroup.GET("/timeseries", handlers.GetTimeseries)
+		subsGroup.GET("/forecast", handlers.GetForecast)
+		subsGroup.GET("/events", timeout.Until(streams), handlers.StreamEvents)
+		subsGroup.POST("/:id/price-changes", idempotent, handlers.CreatePriceChange)
+		subsGroup.GET("/:id/price-changes", handlers.ListPriceChanges)
+		subsGroup.DELETE("/:id/price-changes/:change_id", handlers.DeletePriceChange)
+	}
+
+	// Каталог и категории общие для всех арендаторов: читают все, меняет только администратор платформы
+	servicesGroup := api.Group("/services", auth.RequirePlatformAdminForWrites(logger))
+	{
+		servicesGroup.POST("", catalogHandlers.CreateService)
+		servicesGroup.GET("/:id", catalogHandlers.GetServiceByID)
+		servicesGroup.PUT("/:id", catalogHandlers.UpdateService)
+		servicesGroup.DELETE("/:id", catalogHandlers.DeleteService)
+		servicesGroup.GET("", catalogHandlers.ListServices)
+	}
+
+	usersGroup := api.Group("/users/:id", auth.RequireUserAccess(logger, "id"))
+	{
+		usersGroup.GET("/subs", usersHandlers.ListUserSubs)
+		usersGroup.GET("/summary", usersHandlers.GetUserSummary)
+	}
+
+	// Вебхуки получают события всех пользователей, поэтому управляет ими только admin
+	webhooksGroup := api.Group("/webhooks", auth.RequireRole(logger, auth.RoleAdmin))
+	{
+		webhooksGroup.POST("", webhooksHandlers.CreateWebhook)
+		webhooksGroup.GET("/:id", webhooksHandlers.GetWebhookByID)
+		webhooksGroup.PUT("/:id", webhooksHandlers.UpdateWebhook)
+		webhooksGroup.DELETE("/:id", webhooksHandlers.DeleteWebhook)
+		webhooksGroup.GET("", webhooksHandlers.ListWebhooks)
+		webhooksGroup.GET("/:id/deliveries", webhooksHandlers.ListDeliveries)
+		webhooksGroup.POST("/:id/deliveries/:delivery_id/retry", webhooksHandlers.RetryDelivery)
+	}
+
+	apiKeysGroup := api.Group("/api-keys", auth.RequireRole(logger, auth.RoleAdmin))
+	{
+		apiKeysGroup.POST("", apiKeysHandlers.CreateKey)
+		apiKeysGroup.GET("/:id", apiKeysHandlers.GetKeyByID)
+		apiKeysGroup.DELETE("/:id", apiKeysHandlers.RevokeKey)
+		apiKeysGroup.GET("", apiKeysHandlers.ListKeys)
+	}
+
+	categoriesGroup := api.Group("/categories", auth.RequirePlatformAdminForWrites(logger))
+	{
+		categoriesGroup.POST("", catalogHandlers.CreateCategory)
+		categoriesGroup.GET("/:id", catalogHandlers.GetCategoryByID)
+		categoriesGroup.PUT("/:id", catalogHandlers.UpdateCategory)
+		categoriesGroup.DELETE("/:id", catalogHandlers.DeleteCategory)
+		categoriesGroup.GET("", catalogHandlers.ListCategories)
 	}
 
 	// Базовый эндпоинт
@@ -67,18 +291,63 @@ func main() {
 		ctx.String(http.StatusOK, "Hello by Effective Mobile")
 	})
 
+	// Проверки живости и готовности для оркестратора; при остановке /readyz сразу отвечает 503
+	healthService := health.NewService(logger,
+		health.Check{Name: "database", Critical: true, Run: database.Ping},
+		health.Check{Name: "migrations", Critical: true, Run: database.CheckSchema},
+		workersHealth.Check(),
+	)
+	context.AfterFunc(ctx, healthService.SetShuttingDown)
+	healthHandlers := health.NewHandlers(healthService, logger)
+	router.GET("/healthz", healthHandlers.Liveness)
+	router.GET("/readyz", healthHandlers.Readiness)
+
 	// Эндпоинт документации Swagger
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 	logger.Infof("Documentation API: http://localhost:8080/swagger/index.html#/")
 
-	// Запуск сервера
-	port := os.Getenv("SERVER_PORT")
-	if port == "" {
-		port = "8080" // значение по умолчанию
+	// Запуск сервера; при остановке потоки событий закрываются сразу, чтобы не ждать их весь grace period
+	if err := server.Run(ctx, router, cfg.Server, logger, stopStreams); err != nil {
+		logger.Errorf("Server stopped with error: %v", err)
+	}
+
+	// Остановка фоновых задач и освобождение ресурсов
+	stopWorkers()
+	if !waitTimeout(&workers, cfg.Server.ShutdownGracePeriod) {
+		logger.Warnf("Background workers did not stop within %s", cfg.Server.ShutdownGracePeriod)
+	}
+	if closer, ok := publisher.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Errorf("Failed to close outbox publisher: %v", err)
+		}
 	}
-	logger.Infof("Server starting on port %s", port)
+	if closer, ok := limiterStore.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Errorf("Failed to close rate limit store: %v", err)
+		}
+	}
+	tracingCtx, cancelTracing := context.WithTimeout(context.Background(), cfg.Server.ShutdownGracePeriod)
+	defer cancelTracing()
+	if err := shutdownTracing(tracingCtx); err != nil {
+		logger.Errorf("Failed to flush traces: %v", err)
+	}
+	if err := database.Close(); err != nil {
+		logger.Errorf("Failed to close database: %v", err)
+	}
+	logger.Info("Shutdown complete")
+}
 
-	if err := router.Run(":" + port); err != nil {
-		logger.Fatalf("Failed to start server: %v", err)
+// waitTimeout ждёт wg не дольше d; false — время вышло
+func waitTimeout(wg *sync.WaitGroup, d time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(d):
+		return false
 	}
 }
@@ -0,0 +1,42 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS services (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    normalized_name VARCHAR(255) NOT NULL,
+    category VARCHAR(255),
+    default_price INTEGER NOT NULL DEFAULT 0,
+    billing_period VARCHAR(16) NOT NULL DEFAULT 'month'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_services_normalized_name ON services(normalized_name);
+
+CREATE TABLE IF NOT EXISTS service_aliases (
+    id SERIAL PRIMARY KEY,
+    service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL,
+    normalized_alias VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_aliases_service_id ON service_aliases(service_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_aliases_normalized_alias ON service_aliases(normalized_alias);
+
+ALTER TABLE user_subs ADD COLUMN IF NOT EXISTS service_id INTEGER REFERENCES services(id);
+CREATE INDEX IF NOT EXISTS idx_user_subs_service_id ON user_subs(service_id);
+
+-- Заполняем каталог существующими названиями и привязываем к нему подписки
+INSERT INTO services (name, normalized_name)
+SELECT DISTINCT ON (lower(btrim(service_name))) btrim(service_name), lower(btrim(service_name))
+FROM user_subs
+WHERE btrim(service_name) <> ''
+ORDER BY lower(btrim(service_name)), service_name
+ON CONFLICT (normalized_name) DO NOTHING;
+
+UPDATE user_subs us
+SET service_id = s.id, service_name = s.name
+FROM services s
+WHERE us.service_id IS NULL AND lower(btrim(us.service_name)) = s.normalized_name;
+
+-- +migrate Down
+ALTER TABLE user_subs DROP COLUMN IF EXISTS service_id;
+DROP TABLE service_aliases;
+DROP TABLE services;
@@ -0,0 +1,21 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name ON categories(name);
+CREATE INDEX IF NOT EXISTS idx_services_category ON services(category);
+
+-- Регистрируем категории, уже указанные у сервисов каталога
+UPDATE services SET category = lower(btrim(category)) WHERE category IS NOT NULL;
+
+INSERT INTO categories (name)
+SELECT DISTINCT category FROM services
+WHERE category IS NOT NULL AND category <> ''
+ON CONFLICT (name) DO NOTHING;
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_services_category;
+DROP TABLE categories;
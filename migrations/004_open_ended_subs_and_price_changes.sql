@@ -0,0 +1,17 @@
+-- +migrate Up
+-- Подписка без end_date считается бессрочной
+ALTER TABLE user_subs ALTER COLUMN end_date DROP NOT NULL;
+
+CREATE TABLE IF NOT EXISTS price_changes (
+    id SERIAL PRIMARY KEY,
+    sub_id INTEGER NOT NULL REFERENCES user_subs(id) ON DELETE CASCADE,
+    price INTEGER NOT NULL,
+    effective_from TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_changes_sub_id ON price_changes(sub_id);
+
+-- +migrate Down
+DROP TABLE price_changes;
+UPDATE user_subs SET end_date = start_date WHERE end_date IS NULL;
+ALTER TABLE user_subs ALTER COLUMN end_date SET NOT NULL;
@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(64) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    sent_at TIMESTAMP
+);
+
+-- event_id уникален: повторная запись того же события (например, expired) игнорируется
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_event_id ON outbox_events(event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_sent_at ON outbox_events(sent_at);
+
+-- +migrate Down
+DROP TABLE outbox_events;
@@ -0,0 +1,53 @@
+-- +migrate Up
+-- Существующие записи относятся к арендатору по умолчанию
+ALTER TABLE user_subs ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE price_changes ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE notifications ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE outbox_events ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_user_subs_tenant_id ON user_subs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_price_changes_tenant_id ON price_changes(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_tenant_id ON notifications(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_tenant_id ON outbox_events(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant_id ON webhooks(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_tenant_id ON webhook_deliveries(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant_id ON api_keys(tenant_id);
+
+-- Row-level security: запросы API выполняются с app.tenant_id арендатора и видят только его строки.
+-- Фоновые задачи (relay, доставка вебхуков, уведомления) работают без app.tenant_id и видят все строки.
+ALTER TABLE user_subs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_subs FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_subs
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE price_changes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE price_changes FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON price_changes
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id = current_setting('app.tenant_id', true));
+
+ALTER TABLE outbox_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE outbox_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON outbox_events
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id = current_setting('app.tenant_id', true));
+
+-- +migrate Down
+DROP POLICY IF EXISTS tenant_isolation ON outbox_events;
+ALTER TABLE outbox_events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE outbox_events DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON price_changes;
+ALTER TABLE price_changes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE price_changes DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON user_subs;
+ALTER TABLE user_subs NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE user_subs DISABLE ROW LEVEL SECURITY;
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE webhooks DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE outbox_events DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE notifications DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE price_changes DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE user_subs DROP COLUMN IF EXISTS tenant_id;
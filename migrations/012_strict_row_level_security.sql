@@ -0,0 +1,28 @@
+-- +migrate Up
+-- Без app.tenant_id политики больше не пропускают ни одной строки. Фоновые задачи работают со всеми арендаторами
+-- от имени роли с BYPASSRLS (DB_WORKER_ROLE), выданной пользователю сервиса; сам пользователь не должен быть
+-- суперпользователем и не должен иметь BYPASSRLS (см. docker/postgres/init-roles.sh)
+DROP POLICY IF EXISTS tenant_isolation ON user_subs;
+CREATE POLICY tenant_isolation ON user_subs
+    USING (tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON price_changes;
+CREATE POLICY tenant_isolation ON price_changes
+    USING (tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON outbox_events;
+CREATE POLICY tenant_isolation ON outbox_events
+    USING (tenant_id = current_setting('app.tenant_id', true));
+
+-- +migrate Down
+DROP POLICY IF EXISTS tenant_isolation ON outbox_events;
+CREATE POLICY tenant_isolation ON outbox_events
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON price_changes;
+CREATE POLICY tenant_isolation ON price_changes
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id = current_setting('app.tenant_id', true));
+
+DROP POLICY IF EXISTS tenant_isolation ON user_subs;
+CREATE POLICY tenant_isolation ON user_subs
+    USING (COALESCE(current_setting('app.tenant_id', true), '') = '' OR tenant_id = current_setting('app.tenant_id', true));
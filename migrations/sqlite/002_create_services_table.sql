@@ -0,0 +1,43 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS services (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    normalized_name VARCHAR(255) NOT NULL,
+    category VARCHAR(255),
+    default_price INTEGER NOT NULL DEFAULT 0,
+    billing_period VARCHAR(16) NOT NULL DEFAULT 'month'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_services_normalized_name ON services(normalized_name);
+
+CREATE TABLE IF NOT EXISTS service_aliases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_id INTEGER NOT NULL REFERENCES services(id) ON DELETE CASCADE,
+    alias VARCHAR(255) NOT NULL,
+    normalized_alias VARCHAR(255) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_service_aliases_service_id ON service_aliases(service_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_service_aliases_normalized_alias ON service_aliases(normalized_alias);
+
+ALTER TABLE user_subs ADD COLUMN service_id INTEGER REFERENCES services(id);
+CREATE INDEX IF NOT EXISTS idx_user_subs_service_id ON user_subs(service_id);
+
+-- Заполняем каталог существующими названиями и привязываем к нему подписки.
+-- В SQLite нет DISTINCT ON: из написаний одного названия берётся первое по алфавиту
+INSERT OR IGNORE INTO services (name, normalized_name)
+SELECT trim(MIN(service_name)), lower(trim(service_name))
+FROM user_subs
+WHERE trim(service_name) <> ''
+GROUP BY lower(trim(service_name));
+
+UPDATE user_subs
+SET service_id = s.id, service_name = s.name
+FROM services s
+WHERE user_subs.service_id IS NULL AND lower(trim(user_subs.service_name)) = s.normalized_name;
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_user_subs_service_id;
+ALTER TABLE user_subs DROP COLUMN service_id;
+DROP TABLE service_aliases;
+DROP TABLE services;
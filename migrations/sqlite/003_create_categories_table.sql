@@ -0,0 +1,20 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS categories (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(255) NOT NULL,
+    description TEXT
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_name ON categories(name);
+CREATE INDEX IF NOT EXISTS idx_services_category ON services(category);
+
+-- Регистрируем категории, уже указанные у сервисов каталога
+UPDATE services SET category = lower(trim(category)) WHERE category IS NOT NULL;
+
+INSERT OR IGNORE INTO categories (name)
+SELECT DISTINCT category FROM services
+WHERE category IS NOT NULL AND category <> '';
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_services_category;
+DROP TABLE categories;
@@ -0,0 +1,59 @@
+-- +migrate Up
+-- Подписка без end_date считается бессрочной.
+-- SQLite не умеет снимать NOT NULL со столбца, поэтому таблица пересоздаётся
+CREATE TABLE user_subs_new (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_name VARCHAR(255) NOT NULL,
+    price INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    start_date DATETIME NOT NULL,
+    end_date DATETIME,
+    service_id INTEGER REFERENCES services(id)
+);
+
+INSERT INTO user_subs_new (id, service_name, price, user_id, start_date, end_date, service_id)
+SELECT id, service_name, price, user_id, start_date, end_date, service_id FROM user_subs;
+
+DROP TABLE user_subs;
+ALTER TABLE user_subs_new RENAME TO user_subs;
+
+CREATE INDEX IF NOT EXISTS idx_user_subs_user_id ON user_subs(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_subs_service_name ON user_subs(service_name);
+CREATE INDEX IF NOT EXISTS idx_user_subs_start_date ON user_subs(start_date);
+CREATE INDEX IF NOT EXISTS idx_user_subs_end_date ON user_subs(end_date);
+CREATE INDEX IF NOT EXISTS idx_user_subs_service_id ON user_subs(service_id);
+
+CREATE TABLE IF NOT EXISTS price_changes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sub_id INTEGER NOT NULL REFERENCES user_subs(id) ON DELETE CASCADE,
+    price INTEGER NOT NULL,
+    effective_from DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_changes_sub_id ON price_changes(sub_id);
+
+-- +migrate Down
+DROP TABLE price_changes;
+UPDATE user_subs SET end_date = start_date WHERE end_date IS NULL;
+
+CREATE TABLE user_subs_old (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    service_name VARCHAR(255) NOT NULL,
+    price INTEGER NOT NULL,
+    user_id TEXT NOT NULL,
+    start_date DATETIME NOT NULL,
+    end_date DATETIME NOT NULL,
+    service_id INTEGER REFERENCES services(id)
+);
+
+INSERT INTO user_subs_old (id, service_name, price, user_id, start_date, end_date, service_id)
+SELECT id, service_name, price, user_id, start_date, end_date, service_id FROM user_subs;
+
+DROP TABLE user_subs;
+ALTER TABLE user_subs_old RENAME TO user_subs;
+
+CREATE INDEX IF NOT EXISTS idx_user_subs_user_id ON user_subs(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_subs_service_name ON user_subs(service_name);
+CREATE INDEX IF NOT EXISTS idx_user_subs_start_date ON user_subs(start_date);
+CREATE INDEX IF NOT EXISTS idx_user_subs_end_date ON user_subs(end_date);
+CREATE INDEX IF NOT EXISTS idx_user_subs_service_id ON user_subs(service_id);
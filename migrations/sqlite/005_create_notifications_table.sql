@@ -0,0 +1,17 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    sub_id INTEGER NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    due_date DATETIME NOT NULL,
+    user_id TEXT NOT NULL,
+    service_name VARCHAR(255) NOT NULL,
+    price INTEGER NOT NULL,
+    sent_at DATETIME NOT NULL
+);
+
+-- Одно уведомление каждого типа на дату списания или окончания подписки
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_unique ON notifications(sub_id, kind, due_date);
+
+-- +migrate Down
+DROP TABLE notifications;
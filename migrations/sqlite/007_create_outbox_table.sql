@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id VARCHAR(64) NOT NULL,
+    type VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sent_at DATETIME
+);
+
+-- event_id уникален: повторная запись того же события (например, expired) игнорируется
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_event_id ON outbox_events(event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_sent_at ON outbox_events(sent_at);
+
+-- +migrate Down
+DROP TABLE outbox_events;
@@ -0,0 +1,36 @@
+-- +migrate Up
+-- Существующие записи относятся к арендатору по умолчанию
+ALTER TABLE user_subs ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE price_changes ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE notifications ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE outbox_events ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhook_deliveries ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_user_subs_tenant_id ON user_subs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_price_changes_tenant_id ON price_changes(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_tenant_id ON notifications(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_tenant_id ON outbox_events(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant_id ON webhooks(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_tenant_id ON webhook_deliveries(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_tenant_id ON api_keys(tenant_id);
+
+-- В SQLite нет row-level security: строки арендатора отбирает только условие tenant_id в запросах сервиса
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_api_keys_tenant_id;
+DROP INDEX IF EXISTS idx_webhook_deliveries_tenant_id;
+DROP INDEX IF EXISTS idx_webhooks_tenant_id;
+DROP INDEX IF EXISTS idx_outbox_events_tenant_id;
+DROP INDEX IF EXISTS idx_notifications_tenant_id;
+DROP INDEX IF EXISTS idx_price_changes_tenant_id;
+DROP INDEX IF EXISTS idx_user_subs_tenant_id;
+
+ALTER TABLE api_keys DROP COLUMN tenant_id;
+ALTER TABLE webhook_deliveries DROP COLUMN tenant_id;
+ALTER TABLE webhooks DROP COLUMN tenant_id;
+ALTER TABLE outbox_events DROP COLUMN tenant_id;
+ALTER TABLE notifications DROP COLUMN tenant_id;
+ALTER TABLE price_changes DROP COLUMN tenant_id;
+ALTER TABLE user_subs DROP COLUMN tenant_id;
@@ -0,0 +1,10 @@
+-- +migrate Up
+-- Порядковый номер назначается при публикации: ID выдаются до фиксации транзакций и могут фиксироваться не по порядку
+ALTER TABLE outbox_events ADD COLUMN seq INTEGER;
+-- Уже опубликованные события сохраняют номера, которые клиенты видели как ID в потоке
+UPDATE outbox_events SET seq = id WHERE sent_at IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_outbox_events_seq ON outbox_events(seq);
+
+-- +migrate Down
+DROP INDEX IF EXISTS idx_outbox_events_seq;
+ALTER TABLE outbox_events DROP COLUMN seq;